@@ -0,0 +1,298 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/apidiff"
+)
+
+// compareRevision returns the changes in the API of the given packages from
+// their sources at a git revision, as found by apidiff comparing both
+// versions type-checked, so changes are judged by their semantics, such as
+// a parameter changing to an alias of the same type, instead of by how the
+// declarations are written. Packages at the revision that no longer exist,
+// in the modules of the given ones, are reported as removed.
+func compareRevision(rev string, pkgNames []string) ([]*APIChange, error) {
+	// The sources at the revision are extracted once per repository.
+	var trees = make(map[string]string)
+	defer func() {
+		for _, dir := range trees {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	var (
+		changes []*APIChange
+		current = make(map[string]bool)
+		// modules are the roots of the modules of the packages, or of
+		// their repositories outside modules, by the repository root.
+		modules = make(map[string]map[string]bool)
+	)
+	for _, pkgName := range pkgNames {
+		dir, err := packageSrcDir(pkgName)
+		if err != nil {
+			return nil, err
+		}
+		current[dir] = true
+
+		root, err := git(dir, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return nil, fmt.Errorf("%s is not in a git repository: %s", pkgName, err)
+		}
+
+		tree, ok := trees[root]
+		if !ok {
+			if tree, err = gitArchive(root, rev); err != nil {
+				return nil, fmt.Errorf("unable to get the sources at %s: %s", rev, err)
+			}
+			trees[root] = tree
+			modules[root] = make(map[string]bool)
+		}
+
+		if mod := moduleRoot(dir); mod != "" {
+			modules[root][mod] = true
+		} else {
+			modules[root][root] = true
+		}
+
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		cur, err := typeCheckDir(pkgName, dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pkgName, err)
+		}
+
+		oldDir := filepath.Join(tree, rel)
+		if !hasGoFiles(oldDir) {
+			changes = append(changes, &APIChange{
+				ImportPath: pkgName,
+				Kind:       "added",
+				New:        "package " + pkgName,
+				Compatible: true,
+			})
+			continue
+		}
+
+		old, err := typeCheckDir(pkgName, oldDir)
+		if err != nil {
+			return nil, fmt.Errorf("%s at %s: %s", pkgName, rev, err)
+		}
+
+		for _, c := range apidiff.Changes(old, cur).Changes {
+			changes = append(changes, &APIChange{
+				ImportPath: pkgName,
+				Kind:       apidiffKind(c.Message),
+				Message:    c.Message,
+				Compatible: c.Compatible,
+			})
+		}
+	}
+
+	for root, mods := range modules {
+		removed, err := removedPackages(root, trees[root], mods, current)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, removed...)
+	}
+
+	return changes, nil
+}
+
+// removedPackages returns the packages in the given modules of the
+// repository at root, as extracted to tree, whose directories no longer
+// have any Go file and are not among the current ones.
+func removedPackages(root, tree string, mods, current map[string]bool) ([]*APIChange, error) {
+	var changes []*APIChange
+	for mod := range mods {
+		rel, err := filepath.Rel(root, mod)
+		if err != nil {
+			return nil, err
+		}
+
+		dirs, err := packageDirsBelow(filepath.Join(tree, rel))
+		if err != nil {
+			continue
+		}
+
+		for _, oldDir := range dirs {
+			rel, err := filepath.Rel(tree, oldDir)
+			if err != nil {
+				return nil, err
+			}
+
+			dir := filepath.Join(root, rel)
+			if current[dir] || hasGoFiles(dir) {
+				continue
+			}
+
+			path, err := dirImportPath(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			changes = append(changes, &APIChange{
+				ImportPath: path,
+				Kind:       "removed",
+				Old:        "package " + path,
+			})
+		}
+	}
+	return changes, nil
+}
+
+// apidiffKind returns the kind of change an apidiff message describes.
+func apidiffKind(msg string) string {
+	switch {
+	case strings.HasSuffix(msg, ": removed"):
+		return "removed"
+	case strings.HasSuffix(msg, ": added"):
+		return "added"
+	}
+	return "changed"
+}
+
+// gitArchive writes the files of the repository at the given root, as they
+// were at the given revision, to a new temporary directory and returns it.
+func gitArchive(root, rev string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "archive", "--format=tar", rev)
+	cmd.Dir = root
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "godocjson-")
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	err = extractTar(dir, out)
+	// The rest of the output is discarded so git is not blocked writing it.
+	io.Copy(io.Discard, out)
+	if werr := cmd.Wait(); werr != nil {
+		err = werr
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = &gitError{werr, msg}
+		}
+	}
+
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// extractTar writes the directories and regular files of the tar archive
+// to the given directory.
+func extractTar(dir string, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(h.Name))
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file %s in archive", h.Name)
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg:
+			err = writeTarFile(path, tr)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeTarFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// typeCheckDir type-checks the package in the given directory. Its type
+// errors are reported as warnings and, unlike when documenting packages,
+// fail the check, as apidiff would compare the types of the package left
+// invalid by them as if they were right.
+func typeCheckDir(pkgName, dir string) (*types.Package, error) {
+	names, err := sourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	pkg, _, parseErrors, err := parsePackage(fset, dir, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files with syntax errors are left out by parsePackage, which would
+	// make their declarations look removed.
+	if len(parseErrors) > 0 {
+		return nil, fmt.Errorf("does not parse, found %d error(s)", len(parseErrors))
+	}
+
+	var files = make([]*ast.File, 0, len(pkg.Files))
+	for _, f := range pkg.Files {
+		files = append(files, f)
+	}
+
+	var errs int
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			warnf("type-checking %s: %s", pkgName, err)
+			errs++
+		},
+	}
+
+	tpkg, _ := conf.Check(pkgName, fset, files, nil)
+	if errs > 0 {
+		return nil, fmt.Errorf("does not type-check, found %d error(s)", errs)
+	}
+	return tpkg, nil
+}
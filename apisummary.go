@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+)
+
+// apiSummaryWriter writes one line per exported symbol, in the format of
+// the api/*.txt files of the Go distribution:
+//
+//	pkg bytes, func Compare([]byte, []byte) int
+//	pkg bytes, method (*Buffer) Len() int
+//	pkg bytes, type Buffer struct
+type apiSummaryWriter struct {
+	w *bufio.Writer
+}
+
+func newAPISummaryWriter(w io.Writer, list bool) packageWriter {
+	return &apiSummaryWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *apiSummaryWriter) Write(pkg *Pkg) error {
+	for _, line := range apiSummary(pkg) {
+		if _, err := fmt.Fprintln(w.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *apiSummaryWriter) Close() error {
+	return w.w.Flush()
+}
+
+// apiSummary returns the sorted lines of the API summary of a package.
+func apiSummary(pkg *Pkg) []string {
+	path := pkg.ImportPath
+	if path == "" {
+		path = pkg.Name
+	}
+
+	var (
+		lines  []string
+		prefix = "pkg " + path + ", "
+	)
+
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, prefix+fmt.Sprintf(format, args...))
+	}
+
+	addValues := func(values []*Value) {
+		for _, v := range values {
+			for _, l := range valueSummary(v) {
+				add("%s", l)
+			}
+		}
+	}
+
+	addFuncs := func(funcs []*Func) {
+		for _, f := range funcs {
+			if !ast.IsExported(f.Name) {
+				continue
+			}
+
+			if f.Recv != "" {
+				add("method (%s) %s%s", f.Recv, f.Name, signatureSummary(f))
+			} else {
+				add("func %s%s", f.Name, signatureSummary(f))
+			}
+		}
+	}
+
+	addValues(pkg.Consts)
+	addValues(pkg.Vars)
+	addFuncs(pkg.Funcs)
+	for _, t := range pkg.Types {
+		for _, l := range typeSummary(t) {
+			add("%s", l)
+		}
+		addValues(t.Consts)
+		addValues(t.Vars)
+		addFuncs(t.Funcs)
+		addFuncs(t.Methods)
+	}
+
+	sort.Strings(lines)
+	return lines
+}
+
+// signatureSummary returns the parameters and results of a function without
+// their names.
+func signatureSummary(f *Func) string {
+	var params = make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = p.Type
+	}
+
+	sig := "(" + strings.Join(params, ", ") + ")"
+	switch len(f.Results) {
+	case 0:
+		return sig
+	case 1:
+		return sig + " " + f.Results[0].Type
+	}
+
+	var results = make([]string, len(f.Results))
+	for i, r := range f.Results {
+		results[i] = r.Type
+	}
+	return sig + " (" + strings.Join(results, ", ") + ")"
+}
+
+// parseDecl parses the declaration of a symbol as printed in its Decl.
+func parseDecl(decl string) (*ast.GenDecl, bool) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+decl, 0)
+	if err != nil || len(f.Decls) != 1 {
+		return nil, false
+	}
+
+	gd, ok := f.Decls[0].(*ast.GenDecl)
+	return gd, ok
+}
+
+func typeSummary(t *Type) []string {
+	decl, ok := parseDecl(t.Decl)
+	if !ok {
+		return []string{"type " + t.Name}
+	}
+
+	ts := typeSpec(decl)
+	if ts == nil {
+		return []string{"type " + t.Name}
+	}
+
+	head := "type " + t.Name
+	if ts.Assign.IsValid() {
+		head += " ="
+	}
+
+	switch typ := ts.Type.(type) {
+	case *ast.StructType:
+		lines := []string{head + " struct"}
+		for _, f := range t.Fields {
+			if !ast.IsExported(f.Name) {
+				continue
+			}
+
+			if f.Embedded {
+				lines = append(lines, fmt.Sprintf("%s struct, embedded %s", head, f.Type))
+			} else {
+				lines = append(lines, fmt.Sprintf("%s struct, %s %s", head, f.Name, f.Type))
+			}
+		}
+		return lines
+	case *ast.InterfaceType:
+		var (
+			names []string
+			lines []string
+		)
+
+		for _, m := range typ.Methods.List {
+			if len(m.Names) == 0 {
+				// Embedded interfaces are listed as they are.
+				name := types.ExprString(m.Type)
+				names = append(names, name)
+				lines = append(lines, fmt.Sprintf("%s interface, embedded %s", head, name))
+				continue
+			}
+
+			for _, n := range m.Names {
+				if !ast.IsExported(n.Name) {
+					continue
+				}
+
+				names = append(names, n.Name)
+				sig := strings.TrimPrefix(types.ExprString(m.Type), "func")
+				lines = append(lines, fmt.Sprintf("%s interface, %s%s", head, n.Name, sig))
+			}
+		}
+
+		sort.Strings(names)
+		return append([]string{fmt.Sprintf("%s interface { %s }", head, strings.Join(names, ", "))}, lines...)
+	default:
+		return []string{head + " " + types.ExprString(ts.Type)}
+	}
+}
+
+// valueSummary returns a line for every exported name of a const or var
+// declaration. Constants without a type that inherit it from a previous spec
+// of an iota group get the inherited one.
+func valueSummary(v *Value) []string {
+	decl, ok := parseDecl(v.Decl)
+	if !ok {
+		return nil
+	}
+
+	kind := "var"
+	if decl.Tok == token.CONST {
+		kind = "const"
+	}
+
+	var (
+		lines   []string
+		lastTyp string
+		i       int
+	)
+
+	for _, spec := range decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		typ := ""
+		if vs.Type != nil {
+			typ = types.ExprString(vs.Type)
+		} else if kind == "const" && len(vs.Values) == 0 {
+			typ = lastTyp
+		}
+
+		if kind == "const" && (vs.Type != nil || len(vs.Values) > 0) {
+			lastTyp = typ
+		}
+
+		for j, n := range vs.Names {
+			// Resolved or inferred types are always more precise, except for
+			// untyped constants, which are better described by their value.
+			t := typ
+			if i < len(v.Types) && v.Types[i] != "" && !strings.HasPrefix(v.Types[i], "untyped ") {
+				t = v.Types[i]
+			}
+			i++
+
+			if !ast.IsExported(n.Name) {
+				continue
+			}
+
+			switch {
+			case t != "":
+				lines = append(lines, fmt.Sprintf("%s %s %s", kind, n.Name, t))
+			case j < len(vs.Values):
+				lines = append(lines, fmt.Sprintf("%s %s = %s", kind, n.Name, types.ExprString(vs.Values[j])))
+			default:
+				lines = append(lines, fmt.Sprintf("%s %s", kind, n.Name))
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"os"
+	"strings"
+)
+
+var batchFile = flag.String("batch", "", "read the packages to document, as import paths, directories or patterns, one per line from the given file, or - for the standard input")
+
+// readBatch returns the packages listed in the given file, or the standard
+// input if it is "-". Blank lines and lines starting with # are ignored.
+func readBatch(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var pkgs []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pkgs = append(pkgs, line)
+	}
+	return pkgs, s.Err()
+}
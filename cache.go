@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var cacheDir = flag.String("cache-dir", "", "directory where the documentation of each package is cached between runs")
+
+// nonCacheableFlags are the flags that have no effect on the generated
+// documentation, so they are not part of the cache key.
+var nonCacheableFlags = map[string]bool{
+	"cache-dir":    true,
+	"concurrency":  true,
+	"doc-overlay":  true,
+	"git":          true,
+	"incremental":  true,
+	"j":            true,
+	"log-format":   true,
+	"no-progress":  true,
+	"openapi":      true,
+	"q":            true,
+	"readme":       true,
+	"since":        true,
+	"since-api":    true,
+	"symbol-index": true,
+	"v":            true,
+	"vv":           true,
+	"version":      true,
+	"with-deps":    true,
+}
+
+// cacheKey returns the key under which the documentation of the package in
+// srcDir made of the given files is cached. It covers the contents of all
+// files, the tool version and any flag affecting the output.
+func cacheKey(pkgName, srcDir string, files []string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "version:%s\n", generatorVersion())
+	fmt.Fprintf(h, "package:%s\ndir:%s\n", pkgName, srcDir)
+	flag.VisitAll(func(f *flag.Flag) {
+		if !nonCacheableFlags[f.Name] {
+			fmt.Fprintf(h, "flag:%s=%s\n", f.Name, f.Value)
+		}
+	})
+
+	for _, name := range files {
+		fmt.Fprintf(h, "file:%s\n", name)
+		f, err := os.Open(filepath.Join(srcDir, name))
+		if err != nil {
+			return "", err
+		}
+
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The go.mod file of the module is part of the documentation too, as
+	// the Go version and the dependencies come from it.
+	if root := moduleRoot(srcDir); root != "" {
+		if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+			fmt.Fprintf(h, "go.mod:%x\n", sha256.Sum256(data))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func cachePath(key string) string {
+	return filepath.Join(*cacheDir, key[:2], key+".json")
+}
+
+// loadCached returns the cached documentation for the given key, if any.
+func loadCached(key string) (*Pkg, bool) {
+	data, err := os.ReadFile(cachePath(key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errorf("unable to read cache entry %s: %s", key, err)
+		}
+		return nil, false
+	}
+
+	var pkg Pkg
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		errorf("ignoring corrupt cache entry %s: %s", key, err)
+		return nil, false
+	}
+
+	return &pkg, true
+}
+
+// storeCached saves the documentation of a package under the given key.
+// Failing to do so is not fatal, as it will just be generated again on the
+// next run.
+func storeCached(key string, pkg *Pkg) {
+	if err := writeCached(key, pkg); err != nil {
+		errorf("unable to write cache entry %s: %s", key, err)
+	}
+}
+
+func writeCached(key string, pkg *Pkg) error {
+	data, err := json.Marshal(pkg)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(cachePath(key), data)
+}
+
+// writeFileAtomic writes a file of the cache, creating its directory if
+// needed.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent runs never see partial
+	// entries.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+var withCallGraph = flag.Bool("callgraph", false, "type-check packages to list the calls between their documented functions, with interface method calls resolved to the types of the package implementing them")
+
+// callNode is a function of the package in the call graph.
+type callNode struct {
+	name       string
+	documented bool
+	body       *ast.BlockStmt
+	calls      []*callEdge
+}
+
+type callEdge struct {
+	callee  *callNode
+	dynamic bool
+	pos     token.Pos
+}
+
+// NewCallGraph returns the calls between the documented functions of the
+// package, following the calls made through unexported functions. Calls
+// through interfaces are resolved with class hierarchy analysis: every
+// type of the package implementing the interface is assumed to be called.
+// It needs the bodies of the functions, so it must be built before the
+// documentation.
+func NewCallGraph(src *Source, info *types.Info) []*Call {
+	var (
+		nodes    = make(map[*types.Func]*callNode)
+		concrete []*types.Named
+		order    []*types.Func
+	)
+
+	for _, f := range src.Files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+
+			obj, ok := info.Defs[fn.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+
+			node := &callNode{name: fn.Name.Name, documented: fn.Name.IsExported(), body: src.Bodies[fn]}
+			if fn.Recv != nil && len(fn.Recv.List) > 0 {
+				recv := embeddedName(fn.Recv.List[0].Type)
+				node.name = recv + "." + fn.Name.Name
+				node.documented = node.documented && token.IsExported(recv)
+			}
+
+			nodes[obj] = node
+			order = append(order, obj)
+		}
+	}
+
+	for _, obj := range info.Defs {
+		tn, ok := obj.(*types.TypeName)
+		if !ok || tn.IsAlias() || tn.Parent() != tn.Pkg().Scope() {
+			continue
+		}
+
+		if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() == 0 && !types.IsInterface(named) {
+			concrete = append(concrete, named)
+		}
+	}
+
+	for _, fn := range order {
+		node := nodes[fn]
+		if node.body == nil {
+			continue
+		}
+
+		ast.Inspect(node.body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			callee, iface := calledFunc(call, info)
+			if callee == nil || callee == fn {
+				return true
+			}
+
+			if iface == nil {
+				if target, ok := nodes[callee.Origin()]; ok {
+					node.calls = append(node.calls, &callEdge{callee: target, pos: call.Pos()})
+				}
+				return true
+			}
+
+			for _, t := range concrete {
+				if impl := implementation(t, iface); impl == nil || !impl.Implements {
+					continue
+				}
+
+				obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, callee.Pkg(), callee.Name())
+				if m, ok := obj.(*types.Func); ok {
+					if target, ok := nodes[m.Origin()]; ok {
+						node.calls = append(node.calls, &callEdge{callee: target, dynamic: true, pos: call.Pos()})
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	var calls = []*Call{}
+	for _, fn := range order {
+		if node := nodes[fn]; node.documented {
+			calls = append(calls, reachableCalls(node, src.Fset)...)
+		}
+	}
+
+	sort.SliceStable(calls, func(i, j int) bool {
+		if calls[i].Caller != calls[j].Caller {
+			return calls[i].Caller < calls[j].Caller
+		}
+		return calls[i].Callee < calls[j].Callee
+	})
+	return calls
+}
+
+// reachableCalls returns the documented functions called by the given one,
+// either directly or through undocumented ones, which are searched breadth
+// first so the most direct call to each function is the one reported.
+func reachableCalls(caller *callNode, fset *token.FileSet) []*Call {
+	type step struct {
+		node     *callNode
+		dynamic  bool
+		indirect bool
+		pos      token.Pos
+	}
+
+	var (
+		calls   []*Call
+		visited = map[*callNode]bool{caller: true}
+		queue   []step
+	)
+
+	for _, e := range caller.calls {
+		queue = append(queue, step{e.callee, e.dynamic, false, e.pos})
+	}
+
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if visited[s.node] {
+			continue
+		}
+		visited[s.node] = true
+
+		if s.node.documented {
+			calls = append(calls, &Call{
+				Caller:   caller.name,
+				Callee:   s.node.name,
+				Dynamic:  s.dynamic,
+				Indirect: s.indirect,
+				Pos:      NewFilePos(s.pos, fset),
+			})
+			continue
+		}
+
+		for _, e := range s.node.calls {
+			queue = append(queue, step{e.callee, s.dynamic || e.dynamic, true, s.pos})
+		}
+	}
+	return calls
+}
+
+// calledFunc returns the function or method called, along with the
+// interface it belongs to if it is called through one. It is nil for calls
+// of function values, conversions and builtins.
+func calledFunc(call *ast.CallExpr, info *types.Info) (*types.Func, *types.Interface) {
+	fun := ast.Unparen(call.Fun)
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+
+	switch f := fun.(type) {
+	case *ast.Ident:
+		fn, _ := info.Uses[f].(*types.Func)
+		return fn, nil
+	case *ast.SelectorExpr:
+		sel, ok := info.Selections[f]
+		if !ok {
+			// A qualified identifier of another package.
+			fn, _ := info.Uses[f.Sel].(*types.Func)
+			return fn, nil
+		}
+
+		fn, ok := sel.Obj().(*types.Func)
+		if !ok {
+			return nil, nil
+		}
+
+		if iface, ok := sel.Recv().Underlying().(*types.Interface); ok && sel.Kind() == types.MethodVal {
+			return fn, iface
+		}
+		return fn, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborSimple = 7
+)
+
+// cborWriter writes packages in CBOR (RFC 8949), with the same data model
+// as their JSON encoding: the same objects, as maps with their keys in the
+// same order, arrays, strings, numbers, booleans and nulls. Packages are
+// written as an array of indefinite length if many may be written, so they
+// are still streamed.
+type cborWriter struct {
+	w    *bufio.Writer
+	list bool
+	n    int
+}
+
+func newCBORWriter(w io.Writer, list bool) packageWriter {
+	return &cborWriter{w: bufio.NewWriter(w), list: list}
+}
+
+func (w *cborWriter) Write(pkg *Pkg) error {
+	if w.list && w.n == 0 {
+		w.w.WriteByte(cborArray<<5 | 31)
+	}
+	w.n++
+
+	data, err := json.Marshal(outputValue(pkg))
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := readJSONValue(dec)
+	if err != nil {
+		return err
+	}
+	return encodeCBOR(w.w, v)
+}
+
+func (w *cborWriter) Close() error {
+	if w.list {
+		end := byte(0xff)
+		if w.n == 0 {
+			end = cborArray << 5
+		}
+
+		if err := w.w.WriteByte(end); err != nil {
+			return err
+		}
+	}
+
+	return w.w.Flush()
+}
+
+// jsonObject is a JSON object with its keys in order.
+type jsonObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// readJSONValue reads the next JSON value of the decoder, with objects as
+// jsonObject, arrays as slices and numbers as json.Number. Maps and arrays
+// need to be read whole, as their length comes before their elements.
+func readJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		var list = []interface{}{}
+		for dec.More() {
+			v, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := dec.Token()
+		return list, err
+	case json.Delim('{'):
+		var obj = new(jsonObject)
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key.(string))
+			obj.values = append(obj.values, v)
+		}
+		_, err := dec.Token()
+		return obj, err
+	}
+	return tok, nil
+}
+
+func encodeCBOR(w *bufio.Writer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		w.WriteByte(cborSimple<<5 | 22)
+	case bool:
+		if v {
+			w.WriteByte(cborSimple<<5 | 21)
+		} else {
+			w.WriteByte(cborSimple<<5 | 20)
+		}
+	case string:
+		writeCBORHead(w, cborText, uint64(len(v)))
+		w.WriteString(v)
+	case json.Number:
+		return encodeCBORNumber(w, v)
+	case []interface{}:
+		writeCBORHead(w, cborArray, uint64(len(v)))
+		for _, elem := range v {
+			if err := encodeCBOR(w, elem); err != nil {
+				return err
+			}
+		}
+	case *jsonObject:
+		writeCBORHead(w, cborMap, uint64(len(v.keys)))
+		for i, key := range v.keys {
+			writeCBORHead(w, cborText, uint64(len(key)))
+			w.WriteString(key)
+			if err := encodeCBOR(w, v.values[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unexpected JSON value of type %T", v)
+	}
+	return nil
+}
+
+// encodeCBORNumber writes a number as an integer if it is one, otherwise
+// as a float, in single precision if it can be without losing any.
+func encodeCBORNumber(w *bufio.Writer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		if i >= 0 {
+			writeCBORHead(w, cborUint, uint64(i))
+		} else {
+			writeCBORHead(w, cborNegint, uint64(-1-i))
+		}
+		return nil
+	}
+
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		writeCBORHead(w, cborUint, u)
+		return nil
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+
+	if float64(float32(f)) == f {
+		var buf [5]byte
+		buf[0] = cborSimple<<5 | 26
+		binary.BigEndian.PutUint32(buf[1:], math.Float32bits(float32(f)))
+		w.Write(buf[:])
+		return nil
+	}
+
+	var buf [9]byte
+	buf[0] = cborSimple<<5 | 27
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(f))
+	w.Write(buf[:])
+	return nil
+}
+
+// writeCBORHead writes the initial byte of an item of the given major type
+// with its argument, which is its value or length, in as few bytes as
+// possible.
+func writeCBORHead(w *bufio.Writer, major byte, n uint64) {
+	var buf [9]byte
+	switch {
+	case n < 24:
+		w.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf[0], buf[1] = major<<5|24, byte(n)
+		w.Write(buf[:2])
+	case n <= math.MaxUint16:
+		buf[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(buf[1:], uint16(n))
+		w.Write(buf[:3])
+	case n <= math.MaxUint32:
+		buf[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(buf[1:], uint32(n))
+		w.Write(buf[:5])
+	default:
+		buf[0] = major<<5 | 27
+		binary.BigEndian.PutUint64(buf[1:], n)
+		w.Write(buf[:])
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeCBOR(t *testing.T) {
+	// Examples of RFC 8949, Appendix A.
+	testCases := []struct {
+		value interface{}
+		want  string
+	}{
+		{json.Number("0"), "00"},
+		{json.Number("23"), "17"},
+		{json.Number("24"), "1818"},
+		{json.Number("1000"), "1903e8"},
+		{json.Number("1000000"), "1a000f4240"},
+		{json.Number("1000000000000"), "1b000000e8d4a51000"},
+		{json.Number("18446744073709551615"), "1bffffffffffffffff"},
+		{json.Number("-1"), "20"},
+		{json.Number("-1000"), "3903e7"},
+		{json.Number("1.5"), "fa3fc00000"},
+		{json.Number("1.1"), "fb3ff199999999999a"},
+		{json.Number("1e+300"), "fb7e37e43c8800759c"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]interface{}{}, "80"},
+		{[]interface{}{json.Number("1"), []interface{}{json.Number("2"), json.Number("3")}}, "8201820203"},
+		{&jsonObject{}, "a0"},
+		{&jsonObject{keys: []string{"a", "b"}, values: []interface{}{json.Number("1"), []interface{}{json.Number("2")}}}, "a261610161628102"},
+	}
+
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		if err := encodeCBOR(w, tc.value); err != nil {
+			t.Fatalf("%v: unexpected error: %s", tc.value, err)
+		}
+		w.Flush()
+
+		if got := hex.EncodeToString(buf.Bytes()); got != tc.want {
+			t.Errorf("%v: expecting %s, got %s", tc.value, tc.want, got)
+		}
+	}
+}
+
+// TestCBORWriter checks that the CBOR of packages decodes to the same data
+// model as their JSON, keys in the same order included.
+func TestCBORWriter(t *testing.T) {
+	pkg, err := extract("container/list")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pkgs := []*Pkg{pkg, {Name: "empty", ImportPath: "example.com/empty"}}
+	for _, list := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := newCBORWriter(&buf, list)
+		for _, pkg := range pkgs {
+			if err := w.Write(pkg); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !list {
+				break
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		r := bytes.NewReader(buf.Bytes())
+		got, err := decodeCBOR(r)
+		if err != nil {
+			t.Fatalf("unexpected error decoding: %s", err)
+		}
+
+		if r.Len() > 0 {
+			t.Errorf("%d bytes left after the CBOR item", r.Len())
+		}
+
+		var want interface{}
+		if list {
+			var items []interface{}
+			for _, pkg := range pkgs {
+				items = append(items, jsonModel(t, pkg))
+			}
+			want = items
+		} else {
+			want = jsonModel(t, pkg)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("CBOR with list %v does not decode to the JSON model", list)
+		}
+	}
+}
+
+func TestCBORWriterEmptyList(t *testing.T) {
+	var buf bytes.Buffer
+	w := newCBORWriter(&buf, true)
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got := hex.EncodeToString(buf.Bytes()); got != "80" {
+		t.Errorf("expecting an empty array, got %s", got)
+	}
+}
+
+// jsonModel returns the JSON of a package read back with readJSONValue,
+// with numbers as float64 as decodeCBOR returns them.
+func jsonModel(t *testing.T, pkg *Pkg) interface{} {
+	t.Helper()
+	data, err := json.Marshal(outputValue(pkg))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	v, err := readJSONValue(json.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return v
+}
+
+// decodeCBOR decodes the next CBOR item, supporting only what cborWriter
+// writes, with maps as jsonObject, arrays as slices and numbers as float64.
+func decodeCBOR(r *bytes.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	major, info := b>>5, b&31
+	if major == cborArray && info == 31 {
+		var list = []interface{}{}
+		for {
+			if next, err := r.ReadByte(); err != nil {
+				return nil, err
+			} else if next == 0xff {
+				return list, nil
+			}
+			r.UnreadByte()
+
+			v, err := decodeCBOR(r)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	}
+
+	if major == cborSimple {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		case 26:
+			var bits uint32
+			err := binary.Read(r, binary.BigEndian, &bits)
+			return float64(math.Float32frombits(bits)), err
+		case 27:
+			var bits uint64
+			err := binary.Read(r, binary.BigEndian, &bits)
+			return math.Float64frombits(bits), err
+		}
+		return nil, fmt.Errorf("unexpected simple value %d", info)
+	}
+
+	n, err := readCBORArgument(r, info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		return float64(n), nil
+	case cborNegint:
+		return -1 - float64(n), nil
+	case cborText:
+		data := make([]byte, n)
+		_, err := io.ReadFull(r, data)
+		return string(data), err
+	case cborArray:
+		var list = make([]interface{}, n)
+		for i := range list {
+			if list[i], err = decodeCBOR(r); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case cborMap:
+		var obj = new(jsonObject)
+		for i := uint64(0); i < n; i++ {
+			key, err := decodeCBOR(r)
+			if err != nil {
+				return nil, err
+			}
+
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected map key of type %T", key)
+			}
+
+			v, err := decodeCBOR(r)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, s)
+			obj.values = append(obj.values, v)
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unexpected major type %d", major)
+}
+
+func readCBORArgument(r *bytes.Reader, info byte) (uint64, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, fmt.Errorf("unexpected additional information %d", info)
+	}
+
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
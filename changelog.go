@@ -0,0 +1,475 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/build"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Changelog are the changes in the API of a module across its releases.
+type Changelog struct {
+	Module string
+	// Releases are the releases of the module after the first version of
+	// the range, from the oldest to the newest, each with its changes
+	// since the previous one.
+	Releases []*Release
+}
+
+// Release are the changes in the API of a release since the previous one.
+type Release struct {
+	Version  string
+	Previous string
+	Changes  []*APIChange
+	// Deprecated are the symbols deprecated in the release.
+	Deprecated []*Deprecation
+}
+
+// Deprecation is a package or symbol marked as deprecated in a release.
+type Deprecation struct {
+	ImportPath string
+	// Name is the name of the symbol, with methods as "Type.Method", or
+	// empty if the whole package is deprecated.
+	Name   string `json:",omitempty"`
+	Notice string
+}
+
+func runChangelog(args []string) {
+	fs := flag.NewFlagSet("changelog", flag.ExitOnError)
+	changelogFormat := fs.String("format", "json", "format of the changelog: json or markdown")
+	module := fs.String("module", "", "module whose versions to read from the module cache, instead of the tags of the git repository of the current directory")
+	addEnvFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson changelog [-format json|markdown] [-module path] from..to\n\n"+
+			"Compares the exported API of every release of a module from one version to\n"+
+			"another with the previous one, listing the symbols added, removed, changed\n"+
+			"and deprecated in each of them. Releases are the tags of the git repository\n"+
+			"of the module in the current directory, or the versions of the module in the\n"+
+			"module cache with -module.\n\n")
+		fs.PrintDefaults()
+	}
+	rest := parseInterspersed(fs, args)
+	if err := applyEnvFlags(); err != nil {
+		fatalf("%s", err)
+	}
+
+	if len(rest) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	from, to, ok := strings.Cut(rest[0], "..")
+	if !ok || from == "" || to == "" {
+		fatalf("invalid range %q: expecting from..to, such as v1.2.0..v1.4.0", rest[0])
+	}
+
+	if *changelogFormat != "json" && *changelogFormat != "markdown" {
+		fatalf("invalid -format %q: expecting json or markdown", *changelogFormat)
+	}
+
+	var src releaseSource
+	if *module != "" {
+		src = &moduleCacheReleases{path: *module, dir: moduleCacheDir()}
+	} else {
+		wd, err := os.Getwd()
+		if err != nil {
+			fatalf("%s", err)
+		}
+
+		if src, err = newGitReleases(wd); err != nil {
+			fatalf("%s", err)
+		}
+	}
+
+	log, err := newChangelog(src, from, to)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if *changelogFormat == "markdown" {
+		printChangelog(os.Stdout, log)
+		return
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(log); err != nil {
+		fatalf("%s", err)
+	}
+}
+
+// releaseSource reads the documentation of the packages of a module at each
+// of its versions.
+type releaseSource interface {
+	// module returns the path of the module.
+	module() string
+	// versions returns all the versions of the module, from the oldest to
+	// the newest.
+	versions() ([]string, error)
+	// packages returns the documentation of all the packages of the module
+	// at the given version.
+	packages(version string) ([]*Pkg, error)
+}
+
+// newChangelog returns the changes of every release of the module from the
+// first version to the last, including releases in between.
+func newChangelog(src releaseSource, from, to string) (*Changelog, error) {
+	all, err := src.versions()
+	if err != nil {
+		return nil, err
+	}
+
+	var versions = []string{from}
+	for _, v := range all {
+		if compareVersions(v, from) > 0 && compareVersions(v, to) < 0 {
+			versions = append(versions, v)
+		}
+	}
+	versions = append(versions, to)
+
+	log := &Changelog{Module: src.module(), Releases: []*Release{}}
+	prev, err := src.packages(from)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", from, err)
+	}
+
+	for i := 1; i < len(versions); i++ {
+		infof("comparing %s with %s", versions[i], versions[i-1])
+		cur, err := src.packages(versions[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", versions[i], err)
+		}
+
+		log.Releases = append(log.Releases, &Release{
+			Version:    versions[i],
+			Previous:   versions[i-1],
+			Changes:    releaseChanges(prev, cur),
+			Deprecated: newDeprecations(prev, cur),
+		})
+		prev = cur
+	}
+
+	return log, nil
+}
+
+// releaseChanges returns the changes in the API from the packages of a
+// release to those of the next one, including the packages added.
+func releaseChanges(prev, cur []*Pkg) []*APIChange {
+	changes := compareAPI(prev, cur)
+	var existed = make(map[string]bool)
+	for _, pkg := range prev {
+		existed[pkg.ImportPath] = true
+	}
+
+	for _, pkg := range cur {
+		if !existed[pkg.ImportPath] {
+			changes = append(changes, &APIChange{
+				ImportPath: pkg.ImportPath,
+				Kind:       "added",
+				New:        "package " + pkg.ImportPath,
+				Compatible: true,
+			})
+		}
+	}
+
+	if changes == nil {
+		changes = []*APIChange{}
+	}
+	return changes
+}
+
+// newDeprecations returns the packages and exported symbols deprecated in
+// the current packages that were not in the previous ones.
+func newDeprecations(prev, cur []*Pkg) []*Deprecation {
+	var before = make(map[string]map[string]string)
+	for _, pkg := range prev {
+		before[pkg.ImportPath] = deprecatedSymbols(pkg)
+	}
+
+	var list = []*Deprecation{}
+	for _, pkg := range cur {
+		old := before[pkg.ImportPath]
+		for name, notice := range deprecatedSymbols(pkg) {
+			if _, ok := old[name]; !ok {
+				list = append(list, &Deprecation{ImportPath: pkg.ImportPath, Name: name, Notice: notice})
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ImportPath != list[j].ImportPath {
+			return list[i].ImportPath < list[j].ImportPath
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// deprecatedSymbols returns the deprecation notices of the exported symbols
+// of the package by name, with that of the package itself under an empty
+// name.
+func deprecatedSymbols(pkg *Pkg) map[string]string {
+	var notices = make(map[string]string)
+	if pkg.Deprecated != "" {
+		notices[""] = pkg.Deprecated
+	}
+
+	for _, sym := range packageSymbols(pkg) {
+		typ, method, isMethod := strings.Cut(sym.Name, ".")
+		if !token.IsExported(typ) || (isMethod && !token.IsExported(method)) {
+			continue
+		}
+
+		var notice string
+		switch {
+		case sym.Type != nil:
+			notice = sym.Type.Deprecated
+		case sym.Func != nil:
+			notice = sym.Func.Deprecated
+		case sym.Value != nil:
+			notice = sym.Value.Deprecated
+		}
+
+		if notice != "" {
+			notices[sym.Name] = notice
+		}
+	}
+	return notices
+}
+
+// printChangelog writes the changelog in Markdown, with a section for each
+// release from the newest to the oldest, as changelogs usually are.
+func printChangelog(w io.Writer, log *Changelog) {
+	fmt.Fprintf(w, "# Changelog of %s\n", log.Module)
+	for i := len(log.Releases) - 1; i >= 0; i-- {
+		r := log.Releases[i]
+		fmt.Fprintf(w, "\n## %s\n", r.Version)
+		if len(r.Changes) == 0 && len(r.Deprecated) == 0 {
+			fmt.Fprintf(w, "\nNo API changes since %s.\n", r.Previous)
+			continue
+		}
+
+		var byKind = make(map[string][]string)
+		for _, c := range r.Changes {
+			var line string
+			switch {
+			case c.Message != "":
+				line = c.Message
+			case c.Kind == "removed":
+				line = c.Old
+			case c.Kind == "added":
+				line = c.New
+			default:
+				line = c.Old + "` is now `" + c.New
+			}
+
+			note := ""
+			if !c.Compatible {
+				note = " (incompatible)"
+			}
+			byKind[c.Kind] = append(byKind[c.Kind], fmt.Sprintf("- %s: `%s`%s", c.ImportPath, line, note))
+		}
+
+		for _, d := range r.Deprecated {
+			name := d.ImportPath
+			if d.Name != "" {
+				name += "." + d.Name
+			}
+			byKind["deprecated"] = append(byKind["deprecated"], fmt.Sprintf("- `%s`: %s", name, strings.Join(strings.Fields(d.Notice), " ")))
+		}
+
+		for _, kind := range []string{"added", "changed", "deprecated", "removed"} {
+			if len(byKind[kind]) == 0 {
+				continue
+			}
+
+			sort.Strings(byKind[kind])
+			fmt.Fprintf(w, "\n### %s%s\n\n%s\n", strings.ToUpper(kind[:1]), kind[1:], strings.Join(byKind[kind], "\n"))
+		}
+	}
+}
+
+// gitReleases are the release tags of the git repository of a module.
+type gitReleases struct {
+	root string
+	// rel is the directory of the module in the repository, and prefix
+	// that of its tags.
+	rel    string
+	prefix string
+	path   string
+}
+
+func newGitReleases(dir string) (*gitReleases, error) {
+	modRoot := moduleRoot(dir)
+	if modRoot == "" {
+		return nil, fmt.Errorf("%s is not in a module", dir)
+	}
+
+	root, err := git(modRoot, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %s", modRoot, err)
+	}
+
+	rel, err := filepath.Rel(root, modRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tags of modules in subdirectories are prefixed with their path.
+	var prefix string
+	if rel != "." {
+		prefix = filepath.ToSlash(rel) + "/"
+	}
+	return &gitReleases{root: root, rel: rel, prefix: prefix, path: modulePath(modRoot)}, nil
+}
+
+func (g *gitReleases) module() string {
+	return g.path
+}
+
+func (g *gitReleases) versions() ([]string, error) {
+	var versions []string
+	for _, tag := range releaseTags(g.root, g.prefix) {
+		versions = append(versions, strings.TrimPrefix(tag, g.prefix))
+	}
+	return versions, nil
+}
+
+func (g *gitReleases) packages(version string) ([]*Pkg, error) {
+	tree, err := gitArchive(g.root, g.prefix+version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the sources: %s", err)
+	}
+	defer os.RemoveAll(tree)
+
+	modDir := filepath.Join(tree, g.rel)
+	mod := modulePath(modDir)
+	if mod == "" {
+		return nil, fmt.Errorf("no go.mod file found in %s", g.rel)
+	}
+
+	dirs, err := packageDirsBelow(modDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []*Pkg
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(modDir, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		pkgName := mod
+		if rel != "." {
+			pkgName += "/" + filepath.ToSlash(rel)
+		}
+
+		pkg, err := extractDir(pkgName, dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pkgName, err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// extractDir builds the documentation of the package with the given import
+// path in a directory, without caching it, as it is not where the package
+// is usually found.
+func extractDir(pkgName, dir string) (*Pkg, error) {
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	pkg, hashes, parseErrors, err := parsePackage(fset, dir, files)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := buildPkg(pkgName, fset, dir, pkg, nil, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ParseErrors = parseErrors
+	result.Hash = documentHash(result)
+	return result, nil
+}
+
+// moduleCacheReleases are the versions of a module downloaded to the module
+// cache.
+type moduleCacheReleases struct {
+	path string
+	dir  string
+}
+
+// moduleCacheDir returns the directory of the module cache, as the go
+// command finds it.
+func moduleCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+
+	if goModCache != "" && *gopathFlag == "" {
+		return goModCache
+	}
+	return filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+}
+
+func (m *moduleCacheReleases) module() string {
+	return m.path
+}
+
+// downloadDir returns the directory with the zips of the versions of the
+// module.
+func (m *moduleCacheReleases) downloadDir() string {
+	return filepath.Join(m.dir, "cache", "download", filepath.FromSlash(escapeModulePath(m.path)), "@v")
+}
+
+func (m *moduleCacheReleases) versions() ([]string, error) {
+	zips, err := filepath.Glob(filepath.Join(m.downloadDir(), "*.zip"))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, zip := range zips {
+		v := strings.TrimSuffix(filepath.Base(zip), ".zip")
+		// Versions are escaped as module paths are.
+		if strings.ContainsRune(v, '!') {
+			continue
+		}
+
+		if releaseTagRegexp.MatchString(v) {
+			versions = append(versions, v)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+func (m *moduleCacheReleases) packages(version string) ([]*Pkg, error) {
+	zip := filepath.Join(m.downloadDir(), escapeModulePath(version)+".zip")
+	if _, err := os.Stat(zip); err != nil {
+		return nil, fmt.Errorf("not in the module cache, download it with go mod download %s@%s", m.path, version)
+	}
+
+	var pkgs []*Pkg
+	err := extractZip(zip, m.path, version, func(pkg *Pkg) error {
+		pkgs = append(pkgs, pkg)
+		return nil
+	})
+	return pkgs, err
+}
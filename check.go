@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/erizocosmico/godocjson/schema"
+)
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	baseline := fs.String("baseline", "", "documentation previously generated by godocjson to compare against")
+	baselineRev := fs.String("baseline-rev", "", "git revision whose sources to compare against instead, type-checking both versions to compare them with apidiff")
+	fs.BoolVar(resolveTypes, "resolve-types", false, "type-check packages, use it if the baseline was generated with -resolve-types")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson check -baseline api.json|-baseline-rev rev [packages]\n\n"+
+			"Compares the exported API of the given packages, ./... by default, against a\n"+
+			"baseline and exits with a non-zero status if it changed incompatibly.\n\n"+
+			"With -baseline, declarations are compared as written, so any change to them\n"+
+			"is reported. With -baseline-rev, both versions are type-checked and compared\n"+
+			"with apidiff, which judges changes by their semantics, such as a parameter\n"+
+			"changing to an alias of the same type, and fails if either does not\n"+
+			"type-check.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if (*baseline == "") == (*baselineRev == "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	pkgNames, err := expandPatterns(patterns)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	var changes []*APIChange
+	if *baselineRev != "" {
+		if changes, err = compareRevision(*baselineRev, pkgNames); err != nil {
+			fatalf("%s", err)
+		}
+	} else {
+		base, err := loadPackages(*baseline)
+		if err != nil {
+			fatalf("unable to load baseline: %s", err)
+		}
+
+		var current []*Pkg
+		err = extractAll(context.Background(), pkgNames, func(pkg *Pkg) error {
+			current = append(current, pkg)
+			return nil
+		})
+		if err != nil {
+			fatalf("%s", err)
+		}
+		changes = compareAPI(base, current)
+	}
+
+	printAPIChanges(os.Stdout, changes)
+	for _, c := range changes {
+		if !c.Compatible {
+			os.Exit(1)
+		}
+	}
+}
+
+// loadPackages reads the packages in a file generated by godocjson, which
+// can hold a single package, a list of them or a corpus merging them.
+func loadPackages(path string) ([]*Pkg, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are matched exactly, as encoding/json would also read corpora
+	// generated with another -field-case, which schema.ReadPackages rejects.
+	var keys map[string]json.RawMessage
+	if json.Unmarshal(data, &keys) == nil && keys["Packages"] != nil {
+		var c Corpus
+		if err := json.Unmarshal(data, &c); err == nil && len(c.Packages) > 0 && c.Packages[0].Doc != nil {
+			return c.packages(), nil
+		}
+	}
+
+	return schema.ReadPackages(bytes.NewReader(data))
+}
+
+// APIChange is a change in the exported API of a package.
+type APIChange struct {
+	ImportPath string
+	// Kind is "removed", "changed" or "added".
+	Kind string
+	// Old and New are the declarations before and after the change, as
+	// lines of the API summary.
+	Old string `json:",omitempty"`
+	New string `json:",omitempty"`
+	// Message describes the change, as found by apidiff, instead of Old
+	// and New when comparing against a git revision.
+	Message    string `json:",omitempty"`
+	Compatible bool
+}
+
+// compareAPI returns the changes from the base packages to the current ones.
+func compareAPI(base, current []*Pkg) []*APIChange {
+	var (
+		changes []*APIChange
+		cur     = make(map[string]*Pkg)
+	)
+
+	for _, pkg := range current {
+		cur[pkg.ImportPath] = pkg
+	}
+
+	for _, b := range base {
+		c, ok := cur[b.ImportPath]
+		if !ok {
+			changes = append(changes, &APIChange{
+				ImportPath: b.ImportPath,
+				Kind:       "removed",
+				Old:        "package " + b.ImportPath,
+			})
+			continue
+		}
+
+		// Identical documents cannot have any API change.
+		if b.Hash != "" && b.Hash == c.Hash {
+			continue
+		}
+
+		changes = append(changes, comparePackageAPI(b.ImportPath, apiSymbols(b), apiSymbols(c))...)
+	}
+
+	return changes
+}
+
+func comparePackageAPI(path string, base, cur map[string]string) []*APIChange {
+	var changes []*APIChange
+	for key, old := range base {
+		switch new, ok := cur[key]; {
+		case !ok:
+			changes = append(changes, &APIChange{ImportPath: path, Kind: "removed", Old: old})
+		case new != old:
+			changes = append(changes, &APIChange{ImportPath: path, Kind: "changed", Old: old, New: new})
+		}
+	}
+
+	for key, new := range cur {
+		if _, ok := base[key]; ok {
+			continue
+		}
+
+		// New methods in an existing interface break its implementations.
+		typ, _, isMethod := strings.Cut(key, " interface, ")
+		_, existed := base[typ]
+		changes = append(changes, &APIChange{
+			ImportPath: path,
+			Kind:       "added",
+			New:        new,
+			Compatible: !isMethod || !existed,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind > changes[j].Kind
+		}
+		return changes[i].Old+changes[i].New < changes[j].Old+changes[j].New
+	})
+	return changes
+}
+
+// apiSymbols returns the lines of the API summary of a package without the
+// package prefix, indexed by the symbol they describe, such as "func Foo",
+// "type T struct, Field" or "type I interface, Method".
+func apiSymbols(pkg *Pkg) map[string]string {
+	var symbols = make(map[string]string)
+	for _, line := range apiSummary(pkg) {
+		_, line, _ = strings.Cut(line, ", ")
+
+		// The line listing all methods of an interface is redundant, as each
+		// of them has its own line.
+		if i := strings.Index(line, " interface { "); i >= 0 {
+			line = line[:i] + " interface"
+		}
+
+		symbols[apiSymbolKey(line)] = line
+	}
+	return symbols
+}
+
+func apiSymbolKey(line string) string {
+	if head, field, ok := strings.Cut(line, ", "); ok && strings.HasPrefix(line, "type ") {
+		if end := strings.IndexAny(field, " ("); end >= 0 && !strings.HasPrefix(field, "embedded ") {
+			field = field[:end]
+		}
+		return head + ", " + field
+	}
+
+	if strings.HasPrefix(line, "func ") {
+		return line[:strings.IndexByte(line, '(')]
+	}
+
+	if rest, ok := strings.CutPrefix(line, "method ("); ok {
+		// Skip the receiver, which never has parens inside.
+		recvEnd := strings.IndexByte(rest, ')') + 1
+		return "method (" + rest[:recvEnd] + rest[recvEnd:recvEnd+strings.IndexByte(rest[recvEnd:], '(')]
+	}
+
+	// Types, consts and vars are identified by their first two words.
+	words := strings.SplitN(line, " ", 3)
+	if len(words) < 2 {
+		return line
+	}
+	return words[0] + " " + words[1]
+}
+
+// changeSigns are the signs changes of each kind are printed with.
+var changeSigns = map[string]string{
+	"removed": "-",
+	"added":   "+",
+	"changed": "~",
+}
+
+func printAPIChanges(w io.Writer, changes []*APIChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "no API changes")
+		return
+	}
+
+	var byPkg = make(map[string][]*APIChange)
+	var paths []string
+	for _, c := range changes {
+		if _, ok := byPkg[c.ImportPath]; !ok {
+			paths = append(paths, c.ImportPath)
+		}
+		byPkg[c.ImportPath] = append(byPkg[c.ImportPath], c)
+	}
+	sort.Strings(paths)
+
+	var incompatible int
+	for _, path := range paths {
+		fmt.Fprintf(w, "%s:\n", path)
+		for _, c := range byPkg[path] {
+			note := ""
+			if !c.Compatible {
+				incompatible++
+				note = " (incompatible)"
+			}
+
+			switch {
+			case c.Message != "":
+				fmt.Fprintf(w, "\t%s %s%s\n", changeSigns[c.Kind], c.Message, note)
+			case c.Kind == "removed":
+				fmt.Fprintf(w, "\t- %s%s\n", c.Old, note)
+			case c.Kind == "added":
+				fmt.Fprintf(w, "\t+ %s%s\n", c.New, note)
+			default:
+				fmt.Fprintf(w, "\t~ %s%s\n\t  now: %s\n", c.Old, note, c.New)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d change(s), %d incompatible\n", len(changes), incompatible)
+}
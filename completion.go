@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The completion command is registered on init, as it needs the list of
+// commands itself.
+func init() {
+	commands["completion"] = runCompletion
+}
+
+func runCompletion(args []string) {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	packages := fs.Bool("packages", false, "print the packages of the current module to complete, used by the scripts")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson completion bash|zsh|fish\n\n"+
+			"Prints the script to complete the flags, subcommands and packages of\n"+
+			"godocjson in the given shell. For example, for bash:\n\n"+
+			"\tsource <(godocjson completion bash)\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *packages {
+		for _, p := range completionPackages() {
+			fmt.Println(p)
+		}
+		return
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var flags []*flag.Flag
+	flag.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+
+	var cmds []string
+	for name := range commands {
+		cmds = append(cmds, name)
+	}
+	sort.Strings(cmds)
+
+	switch fs.Arg(0) {
+	case "bash":
+		writeBashCompletion(os.Stdout, flags, cmds)
+	case "zsh":
+		writeZshCompletion(os.Stdout, flags, cmds)
+	case "fish":
+		writeFishCompletion(os.Stdout, flags, cmds)
+	default:
+		fatalf("unsupported shell %q: expecting bash, zsh or fish", fs.Arg(0))
+	}
+}
+
+// completionPackages returns the packages of the module, or the directory
+// if it is not in one, that the working directory is in, both as relative
+// directories and, if they are in GOPATH, as import paths.
+func completionPackages() []string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	root := moduleRoot(wd)
+	if root == "" {
+		root = wd
+	}
+
+	dirs, err := packageDirsBelow(root)
+	if err != nil {
+		debugf("unable to list packages: %s", err)
+	}
+
+	var result []string
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(wd, dir); err == nil {
+			rel = filepath.ToSlash(rel)
+			if !strings.HasPrefix(rel, "../") && rel != ".." && rel != "." {
+				rel = "./" + rel
+			}
+			result = append(result, rel)
+			if rel != "." {
+				result = append(result, rel+"/...")
+			}
+		}
+
+		if pkg, ok := gopathImportPath(dir); ok {
+			result = append(result, pkg)
+		}
+	}
+
+	result = append(result, "./...")
+	sort.Strings(result)
+	return result
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func writeBashCompletion(w io.Writer, flags []*flag.Flag, cmds []string) {
+	var names []string
+	for _, f := range flags {
+		names = append(names, "-"+f.Name)
+	}
+
+	fmt.Fprintf(w, `# bash completion for godocjson
+_godocjson() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+
+	local words="$(godocjson completion -packages 2>/dev/null)"
+	if [[ $COMP_CWORD -eq 1 ]]; then
+		words="%s $words"
+	fi
+	COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+complete -o default -F _godocjson godocjson
+`, strings.Join(names, " "), strings.Join(cmds, " "))
+}
+
+func writeZshCompletion(w io.Writer, flags []*flag.Flag, cmds []string) {
+	var b strings.Builder
+	for _, f := range flags {
+		fmt.Fprintf(&b, "\t\t%s\n", shellQuote("-"+f.Name+":"+f.Usage))
+	}
+
+	fmt.Fprintf(w, `#compdef godocjson
+_godocjson() {
+	local -a flags
+	flags=(
+%s	)
+
+	if [[ $PREFIX == -* ]]; then
+		_describe 'flag' flags
+		return
+	fi
+
+	if (( CURRENT == 2 )); then
+		compadd -- %s
+	fi
+	compadd -- ${(f)"$(godocjson completion -packages 2>/dev/null)"}
+	_files
+}
+compdef _godocjson godocjson
+`, b.String(), strings.Join(cmds, " "))
+}
+
+func writeFishCompletion(w io.Writer, flags []*flag.Flag, cmds []string) {
+	fmt.Fprintln(w, "# fish completion for godocjson")
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "complete -c godocjson -n __fish_use_subcommand -f -a %s\n", cmd)
+	}
+
+	for _, f := range flags {
+		arg := " -r"
+		if isBoolFlag(f) {
+			arg = ""
+		}
+		fmt.Fprintf(w, "complete -c godocjson -o %s%s -d %s\n", f.Name, arg, shellQuote(f.Usage))
+	}
+
+	fmt.Fprintln(w, "complete -c godocjson -a '(godocjson completion -packages 2>/dev/null)'")
+}
+
+// shellQuote quotes a string with single quotes for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
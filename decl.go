@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+var (
+	declSingleLine  = flag.Bool("decl-single-line", false, "print the signatures of functions and methods in a single line, even if they are split in several in the source")
+	declMaxWidth    = flag.Int("decl-max-width", 0, "print the signatures of functions and methods in a single line unless longer than this, in which case each parameter is printed in its own line")
+	declIndent      = flag.Int("decl-indent", 0, "indent and align declarations with this number of spaces instead of tabs")
+	declSplitGroups = flag.Bool("decl-split-groups", false, "include the declaration of each of the names of groups of constants and variables on its own, as the Decls of the group")
+)
+
+// printDecl returns the declaration of a node as printed in its Decl.
+// Functions are printed without their bodies, which are removed by go/doc.
+func printDecl(fset *token.FileSet, node ast.Node) string {
+	if fn, ok := node.(*ast.FuncDecl); ok && (*declSingleLine || *declMaxWidth > 0) {
+		return printSignature(fn)
+	}
+
+	var buf bytes.Buffer
+	declPrinter().Fprint(&buf, fset, node)
+	return separateFiltered(buf.String(), filteredGaps(fset, node))
+}
+
+// filteredComment is the prefix of the comment printed in place of the
+// fields and methods removed by go/doc.
+const filteredComment = "// contains filtered or unexported "
+
+// filteredGaps reports, for every list of fields or methods of the node
+// with some of them removed, in the order they are printed, whether there
+// is a gap of at least a line in the source between the last one left and
+// the end of the list, as go doc prints a blank line before its comment
+// then.
+func filteredGaps(fset *token.FileSet, node ast.Node) []bool {
+	var gaps []bool
+	ast.Inspect(node, func(n ast.Node) bool {
+		var list *ast.FieldList
+		switch n := n.(type) {
+		case *ast.StructType:
+			if n.Incomplete {
+				list = n.Fields
+			}
+		case *ast.InterfaceType:
+			if n.Incomplete {
+				list = n.Methods
+			}
+		}
+
+		if list != nil {
+			var gap bool
+			if len(list.List) > 0 && list.Closing.IsValid() {
+				last := list.List[len(list.List)-1]
+				gap = fset.Position(list.Closing-1).Line-fset.Position(last.End()).Line > 1
+			}
+			gaps = append(gaps, gap)
+		}
+		return true
+	})
+	return gaps
+}
+
+// separateFiltered adds a blank line before the comments of the removed
+// fields and methods with a gap before them.
+func separateFiltered(decl string, gaps []bool) string {
+	if len(gaps) == 0 {
+		return decl
+	}
+
+	var (
+		lines = strings.Split(decl, "\n")
+		out   = make([]string, 0, len(lines)+len(gaps))
+	)
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), filteredComment) && len(gaps) > 0 {
+			if gaps[0] {
+				out = append(out, "")
+			}
+			gaps = gaps[1:]
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// declPrinter returns the printer of declarations, which indents and aligns
+// them with tabs, as printer.Fprint does, unless spaces are requested.
+func declPrinter() *printer.Config {
+	if *declIndent > 0 {
+		return &printer.Config{Mode: printer.UseSpaces, Tabwidth: *declIndent}
+	}
+	return &printer.Config{Tabwidth: 8}
+}
+
+// printSignature returns the signature of a function in a single line or,
+// if it is longer than -decl-max-width, with every parameter in its own
+// line, as gofmt keeps them when written that way. Comments inside the
+// signature are not printed.
+func printSignature(fn *ast.FuncDecl) string {
+	var head = "func "
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		head += "(" + strings.Join(signatureFields(fn.Recv), ", ") + ") "
+	}
+
+	head += fn.Name.Name
+	if fn.Type.TypeParams != nil {
+		head += "[" + strings.Join(signatureFields(fn.Type.TypeParams), ", ") + "]"
+	}
+
+	var results string
+	switch list := signatureFields(fn.Type.Results); {
+	case len(list) == 0:
+	case len(list) == 1 && len(fn.Type.Results.List[0].Names) == 0:
+		results = " " + list[0]
+	default:
+		results = " (" + strings.Join(list, ", ") + ")"
+	}
+
+	params := signatureFields(fn.Type.Params)
+	line := head + "(" + strings.Join(params, ", ") + ")" + results
+	if *declMaxWidth <= 0 || len(line) <= *declMaxWidth || len(params) == 0 {
+		return line
+	}
+
+	indent := "\t"
+	if *declIndent > 0 {
+		indent = strings.Repeat(" ", *declIndent)
+	}
+
+	var b strings.Builder
+	b.WriteString(head + "(\n")
+	for _, p := range params {
+		b.WriteString(indent + p + ",\n")
+	}
+	b.WriteString(")" + results)
+	return b.String()
+}
+
+// signatureFields returns the fields of a list of parameters, results or
+// type parameters as written in a signature, with the names sharing a type
+// together.
+func signatureFields(list *ast.FieldList) []string {
+	if list == nil {
+		return nil
+	}
+
+	var fields []string
+	for _, f := range list.List {
+		typ := types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			fields = append(fields, typ)
+			continue
+		}
+
+		var names = make([]string, len(f.Names))
+		for i, n := range f.Names {
+			names[i] = n.Name
+		}
+		fields = append(fields, strings.Join(names, ", ")+" "+typ)
+	}
+	return fields
+}
+
+// valueDecls returns the declaration of each of the names of a group of
+// constants or variables on its own, as if it was declared alone, if
+// requested. Names declared along with others by a single value, such as
+// the results of a call, keep the others in their declaration, and those
+// whose type and value are implied by the previous ones in a group of
+// constants are declared without them, as they were written.
+func valueDecls(fset *token.FileSet, decl *ast.GenDecl, names []string) []string {
+	if !*declSplitGroups {
+		return nil
+	}
+
+	var byName = make(map[string]string)
+	for _, spec := range decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		for i, name := range vs.Names {
+			single := &ast.ValueSpec{Names: []*ast.Ident{name}, Type: vs.Type}
+			switch {
+			case len(vs.Values) == len(vs.Names):
+				single.Values = []ast.Expr{vs.Values[i]}
+			case len(vs.Values) > 0:
+				single.Names, single.Values = vs.Names, vs.Values
+			}
+
+			var buf bytes.Buffer
+			declPrinter().Fprint(&buf, fset, &ast.GenDecl{Tok: decl.Tok, Specs: []ast.Spec{single}})
+			byName[name.Name] = buf.String()
+		}
+	}
+
+	var decls = make([]string, len(names))
+	for i, name := range names {
+		decls[i] = byName[name]
+	}
+	return decls
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var withDependencies = flag.String("dependencies", "", "include the requirements of the module of the package from its go.mod file: direct, or all to include the indirect ones too")
+
+// NewDependencies returns the requirements of the module the package
+// belongs to, if requested, reporting which ones provide the given imports
+// of the package.
+func NewDependencies(src *Source, imports []string) []*Dependency {
+	if *withDependencies == "" {
+		return nil
+	}
+
+	_, mod := packageGoMod(src)
+	if mod == nil {
+		return nil
+	}
+
+	// Imports are matched against all the requirements, even if indirect
+	// ones are not included, as modules can be nested in others.
+	var paths = make([]string, len(mod.Requires))
+	for i, r := range mod.Requires {
+		paths[i] = r.Path
+	}
+	imported := importedModules(imports, paths)
+
+	var deps = []*Dependency{}
+	for _, r := range mod.Requires {
+		if r.Indirect && *withDependencies != "all" {
+			continue
+		}
+
+		deps = append(deps, &Dependency{
+			Path:     r.Path,
+			Version:  r.Version,
+			Indirect: r.Indirect,
+			Imported: imported[r.Path],
+		})
+	}
+	return deps
+}
+
+// NewReplaces returns the replace directives of the module the package
+// belongs to, reporting which ones apply to the given imports of the
+// package. Unlike the requirements, they are included without
+// -dependencies, as they change the sources the package is built with.
+func NewReplaces(src *Source, imports []string) []*ModuleReplace {
+	root, mod := packageGoMod(src)
+	if mod == nil || len(mod.Replaces) == 0 {
+		return nil
+	}
+
+	var paths = make([]string, len(mod.Replaces))
+	for i, r := range mod.Replaces {
+		paths[i] = r.Path
+	}
+	imported := importedModules(imports, paths)
+
+	var replaces = make([]*ModuleReplace, len(mod.Replaces))
+	for i, r := range mod.Replaces {
+		replaces[i] = &ModuleReplace{
+			Path:       r.Path,
+			Version:    r.Version,
+			NewPath:    r.NewPath,
+			NewVersion: r.NewVersion,
+			Imported:   imported[r.Path],
+		}
+
+		if dir := r.dir(root); dir != "" {
+			replaces[i].Dir = relPath(dir)
+		}
+	}
+	return replaces
+}
+
+// NewExcludes returns the exclude directives of the module the package
+// belongs to.
+func NewExcludes(src *Source) []*ModuleVersion {
+	_, mod := packageGoMod(src)
+	if mod == nil || len(mod.Excludes) == 0 {
+		return nil
+	}
+
+	var excludes = make([]*ModuleVersion, len(mod.Excludes))
+	for i, e := range mod.Excludes {
+		excludes[i] = &ModuleVersion{Path: e.Path, Version: e.Version}
+	}
+	return excludes
+}
+
+// packageGoMod returns the root and the go.mod file of the module the
+// package belongs to, or nil if it is not in one. The file is only read
+// once for all the packages of the module.
+func packageGoMod(src *Source) (string, *goModFile) {
+	if src.Dir == "" {
+		return "", nil
+	}
+
+	root := moduleRoot(src.Dir)
+	if root == "" {
+		return "", nil
+	}
+
+	mod, err := readGoMod(root)
+	if err != nil {
+		debugf("unable to read the go.mod file of %s: %s", root, err)
+		return "", nil
+	}
+	return root, mod
+}
+
+// importedModules returns which of the given module paths provide any of
+// the imports, which are provided by the module with the longest path they
+// are in, as modules can be nested in others.
+func importedModules(imports, paths []string) map[string]bool {
+	var imported = make(map[string]bool)
+	for _, imp := range imports {
+		var mod string
+		for _, path := range paths {
+			if (imp == path || strings.HasPrefix(imp, path+"/")) && len(path) > len(mod) {
+				mod = path
+			}
+		}
+
+		if mod != "" {
+			imported[mod] = true
+		}
+	}
+	return imported
+}
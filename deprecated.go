@@ -0,0 +1,150 @@
+package main
+
+import (
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// replacementRegexp matches the usual ways of pointing to the symbol to use
+// instead of a deprecated one, such as "Use [Foo] instead" or "use
+// bar.NewBaz()".
+var replacementRegexp = regexp.MustCompile(`(?i)\b(?:use|replaced by|in favou?r of)\s+\[?\*?([A-Za-z_][\w./]*\w)\]?(?:\(\))?`)
+
+// deprecationNotice returns the text of the paragraph of the doc comment
+// starting with "Deprecated: ", which is the convention to mark packages
+// and symbols as deprecated.
+func deprecationNotice(doc string) string {
+	for _, par := range strings.Split(doc, "\n\n") {
+		if notice, ok := strings.CutPrefix(strings.TrimSpace(par), "Deprecated: "); ok {
+			return strings.Join(strings.Fields(notice), " ")
+		}
+	}
+	return ""
+}
+
+// packageDoc returns the text of the package comments of all the files.
+// Unlike the Doc of the package, it is not removed when symbols are
+// filtered.
+func (s *Source) packageDoc() string {
+	var texts []string
+	for _, f := range s.Files {
+		if doc := s.Docs[f]; doc != nil {
+			texts = append(texts, doc.Text())
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// resolveReplacements fills the Replacement of every deprecated symbol of
+// the package whose notice refers to a symbol of the same package or to
+// one of an imported package.
+func resolveReplacements(pkg *Pkg) {
+	var symbols = make(map[string]*Symbol)
+	for _, sym := range packageSymbols(pkg) {
+		symbols[sym.Name] = sym
+	}
+
+	var imports = make(map[string]string)
+	for _, spec := range pkg.ImportSpecs {
+		switch {
+		case spec.IsBlank, spec.IsDot:
+		case spec.Name != "":
+			imports[spec.Name] = spec.Path
+		default:
+			imports[importName(spec.Path)] = spec.Path
+		}
+	}
+
+	resolve := func(notice string) *Replacement {
+		for _, m := range replacementRegexp.FindAllStringSubmatch(notice, -1) {
+			if r := resolveReplacement(m[1], pkg.ImportPath, symbols, imports); r != nil {
+				return r
+			}
+		}
+		return nil
+	}
+
+	pkg.Replacement = resolve(pkg.Deprecated)
+	for _, v := range append(pkg.Consts, pkg.Vars...) {
+		v.Replacement = resolve(v.Deprecated)
+	}
+
+	for _, f := range pkg.Funcs {
+		f.Replacement = resolve(f.Deprecated)
+	}
+
+	for _, t := range pkg.Types {
+		t.Replacement = resolve(t.Deprecated)
+		for _, v := range append(t.Consts, t.Vars...) {
+			v.Replacement = resolve(v.Deprecated)
+		}
+
+		for _, f := range append(t.Funcs, t.Methods...) {
+			f.Replacement = resolve(f.Deprecated)
+		}
+	}
+}
+
+// resolveReplacement resolves a reference to a symbol, which can be local,
+// such as "Foo" or "Type.Method", qualified with the name of an imported
+// package, as in "bar.Foo", or with a full import path, as in
+// "example.com/bar.Foo".
+func resolveReplacement(ref, importPath string, symbols map[string]*Symbol, imports map[string]string) *Replacement {
+	if ref == "" {
+		return nil
+	}
+
+	if sym, ok := symbols[ref]; ok {
+		return &Replacement{ImportPath: importPath, Name: sym.Name, Kind: sym.Kind}
+	}
+
+	slash := strings.LastIndexByte(ref, '/')
+	dot := strings.IndexByte(ref[slash+1:], '.')
+	if dot < 0 {
+		return nil
+	}
+
+	qualifier, name := ref[:slash+1+dot], ref[slash+1+dot+1:]
+	if !isExportedName(name) {
+		return nil
+	}
+
+	if slash >= 0 {
+		return &Replacement{ImportPath: qualifier, Name: name}
+	}
+
+	if path, ok := imports[qualifier]; ok {
+		return &Replacement{ImportPath: path, Name: name}
+	}
+	return nil
+}
+
+// importName returns the name a package is usually imported with, which
+// is the last element of its import path without any major version
+// suffix, as in gopkg.in/yaml.v3 or example.com/foo/v2.
+func importName(importPath string) string {
+	name := path.Base(importPath)
+	if strings.HasPrefix(name, "v") && strings.Trim(name[1:], "0123456789") == "" && name != "v" {
+		if dir := path.Dir(importPath); dir != "." {
+			name = path.Base(dir)
+		}
+	}
+
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// isExportedName reports whether every element of a possibly dotted name,
+// such as "Type.Method", is exported.
+func isExportedName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !token.IsExported(part) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// NewGenerators returns all the //go:generate directives in the files of
+// the package, in the order they appear.
+func NewGenerators(src *Source) []*Generator {
+	var gens = []*Generator{}
+	for _, f := range src.Files {
+		for _, group := range src.Comments[f] {
+			for _, c := range group.List {
+				if cmd, ok := strings.CutPrefix(c.Text, "//go:generate "); ok {
+					gens = append(gens, &Generator{
+						Command: strings.TrimSpace(cmd),
+						Pos:     NewPos(c, src.Fset),
+					})
+				}
+			}
+		}
+	}
+	return gens
+}
+
+// isDirective reports whether the comment text is a directive, which, as
+// the go tool defines them, are comments of the form //name:args without
+// spaces after the slashes.
+func isDirective(text string) bool {
+	text, ok := strings.CutPrefix(text, "//")
+	if !ok {
+		return false
+	}
+
+	colon := strings.Index(text, ":")
+	if colon <= 0 || colon+1 >= len(text) {
+		return false
+	}
+
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+
+		b := text[i]
+		if !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// NewDirectives returns the directives in the given comment groups.
+func NewDirectives(groups []*ast.CommentGroup, fset *token.FileSet) []*Directive {
+	var directives []*Directive
+	for _, group := range groups {
+		for _, c := range group.List {
+			if !isDirective(c.Text) {
+				continue
+			}
+
+			name, args, _ := strings.Cut(c.Text[2:], " ")
+			directives = append(directives, &Directive{
+				Name: name,
+				Args: strings.TrimSpace(args),
+				Pos:  NewPos(c, fset),
+			})
+		}
+	}
+	return directives
+}
+
+// fileDirectives returns the directives of the package files that are not
+// attached to any declaration, such as //go:build constraints.
+func fileDirectives(src *Source) []*Directive {
+	var directives = []*Directive{}
+	for _, f := range src.Files {
+		directives = append(directives, NewDirectives(src.Floating[f], src.Fset)...)
+	}
+	return directives
+}
+
+// NewEmbeds returns the variables of the given declaration that are
+// populated through //go:embed directives.
+func NewEmbeds(decl *ast.GenDecl, src *Source) []*Embed {
+	var embeds []*Embed
+	if decl.Tok != token.VAR {
+		return nil
+	}
+
+	for _, spec := range decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok || len(vs.Names) != 1 {
+			continue
+		}
+
+		// The directives can be either on the spec or on the declaration, if
+		// it is not a group.
+		docs := src.docs(vs)
+		if len(decl.Specs) == 1 {
+			docs = src.docs(decl, vs)
+		}
+
+		var patterns []string
+		for _, d := range NewDirectives(docs, src.Fset) {
+			if d.Name == "go:embed" {
+				patterns = append(patterns, splitEmbedPatterns(d.Args)...)
+			}
+		}
+
+		if len(patterns) > 0 {
+			embeds = append(embeds, &Embed{
+				Var:      vs.Names[0].Name,
+				Patterns: patterns,
+				Pos:      NewPos(vs, src.Fset),
+			})
+		}
+	}
+	return embeds
+}
+
+// packageEmbeds returns all the variables of the package populated through
+// //go:embed directives, including unexported ones.
+func packageEmbeds(src *Source) []*Embed {
+	var embeds = []*Embed{}
+	for _, f := range src.Files {
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok {
+				embeds = append(embeds, NewEmbeds(gd, src)...)
+			}
+		}
+	}
+	return embeds
+}
+
+// splitEmbedPatterns splits the arguments of a //go:embed directive, which
+// are separated by spaces but can also be quoted.
+func splitEmbedPatterns(args string) []string {
+	var patterns []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		switch args[0] {
+		case '"', '`':
+			end := strings.IndexByte(args[1:], args[0])
+			if end < 0 {
+				return append(patterns, args[1:])
+			}
+
+			pattern := args[1 : end+1]
+			if args[0] == '"' {
+				if p, err := strconv.Unquote(args[:end+2]); err == nil {
+					pattern = p
+				}
+			}
+
+			patterns = append(patterns, pattern)
+			args = args[end+2:]
+		default:
+			end := strings.IndexAny(args, " \t")
+			if end < 0 {
+				end = len(args)
+			}
+
+			patterns = append(patterns, args[:end])
+			args = args[end:]
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"go/ast"
+	"go/doc/comment"
+	"path"
+	"strings"
+)
+
+// symbolNames returns the names of all the symbols of the package, as in
+// packageSymbols, along with the fields of its struct types and the methods
+// of its interface types, such as T.Field, which doc links can refer to as
+// well.
+func symbolNames(pkg *Pkg) map[string]bool {
+	var names = make(map[string]bool)
+	for _, sym := range packageSymbols(pkg) {
+		names[sym.Name] = true
+	}
+
+	for _, t := range pkg.Types {
+		for _, name := range memberNames(t) {
+			names[t.Name+"."+name] = true
+		}
+	}
+	return names
+}
+
+// memberNames returns the names of the fields of a struct type or the
+// methods of an interface type, as in its declaration, so hidden ones are
+// not included. Embedded fields and interfaces have no name of their own.
+func memberNames(t *Type) []string {
+	decl, ok := parseDecl(t.Decl)
+	if !ok {
+		return nil
+	}
+
+	ts := typeSpec(decl)
+	if ts == nil {
+		return nil
+	}
+
+	var list *ast.FieldList
+	switch typ := ts.Type.(type) {
+	case *ast.StructType:
+		list = typ.Fields
+	case *ast.InterfaceType:
+		list = typ.Methods
+	default:
+		return nil
+	}
+
+	var names []string
+	for _, f := range list.List {
+		for _, name := range f.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
+// newDocParser returns a parser of the doc comments of the package, which
+// recognizes the doc links to the given symbols of the package, to the
+// packages it imports, by the name they are imported with, and to the
+// package itself, by its name.
+func newDocParser(pkg *Pkg, symbols map[string]bool) *comment.Parser {
+	return &comment.Parser{
+		LookupPackage: func(name string) (string, bool) {
+			for _, imp := range pkg.ImportSpecs {
+				if imp.Name == name || (imp.Name == "" && path.Base(imp.Path) == name) {
+					return imp.Path, true
+				}
+			}
+			return "", name == pkg.Name
+		},
+		LookupSym: func(recv, name string) bool {
+			if recv != "" {
+				name = recv + "." + name
+			}
+			return symbols[name]
+		},
+	}
+}
+
+// docLinkName returns the name of the symbol a doc link refers to, as in
+// packageSymbols, or an empty string for links to packages.
+func docLinkName(link *comment.DocLink) string {
+	if link.Recv != "" {
+		return strings.TrimPrefix(link.Recv, "*") + "." + link.Name
+	}
+	return link.Name
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"go/doc"
+	"regexp"
+	"strings"
+)
+
+var withDocScore = flag.Bool("doc-score", false, "score the documentation of every symbol and package")
+
+// docCheckWeights are the weights of each check in the score.
+var docCheckWeights = map[string]int{
+	"name":     30,
+	"short":    15,
+	"long":     15,
+	"params":   20,
+	"examples": 20,
+}
+
+const (
+	// minDocWords is the minimum number of words of a comment.
+	minDocWords = 4
+	// maxSynopsisWords is the maximum number of words of the first
+	// sentence of a comment.
+	maxSynopsisWords = 40
+)
+
+var docWordRegexp = regexp.MustCompile(`[\pL\pN_]+`)
+
+// docScorer computes the score of a comment from the checks applied to it.
+type docScorer struct {
+	total, passed int
+	failed        []string
+}
+
+func (s *docScorer) check(name string, ok bool) {
+	s.total += docCheckWeights[name]
+	if ok {
+		s.passed += docCheckWeights[name]
+	} else {
+		s.failed = append(s.failed, name)
+	}
+}
+
+func (s *docScorer) score() *DocScore {
+	return &DocScore{Score: 100 * s.passed / s.total, Failed: s.failed}
+}
+
+// scoreDoc returns the score of the given comment of a symbol or package,
+// whose first sentence must start with the given name, optionally preceded
+// by an article. Params are the names of the parameters the comment must
+// mention, and examples whether the symbol must have examples, or nil if
+// the check does not apply.
+func scoreDoc(text string, name string, params []string, examples *bool) *DocScore {
+	if strings.TrimSpace(text) == "" {
+		return &DocScore{Failed: []string{"missing"}}
+	}
+
+	var s docScorer
+	synopsis := new(doc.Package).Synopsis(text)
+	if name != "" {
+		s.check("name", startsWithName(synopsis, name))
+	}
+
+	words := docWordRegexp.FindAllString(text, -1)
+	s.check("short", len(words) >= minDocWords)
+	s.check("long", len(docWordRegexp.FindAllString(synopsis, -1)) <= maxSynopsisWords)
+
+	if len(params) > 0 {
+		mentioned := make(map[string]bool, len(words))
+		for _, w := range words {
+			mentioned[w] = true
+		}
+
+		all := true
+		for _, p := range params {
+			all = all && mentioned[p]
+		}
+		s.check("params", all)
+	}
+
+	if examples != nil {
+		s.check("examples", *examples)
+	}
+	return s.score()
+}
+
+// startsWithName reports whether the sentence starts with the given name,
+// which can be made of several words, such as "Package foo", optionally
+// preceded by "A", "An" or "The".
+func startsWithName(sentence, name string) bool {
+	for _, article := range []string{"A ", "An ", "The "} {
+		sentence = strings.TrimPrefix(sentence, article)
+	}
+
+	rest := strings.TrimPrefix(sentence, name)
+	if rest == sentence {
+		return false
+	}
+	return rest == "" || !docWordRegexp.MatchString(rest[:1])
+}
+
+// scoreDocs sets the documentation score of the package and all its
+// symbols. Examples are only checked if they are extracted, and for
+// functions and types.
+func scoreDocs(pkg *Pkg) {
+	var (
+		total int
+		ps    = &PackageDocScore{Doc: scoreDoc(pkg.Doc, "Package "+pkg.Name, nil, nil)}
+	)
+	add := func(score *DocScore) *DocScore {
+		ps.Symbols++
+		total += score.Score
+		// Documented symbols always pass either the short or the long
+		// check, so only undocumented ones score 0.
+		if score.Score == 0 {
+			ps.Undocumented++
+		}
+		return score
+	}
+
+	examples := func(list []*Example) *bool {
+		if !*withExamples {
+			return nil
+		}
+		ok := len(list) > 0
+		return &ok
+	}
+
+	values := func(list []*Value) {
+		for _, v := range list {
+			// The comment of a group is about all of the names.
+			var name string
+			if len(v.Names) == 1 {
+				name = v.Names[0]
+			}
+			v.DocScore = add(scoreDoc(v.Doc, name, nil, nil))
+		}
+	}
+
+	funcs := func(list []*Func) {
+		for _, f := range list {
+			var params []string
+			for _, p := range f.Params {
+				if p.Name != "" && p.Name != "_" {
+					params = append(params, p.Name)
+				}
+			}
+			f.DocScore = add(scoreDoc(f.Doc, f.Name, params, examples(f.Examples)))
+		}
+	}
+
+	values(pkg.Consts)
+	values(pkg.Vars)
+	funcs(pkg.Funcs)
+	for _, t := range pkg.Types {
+		t.DocScore = add(scoreDoc(t.Doc, t.Name, nil, examples(t.Examples)))
+		values(t.Consts)
+		values(t.Vars)
+		funcs(t.Funcs)
+		funcs(t.Methods)
+	}
+
+	ps.Score = (ps.Doc.Score + total) / (ps.Symbols + 1)
+	pkg.DocScore = ps
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"go/doc/comment"
+	"strings"
+)
+
+// NewDocSections returns the sections of the package comment, each one
+// starting at one of its headings, or nil if it has none. Text before the
+// first heading is not part of any section.
+func NewDocSections(text string) []*DocSection {
+	var (
+		sections []*DocSection
+		blocks   []comment.Block
+		printer  comment.Printer
+	)
+
+	flush := func() {
+		if len(sections) > 0 {
+			last := sections[len(sections)-1]
+			last.Text = strings.TrimSpace(string(printer.Text(&comment.Doc{Content: blocks})))
+		}
+		blocks = nil
+	}
+
+	for _, block := range new(comment.Parser).Parse(text).Content {
+		h, ok := block.(*comment.Heading)
+		if !ok {
+			blocks = append(blocks, block)
+			continue
+		}
+
+		flush()
+		title := printer.Text(&comment.Doc{Content: []comment.Block{&comment.Paragraph{Text: h.Text}}})
+		sections = append(sections, &DocSection{
+			Heading: strings.TrimSpace(string(title)),
+			ID:      h.DefaultID(),
+		})
+	}
+	flush()
+
+	return sections
+}
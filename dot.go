@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"io"
+	"strconv"
+)
+
+// dotWriter writes the graph of the types of the packages embedding other
+// types in the DOT language of Graphviz, with a cluster per package, where
+// interfaces are dashed, and an edge from every struct or interface to the
+// types it embeds.
+type dotWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+func newDOTWriter(w io.Writer, list bool) packageWriter {
+	return &dotWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *dotWriter) header() {
+	if w.n == 0 {
+		w.w.WriteString("digraph types {\n\trankdir=BT;\n\tnode [shape=box];\n")
+	}
+}
+
+func (w *dotWriter) Write(pkg *Pkg) error {
+	w.header()
+	w.n++
+
+	path := pkg.ImportPath
+	if path == "" {
+		path = pkg.Name
+	}
+
+	if len(pkg.Types) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w.w, "\n\tsubgraph %s {\n\t\tlabel=%s;\n", strconv.Quote(fmt.Sprintf("cluster_%d", w.n)), strconv.Quote(path))
+	for _, t := range pkg.Types {
+		attrs := "label=" + strconv.Quote(t.Name)
+		if isInterfaceDecl(t.Decl) {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(w.w, "\t\t%s [%s];\n", strconv.Quote(path+"."+t.Name), attrs)
+	}
+	fmt.Fprint(w.w, "\t}\n")
+
+	for _, e := range pkg.Embeddings {
+		fmt.Fprintf(w.w, "\t%s -> %s;\n", strconv.Quote(path+"."+e.Type), strconv.Quote(e.Embedded))
+	}
+	return nil
+}
+
+func (w *dotWriter) Close() error {
+	w.header()
+	if _, err := w.w.WriteString("}\n"); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// isInterfaceDecl reports whether the given type declaration declares an
+// interface.
+func isInterfaceDecl(decl string) bool {
+	gd, ok := parseDecl(decl)
+	if !ok || len(gd.Specs) != 1 {
+		return false
+	}
+
+	_, ok = gd.Specs[0].(*ast.TypeSpec).Type.(*ast.InterfaceType)
+	return ok
+}
+
+// writeImportGraphDOT writes the import graph in the DOT language, with the
+// packages of the standard library in gray and external ones dashed.
+func writeImportGraphDOT(w io.Writer, g *ImportGraph) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph imports {\n\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		var attrs string
+		switch n.Kind {
+		case "stdlib":
+			attrs = " [color=gray, fontcolor=gray]"
+		case "external":
+			attrs = " [style=dashed]"
+		}
+		fmt.Fprintf(bw, "\t%s%s;\n", strconv.Quote(n.ImportPath), attrs)
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "\t%s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+	}
+
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/types"
+	"strconv"
+)
+
+// NewEmbeddings returns the types embedded by the documented types of the
+// package, in the order they are declared. Unions and approximation
+// elements of constraint interfaces are not embeddings, so they are not
+// included.
+func NewEmbeddings(pkg *doc.Package, src *Source) []*Embedding {
+	var result = []*Embedding{}
+	for _, t := range pkg.Types {
+		for _, spec := range t.Decl.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+
+			var (
+				kind  string
+				exprs []ast.Expr
+			)
+			switch typ := ts.Type.(type) {
+			case *ast.StructType:
+				kind = "struct"
+				for _, f := range typ.Fields.List {
+					if len(f.Names) == 0 {
+						exprs = append(exprs, f.Type)
+					}
+				}
+			case *ast.InterfaceType:
+				kind = "interface"
+				for _, m := range typ.Methods.List {
+					if len(m.Names) == 0 {
+						exprs = append(exprs, m.Type)
+					}
+				}
+			default:
+				continue
+			}
+
+			imports := fileImports(src, ts)
+			for _, expr := range exprs {
+				name, ptr, ok := embeddedType(expr, pkg.ImportPath, imports, ts.TypeParams)
+				if !ok {
+					continue
+				}
+
+				result = append(result, &Embedding{
+					Type:     ts.Name.Name,
+					Embedded: name,
+					Kind:     kind,
+					Pointer:  ptr,
+				})
+			}
+		}
+	}
+	return result
+}
+
+// embeddedType returns the qualified name of an embedded type expression in
+// a package with the given import path and imports, by name, and whether it
+// is a pointer. It is not ok if the expression is not an embedding, such as
+// a union, or one of the type parameters.
+func embeddedType(expr ast.Expr, path string, imports map[string]string, params *ast.FieldList) (name string, ptr bool, ok bool) {
+	if star, isPtr := expr.(*ast.StarExpr); isPtr {
+		expr, ptr = star.X, true
+	}
+
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		expr = e.X
+	case *ast.IndexListExpr:
+		expr = e.X
+	}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if isTypeParam(e.Name, params) {
+			return "", false, false
+		}
+
+		if _, isType := types.Universe.Lookup(e.Name).(*types.TypeName); isType {
+			return e.Name, ptr, true
+		}
+
+		if path == "" {
+			return e.Name, ptr, true
+		}
+		return path + "." + e.Name, ptr, true
+	case *ast.SelectorExpr:
+		x, isIdent := e.X.(*ast.Ident)
+		if !isIdent {
+			return "", false, false
+		}
+
+		if imp, found := imports[x.Name]; found {
+			return imp + "." + e.Sel.Name, ptr, true
+		}
+		return types.ExprString(e), ptr, true
+	}
+	return "", false, false
+}
+
+func isTypeParam(name string, params *ast.FieldList) bool {
+	if params == nil {
+		return false
+	}
+
+	for _, f := range params.List {
+		for _, n := range f.Names {
+			if n.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fileImports returns the import paths of the packages imported by the file
+// containing the given node, by the name they are used with.
+func fileImports(src *Source, node ast.Node) map[string]string {
+	var result = make(map[string]string)
+	for _, f := range src.Files {
+		if node.Pos() < f.Pos() || node.Pos() >= f.End() {
+			continue
+		}
+
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+
+			name := importName(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			result[name] = path
+		}
+	}
+	return result
+}
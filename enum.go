@@ -0,0 +1,199 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// NewEnum returns the enum made of the constants of the type, or nil if it
+// has none.
+func NewEnum(typ *doc.Type, src *Source) *Enum {
+	var members []*EnumMember
+	for _, c := range typ.Consts {
+		for _, spec := range c.Decl.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+
+			var text string
+			if doc := src.Docs[vs]; doc != nil {
+				text = doc.Text()
+			} else if vs.Comment != nil {
+				text = vs.Comment.Text()
+			}
+
+			for _, name := range vs.Names {
+				if !name.IsExported() {
+					continue
+				}
+
+				m := &EnumMember{Name: name.Name, Doc: strings.TrimSpace(text)}
+				if v := src.constValue(name); v != nil {
+					m.Value = v.ExactString()
+				}
+				members = append(members, m)
+			}
+		}
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+	return &Enum{Members: members}
+}
+
+// constValue returns the value of the constant with the given name, or nil
+// if it is not known.
+func (s *Source) constValue(name *ast.Ident) constant.Value {
+	if s.Info != nil {
+		if c, ok := s.Info.Defs[name].(*types.Const); ok && c.Val().Kind() != constant.Unknown {
+			return c.Val()
+		}
+	}
+	return s.Consts[name]
+}
+
+// constValues evaluates all the constants declared in the files. Without
+// type information, only those whose values are made of literals, iota and
+// other constants of the package can be evaluated.
+func constValues(files []*ast.File) map[*ast.Ident]constant.Value {
+	var (
+		values = make(map[*ast.Ident]constant.Value)
+		byName = make(map[string]constant.Value)
+	)
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+
+			var last []ast.Expr
+			for index, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if len(vs.Values) > 0 {
+					last = vs.Values
+				}
+
+				for i, name := range vs.Names {
+					if i >= len(last) {
+						break
+					}
+
+					if v := evalConst(last[i], index, byName); v != nil {
+						values[name] = v
+						if name.Name != "_" {
+							byName[name.Name] = v
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return values
+}
+
+// Limits of the constants evaluated, the same go/types has, so untrusted
+// sources cannot make them grow without bound.
+const (
+	// maxConstShift is the largest shift count.
+	maxConstShift = 1023 - 1 + 52
+	// maxConstBits is the size in bits of the largest integer.
+	maxConstBits = 512
+)
+
+// evalConst returns the value of the constant expression, with iota being
+// the given index, or nil if it cannot be evaluated or is larger than those
+// go/types allows.
+func evalConst(expr ast.Expr, iota int, consts map[string]constant.Value) (v constant.Value) {
+	defer func() {
+		// Invalid operations, such as adding strings and numbers, panic.
+		if recover() != nil || (v != nil && v.Kind() == constant.Unknown) {
+			v = nil
+		}
+
+		if v != nil && v.Kind() == constant.Int && constant.BitLen(v) > maxConstBits {
+			v = nil
+		}
+	}()
+
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(e.Value, e.Kind, 0)
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			return constant.MakeInt64(int64(iota))
+		case "true":
+			return constant.MakeBool(true)
+		case "false":
+			return constant.MakeBool(false)
+		}
+		return consts[e.Name]
+	case *ast.ParenExpr:
+		return evalConst(e.X, iota, consts)
+	case *ast.CallExpr:
+		fun, ok := e.Fun.(*ast.Ident)
+		if !ok || len(e.Args) != 1 {
+			return nil
+		}
+
+		arg := evalConst(e.Args[0], iota, consts)
+		switch {
+		case arg == nil:
+			return nil
+		case fun.Name == "len":
+			// The length of constant strings is constant too.
+			if arg.Kind() == constant.String {
+				return constant.MakeInt64(int64(len(constant.StringVal(arg))))
+			}
+			return nil
+		case isConstBuiltin(fun.Name):
+			return nil
+		}
+		// Conversions to a type of the package keep the value.
+		return arg
+	case *ast.UnaryExpr:
+		x := evalConst(e.X, iota, consts)
+		if x == nil {
+			return nil
+		}
+		return constant.UnaryOp(e.Op, x, 0)
+	case *ast.BinaryExpr:
+		x, y := evalConst(e.X, iota, consts), evalConst(e.Y, iota, consts)
+		if x == nil || y == nil {
+			return nil
+		}
+
+		switch e.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(y)
+			if !ok || s > maxConstShift {
+				return nil
+			}
+			return constant.Shift(x, e.Op, uint(s))
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return constant.MakeBool(constant.Compare(x, e.Op, y))
+		case token.QUO:
+			if x.Kind() == constant.Int && y.Kind() == constant.Int {
+				if constant.Sign(y) == 0 {
+					return nil
+				}
+				return constant.BinaryOp(x, token.QUO_ASSIGN, y)
+			}
+		case token.REM:
+			if constant.Sign(y) == 0 {
+				return nil
+			}
+		}
+		return constant.BinaryOp(x, e.Op, y)
+	}
+	return nil
+}
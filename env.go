@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	parseutil "gopkg.in/src-d/go-parse-utils.v1"
+)
+
+const (
+	gopathUsage      = "GOPATH to resolve import paths in, instead of the one of the environment, a list of directories like it"
+	goflagsUsage     = "GOFLAGS the go command is run with when type-checking, whose -tags are also used to match files, instead of the one of the environment"
+	go111moduleUsage = "GO111MODULE the go command is run with when type-checking, instead of the one of the environment: on, off or auto"
+	goproxyUsage     = "GOPROXY the go command downloads the modules imported from when type-checking, instead of the one of the environment"
+	gonosumdbUsage   = "GONOSUMDB the go command is run with when type-checking, patterns of the modules not checked against the checksum database"
+	goprivateUsage   = "GOPRIVATE the go command is run with when type-checking, patterns of the private modules downloaded without the proxy and the checksum database"
+	gorootUsage      = "GOROOT to resolve the standard library in and run the go command of, instead of the one go env reports"
+	offlineUsage     = "never access the network: imports are only resolved in the module cache, vendor directories and GOPATH, failing if any is missing when type-checking"
+)
+
+var (
+	gopathFlag      = flag.String("gopath", "", gopathUsage)
+	goflagsFlag     = flag.String("goflags", "", goflagsUsage)
+	go111moduleFlag = flag.String("go111module", "", go111moduleUsage)
+	goproxyFlag     = flag.String("goproxy", "", goproxyUsage)
+	gonosumdbFlag   = flag.String("gonosumdb", "", gonosumdbUsage)
+	goprivateFlag   = flag.String("goprivate", "", goprivateUsage)
+	gorootFlag      = flag.String("goroot", "", gorootUsage)
+	offline         = flag.Bool("offline", false, offlineUsage)
+)
+
+// offlineEnv is the environment the go command is run with when offline,
+// so it never downloads modules, checksums or toolchains.
+var offlineEnv = map[string]string{
+	"GOPROXY":     "off",
+	"GOSUMDB":     "off",
+	"GOTOOLCHAIN": "local",
+}
+
+// addEnvFlags adds the flags overriding the environment to the flag set of
+// a subcommand.
+func addEnvFlags(fs *flag.FlagSet) {
+	fs.StringVar(gopathFlag, "gopath", "", gopathUsage)
+	fs.StringVar(goflagsFlag, "goflags", "", goflagsUsage)
+	fs.StringVar(go111moduleFlag, "go111module", "", go111moduleUsage)
+	fs.StringVar(goproxyFlag, "goproxy", "", goproxyUsage)
+	fs.StringVar(gonosumdbFlag, "gonosumdb", "", gonosumdbUsage)
+	fs.StringVar(goprivateFlag, "goprivate", "", goprivateUsage)
+	fs.StringVar(gorootFlag, "goroot", "", gorootUsage)
+	fs.BoolVar(offline, "offline", false, offlineUsage)
+}
+
+// applyEnvFlags overrides the environment used to resolve packages, which
+// is the one go env reports, with the values of the flags that were given.
+// The environment of the process is changed too, as the go command is run
+// with it to find the packages imported when type-checking.
+func applyEnvFlags() error {
+	switch *go111moduleFlag {
+	case "", "on", "off", "auto":
+	default:
+		return fmt.Errorf("invalid -go111module %q: expecting on, off or auto", *go111moduleFlag)
+	}
+
+	if *gorootFlag != "" {
+		goroot, err := filepath.Abs(*gorootFlag)
+		if err != nil {
+			return err
+		}
+
+		if fi, err := os.Stat(filepath.Join(goroot, "src")); err != nil || !fi.IsDir() {
+			return fmt.Errorf("invalid -goroot %q: expecting a Go installation, with the standard library in src", *gorootFlag)
+		}
+
+		*gorootFlag = goroot
+		os.Setenv("GOROOT", goroot)
+	}
+	applyGoEnv(*gorootFlag, *goflagsFlag)
+
+	if *gopathFlag != "" {
+		gopath, err := absPathList(*gopathFlag)
+		if err != nil {
+			return err
+		}
+
+		build.Default.GOPATH = gopath
+		parseutil.DefaultGoPath = parseutil.GoPath(filepath.SplitList(gopath))
+		os.Setenv("GOPATH", gopath)
+	}
+
+	if *goflagsFlag != "" {
+		os.Setenv("GOFLAGS", *goflagsFlag)
+	}
+
+	if *go111moduleFlag != "" {
+		os.Setenv("GO111MODULE", *go111moduleFlag)
+	}
+
+	if *offline && *goproxyFlag != "" {
+		return fmt.Errorf("invalid -goproxy %q: modules are never downloaded with -offline", *goproxyFlag)
+	}
+
+	for name, value := range map[string]string{
+		"GOPROXY":   *goproxyFlag,
+		"GONOSUMDB": *gonosumdbFlag,
+		"GOPRIVATE": *goprivateFlag,
+	} {
+		if value != "" {
+			os.Setenv(name, value)
+		}
+	}
+
+	if *offline {
+		for k, v := range offlineEnv {
+			os.Setenv(k, v)
+		}
+	}
+	return nil
+}
+
+// absPathList makes all the directories of a list like GOPATH absolute, as
+// it must be.
+func absPathList(list string) (string, error) {
+	dirs := filepath.SplitList(list)
+	for i, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", err
+		}
+		dirs[i] = abs
+	}
+	return strings.Join(dirs, string(filepath.ListSeparator)), nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// NewErrors returns the sentinel errors of the package, in the order they
+// are declared, followed by its error types.
+func NewErrors(pkg *doc.Package, src *Source) []*ErrorDecl {
+	var (
+		errTypes   = make(map[string]bool)
+		typeErrors []*ErrorDecl
+		vars       = pkg.Vars
+	)
+
+	for _, t := range pkg.Types {
+		vars = append(vars, t.Vars...)
+		if ptr, ok := src.implementsError(t); ok {
+			errTypes[t.Name] = true
+			typeErrors = append(typeErrors, &ErrorDecl{
+				Kind:            "type",
+				Name:            t.Name,
+				Doc:             t.Doc,
+				PointerReceiver: ptr,
+				Pos:             NewPos(t.Decl, src.Fset),
+			})
+		}
+	}
+
+	var errors = []*ErrorDecl{}
+	for _, v := range vars {
+		for _, spec := range v.Decl.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+
+			text := v.Doc
+			if doc := src.Docs[vs]; doc != nil {
+				text = doc.Text()
+			}
+
+			for i, name := range vs.Names {
+				var value ast.Expr
+				if i < len(vs.Values) {
+					value = vs.Values[i]
+				}
+
+				if !name.IsExported() || !src.isSentinel(name, vs.Type, value, errTypes) {
+					continue
+				}
+
+				errors = append(errors, &ErrorDecl{
+					Kind:    "sentinel",
+					Name:    name.Name,
+					Doc:     text,
+					Message: errorMessage(value),
+					Pos:     NewPos(vs, src.Fset),
+				})
+			}
+		}
+	}
+
+	return append(errors, typeErrors...)
+}
+
+// implementsError reports whether the type implements the error interface
+// and, if so, whether only pointers to it do.
+func (s *Source) implementsError(t *doc.Type) (ptr bool, ok bool) {
+	if s.Info != nil {
+		if spec := typeSpec(t.Decl); spec != nil {
+			if obj := s.Info.Defs[spec.Name]; obj != nil {
+				if hasErrorMethod(obj.Type()) {
+					return false, true
+				}
+				return true, hasErrorMethod(types.NewPointer(obj.Type()))
+			}
+		}
+	}
+
+	if spec := typeSpec(t.Decl); spec != nil {
+		if iface, isIface := spec.Type.(*ast.InterfaceType); isIface {
+			for _, m := range iface.Methods.List {
+				if id, isIdent := m.Type.(*ast.Ident); isIdent && id.Name == "error" {
+					return false, true
+				}
+
+				if len(m.Names) == 1 && m.Names[0].Name == "Error" && isErrorMethod(m.Type.(*ast.FuncType)) {
+					return false, true
+				}
+			}
+			return false, false
+		}
+	}
+
+	for _, m := range t.Methods {
+		if m.Name == "Error" && isErrorMethod(m.Decl.Type) {
+			return strings.HasPrefix(m.Recv, "*"), true
+		}
+	}
+	return false, false
+}
+
+// hasErrorMethod reports whether the method set of t has the Error method
+// of the error interface. Unlike types.Implements, it does not assume types
+// that failed to type-check implement it.
+func hasErrorMethod(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, "Error")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+		types.Identical(sig.Results().At(0).Type(), types.Typ[types.String])
+}
+
+// isErrorMethod reports whether the function has the signature of the
+// Error method of the error interface.
+func isErrorMethod(fn *ast.FuncType) bool {
+	if fn.Params.NumFields() != 0 || fn.Results.NumFields() != 1 {
+		return false
+	}
+
+	id, ok := fn.Results.List[0].Type.(*ast.Ident)
+	return ok && id.Name == "string"
+}
+
+// isSentinel reports whether the variable with the given name, declared
+// type and value holds an error. Without type information, variables are
+// errors if they are declared as such, are created with errors.New or
+// fmt.Errorf or are values of an error type of the package.
+func (s *Source) isSentinel(name *ast.Ident, typ, value ast.Expr, errTypes map[string]bool) bool {
+	if s.Info != nil {
+		if obj := s.Info.Defs[name]; obj != nil && obj.Type() != types.Typ[types.Invalid] {
+			return hasErrorMethod(obj.Type())
+		}
+	}
+
+	if typ != nil {
+		return isErrorTypeExpr(typ, errTypes)
+	}
+
+	switch v := value.(type) {
+	case *ast.CallExpr:
+		return isErrorConstructor(v) || isErrorTypeExpr(v.Fun, errTypes)
+	case *ast.UnaryExpr:
+		if lit, ok := v.X.(*ast.CompositeLit); ok && v.Op == token.AND {
+			return isErrorTypeExpr(lit.Type, errTypes)
+		}
+	case *ast.CompositeLit:
+		return isErrorTypeExpr(v.Type, errTypes)
+	}
+	return false
+}
+
+// isErrorConstructor reports whether the call creates an error with
+// errors.New or fmt.Errorf.
+func isErrorConstructor(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	switch pkg.Name + "." + sel.Sel.Name {
+	case "errors.New", "fmt.Errorf", "xerrors.New", "xerrors.Errorf":
+		return true
+	}
+	return false
+}
+
+func isErrorTypeExpr(expr ast.Expr, errTypes map[string]bool) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name == "error" || errTypes[e.Name]
+	case *ast.StarExpr:
+		return isErrorTypeExpr(e.X, errTypes)
+	case *ast.ParenExpr:
+		return isErrorTypeExpr(e.X, errTypes)
+	}
+	return false
+}
+
+// errorMessage returns the message of an error created from a string
+// literal.
+func errorMessage(value ast.Expr) string {
+	call, ok := value.(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 || !isErrorConstructor(call) {
+		return ""
+	}
+
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+
+	msg, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	return msg
+}
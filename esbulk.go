@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"go/doc"
+	"io"
+)
+
+var esIndex = flag.String("es-index", "godocjson", "name of the Elasticsearch index the documents are written to with -format esbulk")
+
+// esbulkWriter writes the documentation of packages as the body of a
+// request to the _bulk API of Elasticsearch: pairs of lines with the action
+// to index a document and the document itself, one for every package and
+// another for every symbol of each of them.
+type esbulkWriter struct {
+	w *bufio.Writer
+}
+
+func newESBulkWriter(w io.Writer, list bool) packageWriter {
+	return &esbulkWriter{w: bufio.NewWriter(w)}
+}
+
+// esAction is the action line of a document of a bulk request.
+type esAction struct {
+	Index struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	} `json:"index"`
+}
+
+// esDocument is a document of a bulk request, either for a package or one
+// of its symbols.
+type esDocument struct {
+	ImportPath string
+	Package    string
+	// Name is the name of the symbol, or the package name for packages.
+	Name string
+	// Kind is "package" or the kind of symbol.
+	Kind     string
+	Synopsis string
+	Doc      string
+	Decl     string `json:",omitempty"`
+	Pos      *Pos   `json:",omitempty"`
+}
+
+func (w *esbulkWriter) Write(pkg *Pkg) error {
+	path := pkg.ImportPath
+	if path == "" {
+		path = pkg.Name
+	}
+
+	err := w.write(path, &esDocument{
+		ImportPath: pkg.ImportPath,
+		Package:    pkg.Name,
+		Name:       pkg.Name,
+		Kind:       "package",
+		Synopsis:   synopsis(pkg),
+		Doc:        packageDocText(pkg),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sym := range packageSymbols(pkg) {
+		err := w.write(path+"."+sym.Name, &esDocument{
+			ImportPath: pkg.ImportPath,
+			Package:    pkg.Name,
+			Name:       sym.Name,
+			Kind:       sym.Kind,
+			Synopsis:   new(doc.Package).Synopsis(sym.Doc),
+			Doc:        sym.Doc,
+			Decl:       sym.Decl,
+			Pos:        sym.Pos,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *esbulkWriter) write(id string, d *esDocument) error {
+	var action esAction
+	action.Index.Index = *esIndex
+	action.Index.ID = id
+	for _, v := range []interface{}{action, outputValue(d)} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		w.w.Write(data)
+		if err := w.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *esbulkWriter) Close() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var withExamples = flag.Bool("examples", false, "extract the examples in test files and attach them to the symbols they document")
+
+var outputRegexp = regexp.MustCompile(`(?i)^[[:space:]]*(unordered )?output:`)
+
+// NewExample returns the example with the given suffix.
+func NewExample(ex *doc.Example, suffix string, fset *token.FileSet) *Example {
+	// The output comment is already in Output, and the comments after the
+	// last statement go in Trailing.
+	var (
+		comments []*ast.CommentGroup
+		trailing []string
+		end      = trailingPos(ex.Code)
+	)
+	for _, c := range ex.Comments {
+		switch {
+		case outputRegexp.MatchString(c.Text()):
+		case end.IsValid() && c.Pos() > end:
+			trailing = append(trailing, strings.TrimSpace(c.Text()))
+		default:
+			comments = append(comments, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, &printer.CommentedNode{Node: ex.Code, Comments: comments})
+
+	var play bytes.Buffer
+	if ex.Play != nil {
+		printer.Fprint(&play, fset, ex.Play)
+	}
+
+	return &Example{
+		Name:        ex.Name,
+		Suffix:      suffix,
+		Doc:         ex.Doc,
+		Code:        buf.String(),
+		Play:        play.String(),
+		Trailing:    strings.Join(trailing, "\n"),
+		Output:      ex.Output,
+		Unordered:   ex.Unordered,
+		EmptyOutput: ex.EmptyOutput,
+		Pos:         NewPos(ex.Code, fset),
+	}
+}
+
+// trailingPos returns the end of the last statement of the body of an
+// example, after which comments are trailing commentary, or an invalid
+// position for whole-file examples, which have no body.
+func trailingPos(code ast.Node) token.Pos {
+	block, ok := code.(*ast.BlockStmt)
+	if !ok {
+		return token.NoPos
+	}
+
+	if len(block.List) == 0 {
+		return block.Lbrace
+	}
+	return block.List[len(block.List)-1].End()
+}
+
+// attachExamples adds the examples to the symbols they document, following
+// the naming conventions of go/doc: Example documents the package,
+// ExampleF the function or type F and ExampleT_M the method M of type T,
+// all optionally followed by a suffix starting with a lowercase letter.
+// Examples of symbols that do not exist are dropped.
+func attachExamples(pkg *Pkg, examples []*doc.Example, fset *token.FileSet) {
+	ids := map[string]*[]*Example{"": &pkg.Examples}
+	for _, f := range pkg.Funcs {
+		ids[f.Name] = &f.Examples
+	}
+
+	for _, t := range pkg.Types {
+		ids[t.Name] = &t.Examples
+		for _, f := range t.Funcs {
+			ids[f.Name] = &f.Examples
+		}
+
+		for _, m := range t.Methods {
+			ids[t.Name+"_"+m.Name] = &m.Examples
+		}
+	}
+
+	for _, ex := range examples {
+		// Try every possible split point, from the full name backwards,
+		// as method names can contain underscores too.
+		for i := len(ex.Name); i >= 0; i = strings.LastIndexByte(ex.Name[:i], '_') {
+			prefix, suffix, ok := splitExampleName(ex.Name, i)
+			if !ok {
+				continue
+			}
+
+			if list, ok := ids[prefix]; ok {
+				*list = append(*list, NewExample(ex, suffix, fset))
+				break
+			}
+		}
+	}
+}
+
+// splitExampleName splits the name of an example at the underscore in the
+// given position into the symbol it documents and its suffix. It is not
+// valid unless the suffix starts with a lowercase letter.
+func splitExampleName(name string, i int) (prefix, suffix string, ok bool) {
+	if i == len(name) {
+		return name, "", true
+	}
+
+	if i == len(name)-1 {
+		return "", "", false
+	}
+
+	prefix, suffix = name[:i], name[i+1:]
+	r, _ := utf8.DecodeRuneInString(suffix)
+	return prefix, suffix, !unicode.IsUpper(r)
+}
+
+// parseTestFiles parses the given test files of the directory.
+func parseTestFiles(fset *token.FileSet, srcDir string, files []string) ([]*ast.File, error) {
+	var result []*ast.File
+	for _, name := range files {
+		path := filepath.Join(srcDir, name)
+		debugf("parsing %s", path)
+
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
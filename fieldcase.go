@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"unicode"
+)
+
+var fieldCase = flag.String("field-case", "pascal", "naming convention of the keys of the objects in the output: pascal, camel or snake")
+
+// recaseName returns the name of a Go field, such as "ImportPath" or
+// "URLPath", following the given naming convention: "importPath" and
+// "urlPath" in camel case, or "import_path" and "url_path" in snake case.
+func recaseName(name, convention string) string {
+	words := splitWords(name)
+	switch convention {
+	case "camel":
+		words[0] = strings.ToLower(words[0])
+		return strings.Join(words, "")
+	case "snake":
+		return strings.ToLower(strings.Join(words, "_"))
+	}
+	return name
+}
+
+// splitWords splits a name in mixed caps into its words, keeping initialisms
+// such as "URL" together.
+func splitWords(name string) []string {
+	var (
+		words []string
+		runes = []rune(name)
+		start int
+	)
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+
+		prev := runes[i-1]
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if !unicode.IsUpper(prev) || nextLower {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var fieldsFlag = flag.String("fields", "", "comma-separated fields of the symbols to output, e.g. Name,Doc,Decl, all of them by default")
+
+// symbolTypes are the types of the symbols whose fields can be selected
+// with -fields, along with the fields that are always kept, as they hold
+// other symbols.
+var symbolTypes = map[reflect.Type][]string{
+	reflect.TypeOf(Value{}): nil,
+	reflect.TypeOf(Func{}):  nil,
+	reflect.TypeOf(Type{}):  {"Consts", "Vars", "Funcs", "Methods"},
+}
+
+// symbolFields are the fields of the symbols selected with -fields, in
+// lower case, or nil if all of them are output.
+var symbolFields map[string]bool
+
+// parseSymbolFields parses the list of fields given with -fields, which are
+// matched case-insensitively, checking that they are fields of symbols.
+func parseSymbolFields(list string) (map[string]bool, error) {
+	var known = make(map[string]bool)
+	for t := range symbolTypes {
+		for i := 0; i < t.NumField(); i++ {
+			known[strings.ToLower(t.Field(i).Name)] = true
+		}
+	}
+
+	var fields = make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		if !known[name] {
+			return nil, fmt.Errorf("invalid -fields: unknown field %q", name)
+		}
+		fields[name] = true
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid -fields: no fields given")
+	}
+	return fields, nil
+}
+
+// keepField reports whether the field with the given name of a struct of
+// the given type is output, which is always the case unless the struct is
+// a symbol and the field was not selected.
+func keepField(t reflect.Type, name string) bool {
+	kept, ok := symbolTypes[t]
+	if !ok || symbolFields == nil {
+		return true
+	}
+
+	for _, k := range kept {
+		if k == name {
+			return true
+		}
+	}
+	return symbolFields[strings.ToLower(name)]
+}
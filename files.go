@@ -0,0 +1,75 @@
+package main
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"strconv"
+)
+
+// NewFiles returns the metadata of every file of the package, sorted by
+// file name.
+func NewFiles(src *Source) []*File {
+	var files = make([]*File, len(src.Files))
+	for i, f := range src.Files {
+		tf := src.Fset.File(f.Pos())
+		file := &File{
+			Name:            relPath(tf.Name()),
+			BuildConstraint: buildConstraint(f, src.Comments[f]),
+			Imports:         []string{},
+			Size:            tf.Size(),
+			Hash:            src.Hashes[tf.Name()],
+			// The comments have already been removed from the file, so
+			// check the ones that were kept aside.
+			IsGenerated: ast.IsGenerated(&ast.File{Package: f.Package, Comments: src.Comments[f]}),
+		}
+
+		if doc := src.Docs[f]; doc != nil {
+			file.Doc = doc.Text()
+		}
+
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			file.Imports = append(file.Imports, path)
+			file.IsCgo = file.IsCgo || path == "C"
+		}
+		files[i] = file
+	}
+	return files
+}
+
+// buildConstraint returns the build constraint in the comments before the
+// package clause of the file. A //go:build line takes precedence over
+// // +build lines, as with the go tool.
+func buildConstraint(f *ast.File, comments []*ast.CommentGroup) string {
+	var plusBuild constraint.Expr
+	for _, group := range comments {
+		if group.End() >= f.Package {
+			break
+		}
+
+		for _, c := range group.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					return expr.String()
+				}
+			case constraint.IsPlusBuild(c.Text):
+				expr, err := constraint.Parse(c.Text)
+				if err != nil {
+					continue
+				}
+
+				if plusBuild == nil {
+					plusBuild = expr
+				} else {
+					plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+				}
+			}
+		}
+	}
+
+	if plusBuild == nil {
+		return ""
+	}
+	return plusBuild.String()
+}
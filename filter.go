@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/doc"
+	"path"
+	"strings"
+)
+
+var (
+	excludeSymbols = flag.String("exclude-symbols", "", "comma-separated shell patterns of the names of the symbols to leave out, such as Test*, matched against the names of methods without their type")
+	excludeKinds   = flag.String("exclude-kinds", "", "comma-separated kinds of the symbols -exclude-symbols applies to, all of them by default: "+strings.Join(symbolKinds, ", "))
+)
+
+// symbolKinds are the kinds of symbols -exclude-kinds accepts.
+var symbolKinds = []string{"const", "var", "func", "type", "method"}
+
+// symbolExclusion leaves out of the documentation the symbols of some kinds
+// whose names match any of some patterns, given with -exclude-symbols and
+// -exclude-kinds.
+type symbolExclusion struct {
+	patterns []string
+	kinds    map[string]bool
+}
+
+// exclusion is the symbolExclusion given with -exclude-symbols, or nil if
+// no symbols are left out.
+var exclusion *symbolExclusion
+
+// parseExclusion parses the patterns and kinds given with -exclude-symbols
+// and -exclude-kinds, returning nil if there are no patterns.
+func parseExclusion(patterns, kinds string) (*symbolExclusion, error) {
+	var e symbolExclusion
+	for _, p := range strings.Split(patterns, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid -exclude-symbols: %q: %s", p, err)
+		}
+		e.patterns = append(e.patterns, p)
+	}
+
+	if len(e.patterns) == 0 {
+		if strings.TrimSpace(kinds) != "" {
+			return nil, fmt.Errorf("-exclude-kinds can only be used with -exclude-symbols")
+		}
+		return nil, nil
+	}
+
+	e.kinds = make(map[string]bool)
+	for _, k := range strings.Split(kinds, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+
+		if !isSymbolKind(k) {
+			return nil, fmt.Errorf("invalid -exclude-kinds: unknown kind %q, expecting some of %s", k, strings.Join(symbolKinds, ", "))
+		}
+		e.kinds[k] = true
+	}
+
+	if len(e.kinds) == 0 {
+		for _, k := range symbolKinds {
+			e.kinds[k] = true
+		}
+	}
+	return &e, nil
+}
+
+func isSymbolKind(kind string) bool {
+	for _, k := range symbolKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes reports whether a symbol of the given kind and name is left out.
+func (e *symbolExclusion) excludes(kind, name string) bool {
+	if !e.kinds[kind] {
+		return false
+	}
+
+	for _, p := range e.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filter removes the excluded symbols from the documentation of a package.
+// Excluded types are removed along with their constants, variables,
+// functions and methods, and groups of constants and variables only when
+// all of their names are excluded, as doc.Package.Filter does.
+func (e *symbolExclusion) filter(pkg *doc.Package) {
+	pkg.Consts = e.filterValues(pkg.Consts)
+	pkg.Vars = e.filterValues(pkg.Vars)
+	pkg.Funcs = e.filterFuncs(pkg.Funcs, "func")
+
+	var types []*doc.Type
+	for _, t := range pkg.Types {
+		if e.excludes("type", t.Name) {
+			continue
+		}
+
+		t.Consts = e.filterValues(t.Consts)
+		t.Vars = e.filterValues(t.Vars)
+		t.Funcs = e.filterFuncs(t.Funcs, "func")
+		t.Methods = e.filterFuncs(t.Methods, "method")
+		types = append(types, t)
+	}
+	pkg.Types = types
+}
+
+func (e *symbolExclusion) filterValues(values []*doc.Value) []*doc.Value {
+	var result []*doc.Value
+	for _, v := range values {
+		if len(v.Names) == 0 {
+			result = append(result, v)
+		}
+
+		for _, name := range v.Names {
+			if !e.excludes(v.Decl.Tok.String(), name) {
+				result = append(result, v)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (e *symbolExclusion) filterFuncs(funcs []*doc.Func, kind string) []*doc.Func {
+	var result []*doc.Func
+	for _, f := range funcs {
+		if !e.excludes(kind, f.Name) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
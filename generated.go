@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+var skipGenerated = flag.Bool("skip-generated", false, "do not document files with a \"Code generated ... DO NOT EDIT.\" header")
+
+// isGeneratedFile reports whether the Go file at the given path, with the
+// given contents if they are not nil, has the header of generated code.
+// Only the comments up to the package clause are parsed.
+func isGeneratedFile(path string, src []byte) bool {
+	// A nil slice would be parsed as an empty file instead of reading it.
+	var contents interface{}
+	if src != nil {
+		contents = src
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), path, contents, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+	return ast.IsGenerated(f)
+}
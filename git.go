@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var withGit = flag.Bool("git", false, "include the git revision the package source is at")
+
+var gitInfos = struct {
+	sync.Mutex
+	m map[string]*GitInfo
+}{m: make(map[string]*GitInfo)}
+
+// gitInfo returns the git revision of the repository containing the given
+// directory, or nil if it is not inside of one. It is computed once per
+// repository.
+func gitInfo(dir string) *GitInfo {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		tracef("%s is not inside a git repository: %s", dir, err)
+		return nil
+	}
+
+	gitInfos.Lock()
+	defer gitInfos.Unlock()
+	if info, ok := gitInfos.m[root]; ok {
+		return info
+	}
+
+	var info *GitInfo
+	if commit, err := git(root, "rev-parse", "HEAD"); err == nil {
+		info = &GitInfo{Commit: commit}
+		info.Tag, _ = git(root, "describe", "--tags", "--exact-match", "HEAD")
+		if branch, err := git(root, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+			info.Branch = branch
+		}
+		if status, err := git(root, "status", "--porcelain"); err == nil {
+			info.Dirty = status != ""
+		}
+	} else {
+		debugf("unable to get the git revision of %s: %s", root, err)
+	}
+
+	gitInfos.m[root] = info
+	return info
+}
+
+// git runs a git command in the given directory and returns its trimmed
+// output.
+func git(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", &gitError{err, msg}
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+type gitError struct {
+	err error
+	msg string
+}
+
+func (e *gitError) Error() string {
+	return e.err.Error() + ": " + e.msg
+}
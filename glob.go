@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// hasGlob reports whether the given argument has any of the metacharacters
+// of shell patterns, such as "github.com/org/*/client".
+func hasGlob(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// expandGlob returns the import paths of the packages matched by a shell
+// pattern, which can be either an import path or a directory and can end
+// in "/..." to match the packages below the matched ones as well. As in
+// the shell, "*" does not match slashes. Only the packages below the part
+// of the pattern without metacharacters, which must be a directory or a
+// package, are matched against it.
+func expandGlob(arg string) ([]string, error) {
+	recursive := strings.HasSuffix(arg, "/...")
+	pattern := strings.TrimSuffix(arg, "/...")
+
+	local := isLocal(pattern)
+	if local {
+		abs, err := filepath.Abs(pattern)
+		if err != nil {
+			return nil, err
+		}
+		pattern = filepath.ToSlash(abs)
+	}
+
+	var (
+		elems  = strings.Split(pattern, "/")
+		prefix []string
+	)
+	for _, e := range elems {
+		if hasGlob(e) {
+			break
+		}
+		prefix = append(prefix, e)
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	base := strings.Join(prefix, "/")
+	if local && base == "" {
+		base = "/"
+	}
+
+	var (
+		root string
+		err  error
+	)
+	if local {
+		root = filepath.FromSlash(base)
+	} else if root, err = packageSrcDir(base); err != nil {
+		return nil, err
+	}
+
+	dirs, err := packageDirsBelow(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []string
+	for _, dir := range dirs {
+		name := filepath.ToSlash(dir)
+		pkg, err := dirImportPath(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if !local {
+			name = pkg
+		}
+
+		if globMatch(pattern, name, recursive) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs, nil
+}
+
+// globMatch reports whether the pattern matches the name or, if recursive,
+// any of its parents.
+func globMatch(pattern, name string, recursive bool) bool {
+	for {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+
+		parent := path.Dir(name)
+		if !recursive || parent == name || parent == "." {
+			return false
+		}
+		name = parent
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+)
+
+// gobWriter writes packages in the gob format, as a stream of Pkg values,
+// for Go programs reading them with the schema package, which decode it
+// much faster than JSON. As it is made of the schema types as they are, it
+// cannot be projected like the JSON output.
+type gobWriter struct {
+	w   *bufio.Writer
+	enc *gob.Encoder
+}
+
+func newGobWriter(w io.Writer, list bool) packageWriter {
+	bw := bufio.NewWriter(w)
+	return &gobWriter{w: bw, enc: gob.NewEncoder(bw)}
+}
+
+func (w *gobWriter) Write(pkg *Pkg) error {
+	return w.enc.Encode(pkg)
+}
+
+func (w *gobWriter) Close() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,452 @@
+// Type definitions of the documents generated by godocjson.
+// Code generated by godocjson -types. DO NOT EDIT.
+
+export interface Pkg {
+	Doc: string;
+	DocRaw?: string;
+	Snippets?: Snippet[] | null;
+	Links?: Link[] | null;
+	Translations?: Record<string, string> | null;
+	Deprecated?: string;
+	Replacement?: Replacement | null;
+	Sections?: DocSection[] | null;
+	Name: string;
+	ImportPath: string;
+	PkgsiteURL?: string;
+	Imports: string[] | null;
+	ImportSpecs: Import[] | null;
+	UsesUnsafe: boolean;
+	UsesReflect: boolean;
+	UsesCgo: boolean;
+	Filenames: string[] | null;
+	Files: File[] | null;
+	ParseErrors?: ParseError[] | null;
+	Notes: Record<string, Note[] | null> | null;
+	Bugs: string[] | null;
+	BugNotes?: Note[] | null;
+	Generate: Generator[] | null;
+	Directives: Directive[] | null;
+	Embeds: Embed[] | null;
+	Stats: Stats | null;
+	GoVersion: GoVersion | null;
+	Licenses: License[] | null;
+	Readme?: Readme | null;
+	Dependencies?: Dependency[] | null;
+	Replaces?: ModuleReplace[] | null;
+	Excludes?: ModuleVersion[] | null;
+	Git?: GitInfo | null;
+	Consts: Value[] | null;
+	Types: Type[] | null;
+	Vars: Value[] | null;
+	Funcs: Func[] | null;
+	Errors: ErrorDecl[] | null;
+	Embeddings: Embedding[] | null;
+	Implementations?: Implementation[] | null;
+	CallGraph?: Call[] | null;
+	Examples?: Example[] | null;
+	Lint?: LintFinding[] | null;
+	DocScore?: PackageDocScore | null;
+	GeneratorVersion: string;
+	SchemaVersion: number;
+	Hash: string;
+}
+
+export interface Index {
+	GeneratorVersion: string;
+	Packages: IndexEntry[] | null;
+	Symbols?: Record<string, IndexSymbol> | null;
+}
+
+export interface Snippet {
+	Code: string;
+	Language: string;
+}
+
+export interface Link {
+	URL: string;
+	Text?: string;
+	Definition?: boolean;
+}
+
+export interface Replacement {
+	ImportPath: string;
+	Name: string;
+	Kind?: string;
+}
+
+export interface DocSection {
+	Heading: string;
+	ID: string;
+	Text: string;
+}
+
+export interface Import {
+	Path: string;
+	Name?: string;
+	IsBlank: boolean;
+	IsDot: boolean;
+	IsStd: boolean;
+	IsInternal: boolean;
+	IsVendored: boolean;
+	Uses: number;
+	Pos: Pos | null;
+}
+
+export interface File {
+	Name: string;
+	Doc?: string;
+	BuildConstraint?: string;
+	Imports: string[] | null;
+	Size: number;
+	Hash: string;
+	IsCgo: boolean;
+	IsGenerated: boolean;
+}
+
+export interface ParseError {
+	Pos: FilePos | null;
+	Message: string;
+}
+
+export interface Note {
+	UID: string;
+	Body: string;
+	Pos: Pos | null;
+}
+
+export interface Generator {
+	Command: string;
+	Pos: Pos | null;
+}
+
+export interface Directive {
+	Name: string;
+	Args?: string;
+	Pos: Pos | null;
+}
+
+export interface Embed {
+	Var: string;
+	Patterns: string[] | null;
+	Pos: Pos | null;
+}
+
+export interface Stats {
+	Files: number;
+	Lines: number;
+	Funcs: Count | null;
+	Methods: Count | null;
+	Types: Count | null;
+	Consts: Count | null;
+	Vars: Count | null;
+}
+
+export interface GoVersion {
+	Directive?: string;
+	Features: LanguageFeature[] | null;
+	Minimum?: string;
+}
+
+export interface License {
+	SPDX: string;
+	File: string;
+}
+
+export interface Readme {
+	File: string;
+	Content: string;
+}
+
+export interface Dependency {
+	Path: string;
+	Version: string;
+	Indirect: boolean;
+	Imported: boolean;
+}
+
+export interface ModuleReplace {
+	Path: string;
+	Version?: string;
+	NewPath: string;
+	NewVersion?: string;
+	Dir?: string;
+	Imported: boolean;
+}
+
+export interface ModuleVersion {
+	Path: string;
+	Version: string;
+}
+
+export interface GitInfo {
+	Commit: string;
+	Tag?: string;
+	Branch?: string;
+	Dirty: boolean;
+}
+
+export interface Value {
+	Kind: string;
+	Doc: string;
+	DocRaw?: string;
+	Snippets?: Snippet[] | null;
+	Links?: Link[] | null;
+	Deprecated?: string;
+	Replacement?: Replacement | null;
+	Since?: string;
+	Names: string[] | null;
+	PkgsiteURLs?: string[] | null;
+	Decl: string;
+	Decls?: string[] | null;
+	Pos: Pos | null;
+	Generated?: boolean;
+	DocScore?: DocScore | null;
+	Tokens?: DeclToken[] | null;
+	AST?: ASTNode | null;
+	NamePos: Pos[] | null;
+	Types?: string[] | null;
+	Directives?: Directive[] | null;
+	Embeds?: Embed[] | null;
+	Iota?: IotaGroup | null;
+	Translations?: Record<string, string> | null;
+}
+
+export interface Type {
+	Kind: string;
+	Doc: string;
+	DocRaw?: string;
+	Snippets?: Snippet[] | null;
+	Links?: Link[] | null;
+	Deprecated?: string;
+	Replacement?: Replacement | null;
+	Since?: string;
+	Name: string;
+	PkgsiteURL?: string;
+	Decl: string;
+	Pos: Pos | null;
+	Generated?: boolean;
+	Lines: LineCount | null;
+	DocScore?: DocScore | null;
+	Tokens?: DeclToken[] | null;
+	AST?: ASTNode | null;
+	Fields: Field[] | null;
+	Directives?: Directive[] | null;
+	Enum?: Enum | null;
+	Consts: Value[] | null;
+	Vars: Value[] | null;
+	Funcs: Func[] | null;
+	Methods: Func[] | null;
+	Inherited?: InheritedMethod[] | null;
+	UsedBy: TypeUses | null;
+	Examples?: Example[] | null;
+	Translations?: Record<string, string> | null;
+}
+
+export interface Func {
+	Kind: string;
+	Doc: string;
+	DocRaw?: string;
+	Snippets?: Snippet[] | null;
+	Links?: Link[] | null;
+	Deprecated?: string;
+	Replacement?: Replacement | null;
+	Since?: string;
+	Name: string;
+	PkgsiteURL?: string;
+	Decl: string;
+	Tokens?: DeclToken[] | null;
+	AST?: ASTNode | null;
+	Params: Field[] | null;
+	Results: Field[] | null;
+	IsVariadic: boolean;
+	ReturnsError: boolean;
+	ErrorResult: number;
+	Errors?: ReturnedError[] | null;
+	Recv: string;
+	Orig: string;
+	Level: number;
+	Pos: Pos | null;
+	Generated?: boolean;
+	Lines: LineCount | null;
+	DocScore?: DocScore | null;
+	Directives?: Directive[] | null;
+	Metrics?: Metrics | null;
+	Examples?: Example[] | null;
+	Translations?: Record<string, string> | null;
+}
+
+export interface ErrorDecl {
+	Kind: string;
+	Name: string;
+	Doc: string;
+	Message?: string;
+	PointerReceiver?: boolean;
+	Pos: Pos | null;
+}
+
+export interface Embedding {
+	Type: string;
+	Embedded: string;
+	Kind: string;
+	Pointer?: boolean;
+}
+
+export interface Implementation {
+	Type: string;
+	Interface: string;
+	Implements: boolean;
+	Pointer?: boolean;
+	Missing?: string[] | null;
+}
+
+export interface Call {
+	Caller: string;
+	Callee: string;
+	Dynamic?: boolean;
+	Indirect?: boolean;
+	Pos: FilePos | null;
+}
+
+export interface Example {
+	Name: string;
+	Suffix?: string;
+	Doc: string;
+	Code: string;
+	Play?: string;
+	Trailing?: string;
+	Output: string;
+	Unordered?: boolean;
+	EmptyOutput?: boolean;
+	Pos: Pos | null;
+}
+
+export interface LintFinding {
+	Linter: string;
+	Symbol: string;
+	Message: string;
+	Pos: Pos | null;
+}
+
+export interface PackageDocScore {
+	Score: number;
+	Doc: DocScore | null;
+	Symbols: number;
+	Undocumented: number;
+}
+
+export interface IndexEntry {
+	ImportPath: string;
+	Name: string;
+	Synopsis: string;
+	File: string;
+	Hash: string;
+}
+
+export interface IndexSymbol {
+	ImportPath: string;
+	Name: string;
+	Kind: string;
+	File: string;
+}
+
+export interface Pos {
+	Start: FilePos | null;
+	End: FilePos | null;
+}
+
+export interface FilePos {
+	Line: number;
+	Column: number;
+	File: string;
+}
+
+export interface Count {
+	Exported: number;
+	Unexported: number;
+}
+
+export interface LanguageFeature {
+	Name: string;
+	Version: string;
+	Pos: FilePos | null;
+}
+
+export interface DocScore {
+	Score: number;
+	Failed?: string[] | null;
+}
+
+export interface DeclToken {
+	Kind: string;
+	Offset: number;
+	Len: number;
+}
+
+export interface ASTNode {
+	Kind: string;
+	Value?: string;
+	Pos: Pos | null;
+	Children?: ASTNode[] | null;
+}
+
+export interface IotaGroup {
+	Members: IotaMember[] | null;
+	Sequence: boolean;
+	Bitmask: boolean;
+}
+
+export interface LineCount {
+	Decl: number;
+	Body?: number;
+}
+
+export interface Field {
+	Name: string;
+	Type: string;
+	Embedded?: boolean;
+	Tag?: string;
+	Doc?: string;
+	IsVariadic?: boolean;
+}
+
+export interface Enum {
+	Members: EnumMember[] | null;
+}
+
+export interface InheritedMethod {
+	Name: string;
+	Package: string;
+	Recv: string;
+	Embedded: string;
+	Signature: string;
+	Level?: number;
+	Pointer?: boolean;
+}
+
+export interface TypeUses {
+	Accepting: string[] | null;
+	Returning: string[] | null;
+}
+
+export interface ReturnedError {
+	Name: string;
+	Doc: string;
+}
+
+export interface Metrics {
+	Lines: number;
+	Statements: number;
+	Complexity: number;
+}
+
+export interface IotaMember {
+	Name: string;
+	Ordinal: number;
+	Value?: string;
+}
+
+export interface EnumMember {
+	Name: string;
+	Value?: string;
+	Doc?: string;
+}
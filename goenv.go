@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"go/build"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	parseutil "gopkg.in/src-d/go-parse-utils.v1"
+)
+
+// goEnvVars are the variables of go env packages are resolved with.
+var goEnvVars = []string{"GOPATH", "GOROOT", "GOMODCACHE", "GOFLAGS"}
+
+// goModCache is the module cache reported by go env, if it could be run.
+var goModCache string
+
+// goEnv returns the values of goEnvVars as the go command of the given
+// GOROOT, or the one in PATH if it is empty, sees them, which includes the
+// settings of its GOENV file. It returns nil if the go command cannot be
+// run.
+func goEnv(goroot string) map[string]string {
+	gocmd := "go"
+	if goroot != "" {
+		gocmd = filepath.Join(goroot, "bin", "go")
+		if runtime.GOOS == "windows" {
+			gocmd += ".exe"
+		}
+	}
+
+	cmd := exec.Command(gocmd, append([]string{"env", "-json"}, goEnvVars...)...)
+	if goroot != "" {
+		cmd.Env = append(os.Environ(), "GOROOT="+goroot)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		debugf("unable to run go env, resolving packages with the defaults of the environment: %s", err)
+		return nil
+	}
+
+	var env map[string]string
+	if err := json.Unmarshal(out, &env); err != nil {
+		debugf("unable to parse the output of go env: %s", err)
+		return nil
+	}
+	return env
+}
+
+// applyGoEnv resolves packages with the GOPATH, GOROOT, module cache and
+// build tags of GOFLAGS the go command would use, instead of the defaults
+// of go/build, which ignore the GOENV file and GOFLAGS. The given GOROOT
+// and GOFLAGS override those of go env, and so does -gopath, which is
+// applied afterwards.
+func applyGoEnv(goroot, goflags string) {
+	env := goEnv(goroot)
+	if goroot == "" {
+		goroot = env["GOROOT"]
+	}
+
+	if goroot != "" {
+		build.Default.GOROOT = goroot
+	}
+
+	if gopath := env["GOPATH"]; gopath != "" && gopath != build.Default.GOPATH {
+		build.Default.GOPATH = gopath
+		parseutil.DefaultGoPath = parseutil.GoPath(filepath.SplitList(gopath))
+	}
+	goModCache = env["GOMODCACHE"]
+
+	if goflags == "" {
+		goflags = env["GOFLAGS"]
+	}
+
+	if tags, ok := goflagsTags(goflags); ok {
+		build.Default.BuildTags = tags
+	}
+}
+
+// goflagsTags returns the comma-separated build tags given with -tags in
+// GOFLAGS, whose flags are separated by spaces and always given as
+// -flag=value. The last -tags wins, as in the go command.
+func goflagsTags(goflags string) ([]string, bool) {
+	var (
+		tags  []string
+		found bool
+	)
+	for _, f := range strings.Fields(goflags) {
+		name, value, _ := strings.Cut(f, "=")
+		if name != "-tags" && name != "--tags" {
+			continue
+		}
+
+		found, tags = true, nil
+		for _, tag := range strings.Split(value, ",") {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, found
+}
+
+// stdlibSrcDir returns the directory of a package of the standard library
+// in GOROOT, if it exists.
+func stdlibSrcDir(pkg string) (string, bool) {
+	if !isStdlib(pkg) || build.Default.GOROOT == "" {
+		return "", false
+	}
+
+	dir := filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(pkg))
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", false
+	}
+	return dir, true
+}
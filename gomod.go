@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// goModFile is the part of a go.mod file needed to document the
+// dependencies of a module.
+type goModFile struct {
+	Requires []*goModRequire
+	Replaces []*goModReplace
+	Excludes []*goModRequire
+}
+
+// goModRequire is a require directive of a go.mod file.
+type goModRequire struct {
+	Path    string
+	Version string
+	// Indirect reports whether it is marked with an "// indirect" comment,
+	// as the go command does with the requirements not imported by any
+	// package of the module.
+	Indirect bool
+}
+
+// goModReplace is a replace directive of a go.mod file.
+type goModReplace struct {
+	// Path and Version are the module replaced, in all its versions if
+	// Version is empty.
+	Path    string
+	Version string
+	// NewPath and NewVersion are the module it is replaced with, which is
+	// a directory if NewVersion is empty.
+	NewPath    string
+	NewVersion string
+}
+
+// dir returns the directory a module is replaced with, relative to the
+// given module root, or an empty string if it is replaced with another
+// module.
+func (r *goModReplace) dir(root string) string {
+	switch {
+	case r.NewVersion != "":
+		return ""
+	case filepath.IsAbs(r.NewPath):
+		return r.NewPath
+	}
+	return filepath.Join(root, filepath.FromSlash(r.NewPath))
+}
+
+// goModFiles are the go.mod files read, by module root, along with the
+// error reading them, as every package of a module needs the same one.
+var goModFiles = struct {
+	sync.Mutex
+	m map[string]*goModResult
+}{m: make(map[string]*goModResult)}
+
+type goModResult struct {
+	file *goModFile
+	err  error
+}
+
+// readGoMod returns the go.mod file at the given module root, which is
+// only read and parsed the first time.
+func readGoMod(root string) (*goModFile, error) {
+	goModFiles.Lock()
+	defer goModFiles.Unlock()
+	if r, ok := goModFiles.m[root]; ok {
+		return r.file, r.err
+	}
+
+	f, err := parseGoMod(root)
+	goModFiles.m[root] = &goModResult{f, err}
+	return f, err
+}
+
+// parseGoMod reads the go.mod file at the given module root. Both single
+// directives and blocks of them are supported.
+func parseGoMod(root string) (*goModFile, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		f     goModFile
+		block string
+	)
+	for _, line := range strings.Split(string(data), "\n") {
+		line, comment, _ := strings.Cut(line, "//")
+		fields := goModFields(line)
+		switch {
+		case len(fields) == 0:
+		case block != "" && fields[0] == ")":
+			block = ""
+		case block != "":
+			f.add(block, fields, comment)
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		default:
+			f.add(fields[0], fields[1:], comment)
+		}
+	}
+	return &f, nil
+}
+
+func (f *goModFile) add(verb string, args []string, comment string) {
+	switch verb {
+	case "require":
+		if len(args) < 2 {
+			return
+		}
+
+		comment = strings.TrimSpace(comment)
+		f.Requires = append(f.Requires, &goModRequire{
+			Path:     args[0],
+			Version:  args[1],
+			Indirect: comment == "indirect" || strings.HasPrefix(comment, "indirect;"),
+		})
+	case "exclude":
+		if len(args) < 2 {
+			return
+		}
+
+		f.Excludes = append(f.Excludes, &goModRequire{Path: args[0], Version: args[1]})
+	case "replace":
+		// Either "old => new" or "old version => new version", with the
+		// versions being optional.
+		var arrow = -1
+		for i, arg := range args {
+			if arg == "=>" {
+				arrow = i
+			}
+		}
+
+		if arrow < 1 || arrow > 2 || len(args)-arrow < 2 || len(args)-arrow > 3 {
+			return
+		}
+
+		r := &goModReplace{Path: args[0], NewPath: args[arrow+1]}
+		if arrow == 2 {
+			r.Version = args[1]
+		}
+
+		if len(args)-arrow == 3 {
+			r.NewVersion = args[arrow+2]
+		}
+		f.Replaces = append(f.Replaces, r)
+	}
+}
+
+// goModFields splits a line of a go.mod file into its fields, unquoting
+// the quoted ones.
+func goModFields(line string) []string {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if s, err := strconv.Unquote(f); err == nil {
+			fields[i] = s
+		}
+	}
+	return fields
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	goversion "go/version"
+	"sort"
+	"strings"
+)
+
+// NewGoVersion returns the minimum Go version of the package.
+func NewGoVersion(src *Source) *GoVersion {
+	v := &GoVersion{Features: src.Features}
+	if src.Dir != "" {
+		if root := moduleRoot(src.Dir); root != "" {
+			v.Directive = goModDirective(root, "go")
+		}
+	}
+
+	if v.Directive != "" {
+		v.Minimum = v.Directive
+	}
+
+	for _, f := range v.Features {
+		if v.Minimum == "" || goversion.Compare("go"+f.Version, "go"+v.Minimum) > 0 {
+			v.Minimum = f.Version
+		}
+	}
+	return v
+}
+
+// languageFeatures returns the versioned language features used in the
+// files of the package, sorted by version. Some of them, such as ranging
+// over functions, can only be found if types are resolved.
+func languageFeatures(src *Source) []*LanguageFeature {
+	var (
+		seen     = make(map[string]*LanguageFeature)
+		declared = make(map[string]bool)
+	)
+
+	// Packages written for older versions often declare their own min and
+	// max functions.
+	for _, f := range src.Files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				declared[fn.Name.Name] = true
+			}
+		}
+	}
+
+	use := func(name, version string, pos token.Pos) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = &LanguageFeature{Name: name, Version: version, Pos: NewFilePos(pos, src.Fset)}
+		}
+	}
+
+	for _, f := range src.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncType:
+				if n.TypeParams != nil {
+					use("type parameters", "1.18", n.TypeParams.Pos())
+				}
+			case *ast.TypeSpec:
+				if n.TypeParams != nil {
+					use("type parameters", "1.18", n.TypeParams.Pos())
+				}
+
+				if n.Assign.IsValid() {
+					use("type aliases", "1.9", n.Pos())
+					if n.TypeParams != nil {
+						use("generic type aliases", "1.24", n.Pos())
+					}
+				}
+			case *ast.Ident:
+				if (n.Name == "any" || n.Name == "comparable") && src.isUniverse(n) && !declared[n.Name] {
+					use(n.Name, "1.18", n.Pos())
+				}
+			case *ast.CallExpr:
+				if id, ok := n.Fun.(*ast.Ident); ok && src.isUniverse(id) && !declared[id.Name] {
+					switch id.Name {
+					case "min", "max":
+						use("min and max builtins", "1.21", n.Pos())
+					case "clear":
+						use("clear builtin", "1.21", n.Pos())
+					}
+				}
+			case *ast.SelectorExpr:
+				if x, ok := n.X.(*ast.Ident); ok && x.Name == "unsafe" {
+					switch n.Sel.Name {
+					case "Add", "Slice":
+						use("unsafe."+n.Sel.Name, "1.17", n.Pos())
+					case "String", "StringData", "SliceData":
+						use("unsafe."+n.Sel.Name, "1.20", n.Pos())
+					}
+				}
+			case *ast.BasicLit:
+				if isNewNumberLiteral(n) {
+					use("binary, octal and separated number literals", "1.13", n.Pos())
+				}
+			case *ast.RangeStmt:
+				switch src.rangeKind(n.X) {
+				case "int":
+					use("range over integers", "1.22", n.X.Pos())
+				case "func":
+					use("range over functions", "1.23", n.X.Pos())
+				}
+			}
+			return true
+		})
+	}
+
+	var features = []*LanguageFeature{}
+	for _, f := range seen {
+		features = append(features, f)
+	}
+
+	sort.Slice(features, func(i, j int) bool {
+		if c := goversion.Compare("go"+features[i].Version, "go"+features[j].Version); c != 0 {
+			return c < 0
+		}
+		return features[i].Name < features[j].Name
+	})
+	return features
+}
+
+// isUniverse reports whether the identifier refers to a predeclared
+// object. Without type information, that is assumed.
+func (s *Source) isUniverse(id *ast.Ident) bool {
+	if s.Info == nil {
+		return true
+	}
+
+	obj, ok := s.Info.Uses[id]
+	return ok && obj.Parent() == types.Universe
+}
+
+// rangeKind returns "int" or "func" if the expression of a range statement
+// is an integer or a function. Without type information, only integer
+// literals and calls to len are recognized.
+func (s *Source) rangeKind(x ast.Expr) string {
+	if s.Info != nil {
+		if t := s.Info.TypeOf(x); t != nil {
+			switch u := t.Underlying().(type) {
+			case *types.Basic:
+				if u.Info()&types.IsInteger != 0 {
+					return "int"
+				}
+			case *types.Signature:
+				return "func"
+			}
+			return ""
+		}
+	}
+
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		if x.Kind == token.INT {
+			return "int"
+		}
+	case *ast.CallExpr:
+		if id, ok := x.Fun.(*ast.Ident); ok && id.Name == "len" {
+			return "int"
+		}
+	case *ast.FuncLit:
+		return "func"
+	}
+	return ""
+}
+
+// isNewNumberLiteral reports whether the literal uses the syntax added in
+// Go 1.13: binary and 0o octal prefixes, hexadecimal floats and digit
+// separators.
+func isNewNumberLiteral(lit *ast.BasicLit) bool {
+	if lit.Kind != token.INT && lit.Kind != token.FLOAT && lit.Kind != token.IMAG {
+		return false
+	}
+
+	v := strings.ToLower(lit.Value)
+	return strings.HasPrefix(v, "0b") || strings.HasPrefix(v, "0o") ||
+		strings.Contains(v, "_") || (strings.HasPrefix(v, "0x") && strings.Contains(v, "p"))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportGraph is the graph of imports between packages.
+type ImportGraph struct {
+	Nodes []*GraphNode
+	Edges []*GraphEdge
+}
+
+// GraphNode is a package in the import graph. Kind is "stdlib" for
+// packages of the standard library, "internal" for packages of the module
+// being documented and "external" for the rest.
+type GraphNode struct {
+	ImportPath string
+	Kind       string
+}
+
+// GraphEdge is an import of To by From.
+type GraphEdge struct {
+	From string
+	To   string
+}
+
+func runGraph(args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	graphFormat := fs.String("format", "json", "output format: json or dot")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson graph [-format dot] [packages]\n\nPrints the import graph of the given packages, ./... by default.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *graphFormat != "json" && *graphFormat != "dot" {
+		fatalf("invalid -format %q: expecting json or dot", *graphFormat)
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	pkgNames, err := expandPatterns(patterns)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	g, err := buildImportGraph(pkgNames)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if *graphFormat == "dot" {
+		if err := writeImportGraphDOT(os.Stdout, g); err != nil {
+			fatalf("%s", err)
+		}
+		return
+	}
+
+	printJSON(g, nil)
+}
+
+// buildImportGraph returns the import graph of the given packages, with
+// nodes for them and every package they import.
+func buildImportGraph(pkgNames []string) (*ImportGraph, error) {
+	var (
+		g        = &ImportGraph{Nodes: []*GraphNode{}, Edges: []*GraphEdge{}}
+		kinds    = make(map[string]string)
+		internal = make(map[string]bool)
+		modules  = make(map[string]bool)
+	)
+
+	for _, pkg := range pkgNames {
+		internal[pkg] = true
+	}
+
+	for _, pkg := range pkgNames {
+		dir, err := packageSrcDir(pkg)
+		if err != nil {
+			return nil, err
+		}
+
+		if root := moduleRoot(dir); root != "" {
+			if mod := modulePath(root); mod != "" {
+				modules[mod] = true
+			}
+		}
+
+		imports, err := packageImports(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		kinds[pkg] = "internal"
+		for _, imp := range imports {
+			g.Edges = append(g.Edges, &GraphEdge{From: pkg, To: imp})
+		}
+	}
+
+	for _, e := range g.Edges {
+		if _, ok := kinds[e.To]; ok {
+			continue
+		}
+
+		switch {
+		case isStdlib(e.To):
+			kinds[e.To] = "stdlib"
+		case internal[e.To] || inModules(e.To, modules):
+			kinds[e.To] = "internal"
+		default:
+			kinds[e.To] = "external"
+		}
+	}
+
+	for pkg, kind := range kinds {
+		g.Nodes = append(g.Nodes, &GraphNode{ImportPath: pkg, Kind: kind})
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ImportPath < g.Nodes[j].ImportPath
+	})
+
+	return g, nil
+}
+
+// packageImports returns the sorted import paths of the package in the
+// given directory.
+func packageImports(dir string) ([]string, error) {
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		fset    = token.NewFileSet()
+		seen    = make(map[string]bool)
+		imports []string
+	)
+
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, imp := range f.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || seen[path] {
+				continue
+			}
+			seen[path] = true
+			imports = append(imports, path)
+		}
+	}
+
+	sort.Strings(imports)
+	return imports, nil
+}
+
+// isStdlib reports whether the given import path belongs to the standard
+// library, which, as the go tool assumes, is the case when its first
+// element contains no dot.
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func inModules(path string, modules map[string]bool) bool {
+	for mod := range modules {
+		if path == mod || strings.HasPrefix(path, mod+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// modulePath returns the path of the module declared in the go.mod file at
+// the given module root, or an empty string if it cannot be read.
+func modulePath(root string) string {
+	return strings.Trim(goModDirective(root, "module"), `"`)
+}
+
+// goModDirective returns the arguments of the first directive with the
+// given name in the go.mod file at the given module root, or an empty
+// string if there is none.
+func goModDirective(root, name string) string {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if rest, ok := strings.CutPrefix(line, name); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			args := strings.TrimSpace(rest)
+			if i := strings.Index(args, "//"); i >= 0 {
+				args = strings.TrimSpace(args[:i])
+			}
+			return args
+		}
+	}
+	return ""
+}
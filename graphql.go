@@ -0,0 +1,950 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements the subset of GraphQL needed to query the
+// documentation: queries with variables, aliases, arguments, fragments and
+// the @include and @skip directives. There are no mutations or
+// subscriptions, and no introspection beyond __typename.
+//
+// Objects are Go structs. Their fields are resolved by name,
+// case-insensitively, so the ImportPath field of a package is queried as
+// importPath. A schema can add computed fields to any type.
+
+// gqlSchema holds the computed fields of every type and the name they are
+// known by in GraphQL, if it is not the name of the Go type.
+type gqlSchema struct {
+	fields map[reflect.Type]map[string]gqlResolver
+	names  map[reflect.Type]string
+}
+
+// gqlResolver returns the value of a computed field of obj.
+type gqlResolver func(obj interface{}, args map[string]interface{}) (interface{}, error)
+
+func newGQLSchema() *gqlSchema {
+	return &gqlSchema{
+		fields: make(map[reflect.Type]map[string]gqlResolver),
+		names:  make(map[reflect.Type]string),
+	}
+}
+
+// object registers the type of obj, a pointer to a struct, with the given
+// GraphQL name.
+func (s *gqlSchema) object(obj interface{}, name string) {
+	s.names[reflect.TypeOf(obj)] = name
+}
+
+// field adds a computed field to the type of obj.
+func (s *gqlSchema) field(obj interface{}, name string, r gqlResolver) {
+	t := reflect.TypeOf(obj)
+	if s.fields[t] == nil {
+		s.fields[t] = make(map[string]gqlResolver)
+	}
+	s.fields[t][name] = r
+}
+
+func (s *gqlSchema) typeName(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.typeName(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return "[" + s.typeName(t.Elem()) + "]"
+	}
+	return t.Name()
+}
+
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type gqlResponse struct {
+	Data   gqlObject   `json:"data,omitempty"`
+	Errors []*gqlError `json:"errors,omitempty"`
+}
+
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// gqlObject is an object of the response. A slice is used so fields are
+// encoded in the order they were requested.
+type gqlObject []gqlEntry
+
+type gqlEntry struct {
+	key   string
+	value interface{}
+}
+
+func (o gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(e.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// executeGraphQL runs the query of the request against root. Once ctx is
+// done, or the result has more than maxGQLNodes fields and list elements,
+// no more fields are resolved and an error is reported.
+func executeGraphQL(ctx context.Context, schema *gqlSchema, root interface{}, req *gqlRequest) *gqlResponse {
+	doc, err := parseGraphQL(req.Query)
+	if err != nil {
+		return &gqlResponse{Errors: []*gqlError{{Message: err.Error()}}}
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &gqlResponse{Errors: []*gqlError{{Message: err.Error()}}}
+	}
+
+	vars := make(map[string]interface{})
+	for _, def := range op.vars {
+		if v, ok := req.Variables[def.name]; ok {
+			vars[def.name] = v
+		} else if def.hasDefault {
+			vars[def.name] = def.def
+		}
+	}
+
+	e := &gqlExecutor{ctx: ctx, schema: schema, doc: doc, vars: vars}
+	data := e.selectObject(reflect.ValueOf(root), op.sels, nil)
+	return &gqlResponse{Data: data, Errors: e.errors}
+}
+
+// maxGQLNodes is the maximum number of fields and list elements of a
+// result, so small queries selecting large lists many times over cannot
+// take the memory and time of the server.
+const maxGQLNodes = 100000
+
+type gqlExecutor struct {
+	ctx    context.Context
+	schema *gqlSchema
+	doc    *gqlDocument
+	vars   map[string]interface{}
+	errors []*gqlError
+	// nodes is the number of fields and list elements of the result so
+	// far, and stopped is set once fields are no longer resolved.
+	nodes   int
+	stopped bool
+}
+
+func (e *gqlExecutor) fail(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, &gqlError{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]interface{}(nil), path...),
+	})
+}
+
+// selectObject resolves the given selections on obj, which must be a
+// pointer to a struct.
+func (e *gqlExecutor) selectObject(obj reflect.Value, sels []*gqlSelection, path []interface{}) gqlObject {
+	var (
+		result = gqlObject{}
+		index  = make(map[string]int)
+	)
+
+	for _, f := range e.collectFields(obj.Type(), sels, nil) {
+		key := f.name
+		if f.alias != "" {
+			key = f.alias
+		}
+
+		fieldPath := append(path, key)
+		value := e.resolveField(obj, f, fieldPath)
+		if i, ok := index[key]; ok {
+			result[i].value = value
+			continue
+		}
+
+		index[key] = len(result)
+		result = append(result, gqlEntry{key: key, value: value})
+	}
+
+	return result
+}
+
+// collectFields returns the fields selected on an object of type t,
+// expanding fragments and applying directives.
+func (e *gqlExecutor) collectFields(t reflect.Type, sels []*gqlSelection, visited map[string]bool) []*gqlSelection {
+	var fields []*gqlSelection
+	for _, s := range sels {
+		if !e.included(s) {
+			continue
+		}
+
+		switch {
+		case s.spread != "":
+			if visited[s.spread] {
+				continue
+			}
+
+			frag, ok := e.doc.fragments[s.spread]
+			if !ok {
+				e.fail(nil, "unknown fragment %q", s.spread)
+				continue
+			}
+
+			if frag.typeCond != e.schema.typeName(t) {
+				continue
+			}
+
+			if visited == nil {
+				visited = make(map[string]bool)
+			}
+			visited[s.spread] = true
+			fields = append(fields, e.collectFields(t, frag.sels, visited)...)
+		case s.inline:
+			if s.typeCond != "" && s.typeCond != e.schema.typeName(t) {
+				continue
+			}
+			fields = append(fields, e.collectFields(t, s.sels, visited)...)
+		default:
+			fields = append(fields, s)
+		}
+	}
+	return fields
+}
+
+func (e *gqlExecutor) included(s *gqlSelection) bool {
+	for _, d := range s.directives {
+		if d.name != "include" && d.name != "skip" {
+			continue
+		}
+
+		v, _ := e.value(d.args["if"]).(bool)
+		if (d.name == "include") != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *gqlExecutor) resolveField(obj reflect.Value, f *gqlSelection, path []interface{}) interface{} {
+	if !e.proceed(path, 1) {
+		return nil
+	}
+
+	if f.name == "__typename" {
+		return e.schema.typeName(obj.Type())
+	}
+
+	if r, ok := e.schema.fields[obj.Type()][f.name]; ok {
+		args := make(map[string]interface{}, len(f.args))
+		for k, v := range f.args {
+			args[k] = e.value(v)
+		}
+
+		v, err := r(obj.Interface(), args)
+		if err != nil {
+			e.fail(path, "%s", err)
+			return nil
+		}
+		return e.complete(reflect.ValueOf(v), f, path)
+	}
+
+	if len(f.args) > 0 {
+		e.fail(path, "field %q of type %s takes no arguments", f.name, e.schema.typeName(obj.Type()))
+		return nil
+	}
+
+	st := obj.Elem()
+	sf, ok := st.Type().FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, f.name)
+	})
+	if !ok || sf.PkgPath != "" {
+		e.fail(path, "unknown field %q on type %s", f.name, e.schema.typeName(obj.Type()))
+		return nil
+	}
+
+	return e.complete(st.FieldByIndex(sf.Index), f, path)
+}
+
+// proceed reports whether the result can grow by n more fields or list
+// elements, reporting an error and stopping otherwise, or if ctx is done.
+func (e *gqlExecutor) proceed(path []interface{}, n int) bool {
+	if e.stopped {
+		return false
+	}
+
+	if err := e.ctx.Err(); err != nil {
+		e.fail(path, "%s", err)
+		e.stopped = true
+		return false
+	}
+
+	if e.nodes += n; e.nodes > maxGQLNodes {
+		e.fail(path, "result has more than %d fields and list elements", maxGQLNodes)
+		e.stopped = true
+		return false
+	}
+	return true
+}
+
+// complete turns a resolved value into its representation in the response.
+// Structs become objects with the subfields selected on them and
+// everything else is encoded as is.
+func (e *gqlExecutor) complete(v reflect.Value, f *gqlSelection, path []interface{}) interface{} {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+		return nil
+	}
+
+	object := isGQLObject(v.Type())
+	if object && f.sels == nil {
+		e.fail(path, "field %q of type %s must have a selection of subfields", f.name, e.schema.typeName(v.Type()))
+		return nil
+	} else if !object && f.sels != nil {
+		e.fail(path, "field %q must not have a selection of subfields", f.name)
+		return nil
+	}
+
+	return e.completeValue(v, f.sels, path)
+}
+
+func (e *gqlExecutor) completeValue(v reflect.Value, sels []*gqlSelection, path []interface{}) interface{} {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+
+		if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+			return e.selectObject(v, sels, path)
+		}
+		return e.completeValue(v.Elem(), sels, path)
+	case reflect.Struct:
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return e.selectObject(ptr, sels, path)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+
+		if !e.proceed(path, v.Len()) {
+			return nil
+		}
+
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = e.completeValue(v.Index(i), sels, append(path, i))
+		}
+		return list
+	default:
+		return v.Interface()
+	}
+}
+
+// isGQLObject reports whether values of type t, or its elements if it is a
+// list, are objects with subfields.
+func isGQLObject(t reflect.Type) bool {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		case reflect.Struct:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// value returns the given argument value with its variables replaced.
+func (e *gqlExecutor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case gqlVariable:
+		return e.vars[string(v)]
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			list[i] = e.value(elem)
+		}
+		return list
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			obj[k] = e.value(elem)
+		}
+		return obj
+	default:
+		return v
+	}
+}
+
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+// operation returns the operation with the given name, or the only one in
+// the document if the name is empty.
+func (d *gqlDocument) operation(name string) (*gqlOperation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return nil, fmt.Errorf("an operation name is required for documents with %d operations", len(d.operations))
+		}
+		return d.operations[0], nil
+	}
+
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+type gqlOperation struct {
+	name string
+	vars []*gqlVarDef
+	sels []*gqlSelection
+}
+
+type gqlVarDef struct {
+	name       string
+	def        interface{}
+	hasDefault bool
+}
+
+type gqlFragment struct {
+	typeCond string
+	sels     []*gqlSelection
+}
+
+// gqlSelection is a field, fragment spread or inline fragment.
+type gqlSelection struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	directives []*gqlDirective
+	sels       []*gqlSelection
+
+	spread   string
+	inline   bool
+	typeCond string
+}
+
+type gqlDirective struct {
+	name string
+	args map[string]interface{}
+}
+
+// gqlVariable is a reference to a variable in an argument value.
+type gqlVariable string
+
+type gqlTokenKind int
+
+const (
+	gqlEOF gqlTokenKind = iota
+	gqlPunct
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+)
+
+type gqlToken struct {
+	kind gqlTokenKind
+	val  string
+	pos  int
+}
+
+// maxGQLDepth is the maximum nesting of the selection sets and values of a
+// document, so deeply nested ones cannot exhaust the stack.
+const maxGQLDepth = 64
+
+type gqlParser struct {
+	src   string
+	pos   int
+	tok   gqlToken
+	depth int
+}
+
+// parseGraphQL parses a GraphQL document.
+func parseGraphQL(src string) (doc *gqlDocument, err error) {
+	p := &gqlParser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			if perr, ok := r.(gqlSyntaxError); ok {
+				err = perr
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	p.next()
+	doc = &gqlDocument{fragments: make(map[string]*gqlFragment)}
+	for p.tok.kind != gqlEOF {
+		switch {
+		case p.is(gqlPunct, "{"):
+			doc.operations = append(doc.operations, &gqlOperation{sels: p.selectionSet()})
+		case p.is(gqlName, "query"):
+			doc.operations = append(doc.operations, p.operationDef())
+		case p.is(gqlName, "mutation"), p.is(gqlName, "subscription"):
+			return nil, fmt.Errorf("%s operations are not supported", p.tok.val)
+		case p.is(gqlName, "fragment"):
+			p.next()
+			name := p.name()
+			p.expectName("on")
+			doc.fragments[name] = &gqlFragment{typeCond: p.name()}
+			p.directives()
+			doc.fragments[name].sels = p.selectionSet()
+		default:
+			p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("the document does not contain any operation")
+	}
+	return doc, nil
+}
+
+type gqlSyntaxError struct {
+	line, col int
+	msg       string
+}
+
+func (e gqlSyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.line, e.col, e.msg)
+}
+
+func (p *gqlParser) fail(pos int, format string, args ...interface{}) {
+	line := 1 + strings.Count(p.src[:pos], "\n")
+	col := utf8.RuneCountInString(p.src[strings.LastIndexByte(p.src[:pos], '\n')+1:pos]) + 1
+	panic(gqlSyntaxError{line: line, col: col, msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *gqlParser) unexpected() {
+	if p.tok.kind == gqlEOF {
+		p.fail(p.tok.pos, "unexpected end of document")
+	}
+	p.fail(p.tok.pos, "unexpected %q", p.tok.val)
+}
+
+func (p *gqlParser) is(kind gqlTokenKind, val string) bool {
+	return p.tok.kind == kind && p.tok.val == val
+}
+
+func (p *gqlParser) expect(punct string) {
+	if !p.is(gqlPunct, punct) {
+		p.unexpected()
+	}
+	p.next()
+}
+
+func (p *gqlParser) expectName(name string) {
+	if !p.is(gqlName, name) {
+		p.unexpected()
+	}
+	p.next()
+}
+
+func (p *gqlParser) name() string {
+	if p.tok.kind != gqlName {
+		p.unexpected()
+	}
+	name := p.tok.val
+	p.next()
+	return name
+}
+
+func (p *gqlParser) operationDef() *gqlOperation {
+	p.next()
+	op := new(gqlOperation)
+	if p.tok.kind == gqlName {
+		op.name = p.name()
+	}
+
+	if p.is(gqlPunct, "(") {
+		p.next()
+		for !p.is(gqlPunct, ")") {
+			p.expect("$")
+			def := &gqlVarDef{name: p.name()}
+			p.expect(":")
+			p.varType()
+			if p.is(gqlPunct, "=") {
+				p.next()
+				def.def, def.hasDefault = p.value(true), true
+			}
+			p.directives()
+			op.vars = append(op.vars, def)
+		}
+		p.next()
+	}
+
+	p.directives()
+	op.sels = p.selectionSet()
+	return op
+}
+
+// varType skips the type of a variable definition. Types are not checked,
+// values are coerced by the fields using them.
+func (p *gqlParser) varType() {
+	if p.is(gqlPunct, "[") {
+		p.next()
+		p.varType()
+		p.expect("]")
+	} else {
+		p.name()
+	}
+
+	if p.is(gqlPunct, "!") {
+		p.next()
+	}
+}
+
+// nest enters a selection set or value, failing if it is nested too deep.
+// The returned function leaves it.
+func (p *gqlParser) nest() func() {
+	p.depth++
+	if p.depth > maxGQLDepth {
+		p.fail(p.tok.pos, "nested more than %d levels", maxGQLDepth)
+	}
+	return func() { p.depth-- }
+}
+
+func (p *gqlParser) selectionSet() []*gqlSelection {
+	defer p.nest()()
+	p.expect("{")
+	sels := []*gqlSelection{}
+	for !p.is(gqlPunct, "}") {
+		sels = append(sels, p.selection())
+	}
+	p.next()
+	return sels
+}
+
+func (p *gqlParser) selection() *gqlSelection {
+	if p.is(gqlPunct, "...") {
+		p.next()
+		if p.tok.kind == gqlName && p.tok.val != "on" {
+			s := &gqlSelection{spread: p.name()}
+			s.directives = p.directives()
+			return s
+		}
+
+		s := &gqlSelection{inline: true}
+		if p.is(gqlName, "on") {
+			p.next()
+			s.typeCond = p.name()
+		}
+		s.directives = p.directives()
+		s.sels = p.selectionSet()
+		return s
+	}
+
+	s := &gqlSelection{name: p.name()}
+	if p.is(gqlPunct, ":") {
+		p.next()
+		s.alias, s.name = s.name, p.name()
+	}
+
+	s.args = p.arguments()
+	s.directives = p.directives()
+	if p.is(gqlPunct, "{") {
+		s.sels = p.selectionSet()
+	}
+	return s
+}
+
+func (p *gqlParser) arguments() map[string]interface{} {
+	if !p.is(gqlPunct, "(") {
+		return nil
+	}
+	p.next()
+
+	args := make(map[string]interface{})
+	for !p.is(gqlPunct, ")") {
+		name := p.name()
+		p.expect(":")
+		args[name] = p.value(false)
+	}
+	p.next()
+	return args
+}
+
+func (p *gqlParser) directives() []*gqlDirective {
+	var ds []*gqlDirective
+	for p.is(gqlPunct, "@") {
+		p.next()
+		ds = append(ds, &gqlDirective{name: p.name(), args: p.arguments()})
+	}
+	return ds
+}
+
+// value parses an argument value. Constant values cannot refer to
+// variables.
+func (p *gqlParser) value(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case gqlInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.val, 10, 64)
+		if err != nil {
+			p.fail(tok.pos, "invalid integer %s", tok.val)
+		}
+		return float64(n)
+	case gqlFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			p.fail(tok.pos, "invalid number %s", tok.val)
+		}
+		return f
+	case gqlString:
+		p.next()
+		return tok.val
+	case gqlName:
+		p.next()
+		switch tok.val {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		default:
+			// Enum values are passed as strings.
+			return tok.val
+		}
+	}
+
+	switch {
+	case p.is(gqlPunct, "$") && !constant:
+		p.next()
+		return gqlVariable(p.name())
+	case p.is(gqlPunct, "["):
+		defer p.nest()()
+		p.next()
+		list := []interface{}{}
+		for !p.is(gqlPunct, "]") {
+			list = append(list, p.value(constant))
+		}
+		p.next()
+		return list
+	case p.is(gqlPunct, "{"):
+		defer p.nest()()
+		p.next()
+		obj := make(map[string]interface{})
+		for !p.is(gqlPunct, "}") {
+			name := p.name()
+			p.expect(":")
+			obj[name] = p.value(constant)
+		}
+		p.next()
+		return obj
+	}
+
+	p.unexpected()
+	return nil
+}
+
+// next reads the next token, skipping whitespace, commas and comments,
+// which are not significant.
+func (p *gqlParser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+
+		if strings.HasPrefix(p.src[p.pos:], "\uFEFF") {
+			p.pos += len("\uFEFF")
+			continue
+		}
+
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			break
+		}
+		p.pos++
+	}
+
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = gqlToken{kind: gqlEOF, pos: start}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = gqlToken{kind: gqlPunct, val: "...", pos: start}
+	case strings.IndexByte("!$&()/:=@[]{}|", c) >= 0:
+		p.pos++
+		p.tok = gqlToken{kind: gqlPunct, val: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = gqlToken{kind: gqlName, val: p.src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		p.number()
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		// Block strings end at the first triple quote not escaped.
+		end := p.pos + 3
+		for {
+			i := strings.Index(p.src[end:], `"""`)
+			if i < 0 {
+				p.fail(start, "unterminated string")
+			}
+
+			end += i
+			if p.src[end-1] != '\\' {
+				break
+			}
+			end += 3
+		}
+		val := strings.ReplaceAll(p.src[p.pos+3:end], `\"""`, `"""`)
+		p.pos = end + 3
+		p.tok = gqlToken{kind: gqlString, val: val, pos: start}
+	case c == '"':
+		p.string()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.fail(start, "unexpected character %q", r)
+	}
+}
+
+func (p *gqlParser) number() {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+
+	digits := func() {
+		from := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == from {
+			p.fail(p.pos, "invalid number")
+		}
+	}
+
+	kind := gqlInt
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = gqlFloat
+		p.pos++
+		digits()
+	}
+
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = gqlFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+
+	p.tok = gqlToken{kind: kind, val: p.src[start:p.pos], pos: start}
+}
+
+func (p *gqlParser) string() {
+	start := p.pos
+	p.pos++
+
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			p.fail(start, "unterminated string")
+		}
+
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			break
+		}
+
+		if c != '\\' {
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		if p.pos+1 >= len(p.src) {
+			p.fail(start, "unterminated string")
+		}
+
+		esc := p.src[p.pos+1]
+		p.pos += 2
+		switch esc {
+		case '"', '\\', '/':
+			sb.WriteByte(esc)
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			if p.pos+4 > len(p.src) {
+				p.fail(p.pos, "invalid unicode escape")
+			}
+
+			r, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+			if err != nil {
+				p.fail(p.pos, "invalid unicode escape")
+			}
+			sb.WriteRune(rune(r))
+			p.pos += 4
+		default:
+			p.fail(p.pos-2, "invalid escape \\%c", esc)
+		}
+	}
+
+	p.tok = gqlToken{kind: gqlString, val: sb.String(), pos: start}
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
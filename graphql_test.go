@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseGraphQL(t *testing.T) {
+	doc, err := parseGraphQL(`
+		# A comment, ignored.
+		query Find($path: String! = "fmt", $kinds: [String!]) @cached {
+			pkg: package(importPath: $path) {
+				name,
+				...Names @include(if: true)
+				... on Package { doc }
+				... @skip(if: false) { imports }
+			}
+			search(text: "a \"b\"\né", limit: -1.5e2, opts: {list: [1, 2.5, true, null, ENUM]}) { name }
+		}
+
+		fragment Names on Package { importPath }
+
+		{ packages { name } }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(doc.operations) != 2 {
+		t.Fatalf("expecting 2 operations, got %d", len(doc.operations))
+	}
+
+	op := doc.operations[0]
+	if op.name != "Find" {
+		t.Errorf("expecting operation Find, got %q", op.name)
+	}
+
+	if len(op.vars) != 2 || op.vars[0].name != "path" || !op.vars[0].hasDefault || op.vars[0].def != "fmt" || op.vars[1].hasDefault {
+		t.Errorf("unexpected variables: %+v %+v", op.vars[0], op.vars[1])
+	}
+
+	pkg := op.sels[0]
+	if pkg.alias != "pkg" || pkg.name != "package" || pkg.args["importPath"] != gqlVariable("path") {
+		t.Errorf("unexpected selection: %+v", pkg)
+	}
+
+	if len(pkg.sels) != 4 {
+		t.Fatalf("expecting 4 subselections, got %d", len(pkg.sels))
+	}
+
+	if s := pkg.sels[1]; s.spread != "Names" || len(s.directives) != 1 || s.directives[0].name != "include" || s.directives[0].args["if"] != true {
+		t.Errorf("unexpected fragment spread: %+v", s)
+	}
+
+	if s := pkg.sels[2]; !s.inline || s.typeCond != "Package" || s.sels[0].name != "doc" {
+		t.Errorf("unexpected inline fragment: %+v", s)
+	}
+
+	if s := pkg.sels[3]; !s.inline || s.typeCond != "" || s.directives[0].name != "skip" {
+		t.Errorf("unexpected inline fragment: %+v", s)
+	}
+
+	search := op.sels[1]
+	if search.args["text"] != "a \"b\"\né" {
+		t.Errorf("unexpected string: %q", search.args["text"])
+	}
+
+	if search.args["limit"] != -150.0 {
+		t.Errorf("unexpected number: %v", search.args["limit"])
+	}
+
+	opts, _ := json.Marshal(search.args["opts"])
+	if string(opts) != `{"list":[1,2.5,true,null,"ENUM"]}` {
+		t.Errorf("unexpected object: %s", opts)
+	}
+
+	if frag := doc.fragments["Names"]; frag == nil || frag.typeCond != "Package" || frag.sels[0].name != "importPath" {
+		t.Errorf("unexpected fragment: %+v", frag)
+	}
+
+	block, err := parseGraphQL(`{ search(text: """a "quoted" \""" block""") { name } }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if text := block.operations[0].sels[0].args["text"]; text != `a "quoted" """ block` {
+		t.Errorf("unexpected block string: %q", text)
+	}
+}
+
+func TestParseGraphQLErrors(t *testing.T) {
+	testCases := []struct {
+		query string
+		err   string
+	}{
+		{``, "the document does not contain any operation"},
+		{`# only a comment`, "the document does not contain any operation"},
+		{`fragment F on Package { name }`, "the document does not contain any operation"},
+		{`{`, "syntax error at 1:2: unexpected end of document"},
+		{`{ name`, "syntax error at 1:7: unexpected end of document"},
+		{`{ name }}`, `syntax error at 1:9: unexpected "}"`},
+		{"{\n  name(\n}", `syntax error at 3:1: unexpected "}"`},
+		{`{ name(a: ) }`, `syntax error at 1:11: unexpected ")"`},
+		{`{ name(a 1) }`, `syntax error at 1:10: unexpected "1"`},
+		{`{ a: }`, `syntax error at 1:6: unexpected "}"`},
+		{`{ 1 }`, `syntax error at 1:3: unexpected "1"`},
+		{`{ name % }`, `syntax error at 1:8: unexpected character '%'`},
+		{`{ name(a: "open) }`, "syntax error at 1:11: unterminated string"},
+		{"{ name(a: \"line\nbreak\") }", "syntax error at 1:11: unterminated string"},
+		{`{ name(a: """open) }`, "syntax error at 1:11: unterminated string"},
+		{`{ name(a: "\q") }`, `syntax error at 1:12: invalid escape \q`},
+		{`{ name(a: "\u12") }`, "syntax error at 1:14: invalid unicode escape"},
+		{`{ name(a: "\uzzzz") }`, "syntax error at 1:14: invalid unicode escape"},
+		{`{ name(a: 1.) }`, "syntax error at 1:13: invalid number"},
+		{`{ name(a: -) }`, "syntax error at 1:12: invalid number"},
+		{`{ name(a: 1e) }`, "syntax error at 1:13: invalid number"},
+		{`{ name(a: 99999999999999999999) }`, "syntax error at 1:11: invalid integer 99999999999999999999"},
+		{`{ name(a: [1, 2) }`, `syntax error at 1:16: unexpected ")"`},
+		{`{ name(a: {b 1}) }`, `syntax error at 1:14: unexpected "1"`},
+		{`query ($a: Int = $b) { name }`, `syntax error at 1:18: unexpected "$"`},
+		{`query ($a Int) { name }`, `syntax error at 1:11: unexpected "Int"`},
+		{`query (a: Int) { name }`, `syntax error at 1:8: unexpected "a"`},
+		{`query Q`, "syntax error at 1:8: unexpected end of document"},
+		{`fragment F { name }`, `syntax error at 1:12: unexpected "{"`},
+		{`mutation { name }`, "mutation operations are not supported"},
+		{`subscription { name }`, "subscription operations are not supported"},
+		{`{ name } bogus`, `syntax error at 1:10: unexpected "bogus"`},
+		{`{ ...on }`, `syntax error at 1:9: unexpected "}"`},
+		{`{ name @ }`, `syntax error at 1:10: unexpected "}"`},
+		{"{ é }", `syntax error at 1:3: unexpected character 'é'`},
+		{strings.Repeat("{ a ", maxGQLDepth+1) + strings.Repeat("}", maxGQLDepth+1), "nested more than 64 levels"},
+		{`{ a(b: ` + strings.Repeat("[", maxGQLDepth+1) + `) }`, "nested more than 64 levels"},
+		{`{ a(b: ` + strings.Repeat("{c: ", maxGQLDepth+1) + `) }`, "nested more than 64 levels"},
+	}
+
+	for _, tc := range testCases {
+		_, err := parseGraphQL(tc.query)
+		if err == nil {
+			t.Errorf("%q: expecting error %q, got none", tc.query, tc.err)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: expecting error %q, got %q", tc.query, tc.err, err)
+		}
+	}
+}
+
+func testCorpus() *corpus {
+	return newCorpus([]*Pkg{
+		{
+			Name:       "foo",
+			ImportPath: "example.com/foo",
+			Doc:        "Package foo does things.",
+			Imports:    []string{"fmt"},
+			Funcs:      []*Func{{Name: "New", Doc: "New returns a foo."}},
+			Types: []*Type{{
+				Name:    "Client",
+				Doc:     "Client is a client.",
+				Methods: []*Func{{Name: "Do", Doc: "Do does it."}},
+			}},
+		},
+		{
+			Name:       "bar",
+			ImportPath: "example.com/bar",
+			Imports:    []string{"example.com/foo"},
+		},
+	})
+}
+
+func executeTestQuery(t *testing.T, query string, vars map[string]interface{}, operationName string) string {
+	t.Helper()
+	c := testCorpus()
+	resp := executeGraphQL(context.Background(), c.schema, &queryRoot{corpus: c}, &gqlRequest{
+		Query:         query,
+		Variables:     vars,
+		OperationName: operationName,
+	})
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("unable to encode response: %s", err)
+	}
+	return string(data)
+}
+
+func TestExecuteGraphQL(t *testing.T) {
+	testCases := []struct {
+		name      string
+		query     string
+		vars      map[string]interface{}
+		operation string
+		expected  string
+	}{
+		{
+			"fields in the order requested",
+			`{ packages { name importPath } }`,
+			nil, "",
+			`{"data":{"packages":[{"name":"foo","importPath":"example.com/foo"},{"name":"bar","importPath":"example.com/bar"}]}}`,
+		},
+		{
+			"aliases and arguments",
+			`{ a: package(importPath: "example.com/foo") { n: name } b: package(importPath: "nope") { name } }`,
+			nil, "",
+			`{"data":{"a":{"n":"foo"},"b":null}}`,
+		},
+		{
+			"variables and their defaults",
+			`query ($path: String = "example.com/bar") { package(importPath: $path) { name } }`,
+			nil, "",
+			`{"data":{"package":{"name":"bar"}}}`,
+		},
+		{
+			"given variables",
+			`query ($path: String = "example.com/bar") { package(importPath: $path) { name } }`,
+			map[string]interface{}{"path": "example.com/foo"}, "",
+			`{"data":{"package":{"name":"foo"}}}`,
+		},
+		{
+			"typename",
+			`{ __typename package(importPath: "example.com/foo") { __typename } }`,
+			nil, "",
+			`{"data":{"__typename":"Query","package":{"__typename":"Package"}}}`,
+		},
+		{
+			"fragments",
+			`{ package(importPath: "example.com/foo") { ...F ... on Symbol { name } ... on Package { doc } } } fragment F on Package { name }`,
+			nil, "",
+			`{"data":{"package":{"name":"foo","doc":"Package foo does things."}}}`,
+		},
+		{
+			"cyclic fragments",
+			`{ package(importPath: "example.com/foo") { ...A } } fragment A on Package { name ...B } fragment B on Package { ...A }`,
+			nil, "",
+			`{"data":{"package":{"name":"foo"}}}`,
+		},
+		{
+			"directives",
+			`query ($yes: Boolean) { package(importPath: "example.com/foo") { name @include(if: $yes) doc @skip(if: $yes) imports @include(if: false) } }`,
+			map[string]interface{}{"yes": true}, "",
+			`{"data":{"package":{"name":"foo"}}}`,
+		},
+		{
+			"repeated fields",
+			`{ package(importPath: "example.com/foo") { name name } }`,
+			nil, "",
+			`{"data":{"package":{"name":"foo"}}}`,
+		},
+		{
+			"computed fields",
+			`{ package(importPath: "example.com/foo") { symbols(kind: "method") { name } importedBy { name } } }`,
+			nil, "",
+			`{"data":{"package":{"symbols":[{"name":"Client.Do"}],"importedBy":[{"name":"bar"}]}}}`,
+		},
+		{
+			"selected operation",
+			`query A { packages { name } } query B { package(importPath: "example.com/bar") { name } }`,
+			nil, "B",
+			`{"data":{"package":{"name":"bar"}}}`,
+		},
+		{
+			"unknown field",
+			`{ package(importPath: "example.com/foo") { name nope } }`,
+			nil, "",
+			`{"data":{"package":{"name":"foo","nope":null}},"errors":[{"message":"unknown field \"nope\" on type Package","path":["package","nope"]}]}`,
+		},
+		{
+			"all the symbols",
+			`{ package(importPath: "example.com/foo") { symbols { name } } }`,
+			nil, "",
+			`{"data":{"package":{"symbols":[{"name":"New"},{"name":"Client"},{"name":"Client.Do"}]}}}`,
+		},
+		{
+			"missing subfields",
+			`{ packages }`,
+			nil, "",
+			`{"data":{"packages":null},"errors":[{"message":"field \"packages\" of type [Package] must have a selection of subfields","path":["packages"]}]}`,
+		},
+		{
+			"subfields of scalars",
+			`{ package(importPath: "example.com/foo") { name { length } } }`,
+			nil, "",
+			`{"data":{"package":{"name":null}},"errors":[{"message":"field \"name\" must not have a selection of subfields","path":["package","name"]}]}`,
+		},
+		{
+			"arguments of plain fields",
+			`{ package(importPath: "example.com/foo") { name(x: 1) } }`,
+			nil, "",
+			`{"data":{"package":{"name":null}},"errors":[{"message":"field \"name\" of type Package takes no arguments","path":["package","name"]}]}`,
+		},
+		{
+			"errors of resolvers",
+			`{ package { name } }`,
+			nil, "",
+			`{"data":{"package":null},"errors":[{"message":"argument \"importPath\" of type String is required","path":["package"]}]}`,
+		},
+		{
+			"paths of list elements",
+			`{ packages { importedBy { nope } } }`,
+			nil, "",
+			`{"data":{"packages":[{"importedBy":[{"nope":null}]},{"importedBy":[]}]},"errors":[{"message":"unknown field \"nope\" on type Package","path":["packages",0,"importedBy",0,"nope"]}]}`,
+		},
+		{
+			"unknown fragment",
+			`{ packages { ...Nope } }`,
+			nil, "",
+			`{"data":{"packages":[{},{}]},"errors":[{"message":"unknown fragment \"Nope\""},{"message":"unknown fragment \"Nope\""}]}`,
+		},
+		{
+			"many operations without a name",
+			`query A { packages { name } } query B { packages { name } }`,
+			nil, "",
+			`{"errors":[{"message":"an operation name is required for documents with 2 operations"}]}`,
+		},
+		{
+			"unknown operation",
+			`query A { packages { name } }`,
+			nil, "B",
+			`{"errors":[{"message":"unknown operation \"B\""}]}`,
+		},
+		{
+			"syntax errors",
+			`{ packages { name }`,
+			nil, "",
+			`{"errors":[{"message":"syntax error at 1:20: unexpected end of document"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := executeTestQuery(t, tc.query, tc.vars, tc.operation)
+			if got != tc.expected {
+				t.Errorf("expecting:\n%s\ngot:\n%s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestExecuteGraphQLTooManyNodes(t *testing.T) {
+	var query strings.Builder
+	query.WriteString("{")
+	for i := 0; i <= maxGQLNodes; i++ {
+		fmt.Fprintf(&query, " a%d: __typename", i)
+	}
+	query.WriteString(" }")
+
+	got := executeTestQuery(t, query.String(), nil, "")
+	want := fmt.Sprintf(`"errors":[{"message":"result has more than %d fields and list elements","path":["a%d"]}]`, maxGQLNodes, maxGQLNodes)
+	if !strings.Contains(got, want) {
+		t.Errorf("expecting an error about the size of the result, got %s", got[len(got)-200:])
+	}
+}
+
+func TestServeGraphQLRequestTooLarge(t *testing.T) {
+	c := testCorpus()
+	body := `{"query":"{ packages { name } }","variables":{"x":"` + strings.Repeat("a", maxGraphQLRequest) + `"}}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting status %d, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ packages { name } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expecting status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+}
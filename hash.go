@@ -0,0 +1,26 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// sourceHash returns the hex-encoded SHA-256 of the content of a file.
+func sourceHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// documentHash returns the hash of the document of a package. The compact
+// JSON encoding is canonical, as fields are always in the same order and
+// map keys are sorted, so the same document always has the same hash.
+func documentHash(pkg *Pkg) string {
+	doc := *pkg
+	doc.Hash = ""
+	data, err := json.Marshal(&doc)
+	if err != nil {
+		return ""
+	}
+	return sourceHash(data)
+}
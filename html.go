@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/doc"
+	"go/doc/comment"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func runHTML(args []string) {
+	fs := flag.NewFlagSet("html", flag.ExitOnError)
+	out := fs.String("o", "site", "directory the site is written to")
+	title := fs.String("title", "Go documentation", "title of the site")
+	fs.BoolVar(resolveTypes, "resolve-types", false, "type-check packages to resolve the types of values")
+	fs.StringVar(cacheDir, "cache-dir", "", "directory where the documentation of each package is cached between runs")
+	addEnvFlags(fs)
+	fs.IntVar(concurrency, "concurrency", 0, concurrencyUsage)
+	fs.IntVar(concurrency, "j", 0, concurrencyUsage)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson html [-o site] [packages]\n\n"+
+			"Writes a static site with the documentation of the given packages, ./... by\n"+
+			"default: an index of all of them, a page for each one and a search index\n"+
+			"searched from the browser, which needs no server.\n\n"+
+			"To document the html package of the standard library instead, run\n"+
+			"godocjson -- html.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyEnvFlags(); err != nil {
+		fatalf("%s", err)
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	pkgNames, err := expandPatterns(patterns)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	var pkgs []*Pkg
+	err = extractAll(context.Background(), pkgNames, func(pkg *Pkg) error {
+		pkgs = append(pkgs, pkg)
+		return nil
+	})
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if err := writeSite(*out, *title, pkgs); err != nil {
+		fatalf("unable to write site: %s", err)
+	}
+	infof("wrote %d package(s) to %s", len(pkgs), *out)
+}
+
+// sitePackage is a package of a static site.
+type sitePackage struct {
+	*Pkg
+	// Path is the path of the page of the package, relative to the root of
+	// the site.
+	Path string
+
+	name string
+}
+
+// siteSearchEntry is a symbol in the search index of a static site.
+type siteSearchEntry struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Package  string `json:"package"`
+	URL      string `json:"url"`
+	Synopsis string `json:"synopsis,omitempty"`
+}
+
+// writeSite writes the static site of the packages to the directory: an
+// index.html with the list of packages, an index.html for each of them in
+// the directory of its import path and search.js, which defines the search
+// index as a script, so it can be loaded from files with no server.
+func writeSite(dir, title string, pkgs []*Pkg) error {
+	var (
+		site   []*sitePackage
+		byPath = make(map[string]*sitePackage)
+	)
+	for _, pkg := range pkgs {
+		name := pkg.ImportPath
+		if name == "" {
+			name = pkg.Name
+		}
+
+		sp := &sitePackage{Pkg: pkg, Path: name + "/index.html", name: name}
+		site = append(site, sp)
+		byPath[pkg.ImportPath] = sp
+	}
+	sort.Slice(site, func(i, j int) bool { return site[i].name < site[j].name })
+
+	var search = []*siteSearchEntry{}
+	for _, sp := range site {
+		search = append(search, &siteSearchEntry{
+			Name:     sp.Name,
+			Kind:     "package",
+			Package:  sp.ImportPath,
+			URL:      sp.Path,
+			Synopsis: synopsis(sp.Pkg),
+		})
+
+		for _, sym := range packageSymbols(sp.Pkg) {
+			search = append(search, &siteSearchEntry{
+				Name:     sym.Name,
+				Kind:     sym.Kind,
+				Package:  sp.ImportPath,
+				URL:      sp.Path + "#" + sym.Name,
+				Synopsis: new(doc.Package).Synopsis(sym.Doc),
+			})
+		}
+	}
+
+	data, err := json.Marshal(search)
+	if err != nil {
+		return err
+	}
+
+	if err := writeSiteFile(dir, "search.js", append(append([]byte("var searchIndex = "), data...), ";\n"...)); err != nil {
+		return err
+	}
+
+	if err := writeSitePage(dir, "index.html", &sitePage{Title: title, SiteTitle: title, Packages: site}); err != nil {
+		return err
+	}
+
+	for _, sp := range site {
+		page := &sitePage{
+			Title:     sp.Name + " - " + title,
+			SiteTitle: title,
+			Root:      strings.Repeat("../", strings.Count(sp.Path, "/")),
+			Package:   sp,
+			byPath:    byPath,
+		}
+		page.parser = newDocParser(sp.Pkg, symbolNames(sp.Pkg))
+		if err := writeSitePage(dir, sp.Path, page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSitePage(dir, name string, page *sitePage) error {
+	var buf bytes.Buffer
+	if err := siteTemplate.Execute(&buf, page); err != nil {
+		return err
+	}
+	return writeSiteFile(dir, name, buf.Bytes())
+}
+
+func writeSiteFile(dir, name string, data []byte) error {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	debugf("writing %s", path)
+	return os.WriteFile(path, data, 0644)
+}
+
+// sitePage is a page of a static site: the index, if it has no package, or
+// the page of a package.
+type sitePage struct {
+	Title     string
+	SiteTitle string
+	// Root is the relative path from the page to the root of the site.
+	Root     string
+	Packages []*sitePackage
+	Package  *sitePackage
+
+	byPath map[string]*sitePackage
+	parser *comment.Parser
+}
+
+// Doc renders a doc comment of the package of the page as HTML. Doc links
+// point to the symbols on the page, the pages of other packages of the site
+// or pkg.go.dev.
+func (p *sitePage) Doc(text string) template.HTML {
+	pr := &comment.Printer{
+		HeadingLevel: 3,
+		DocLinkURL: func(link *comment.DocLink) string {
+			if link.ImportPath == "" {
+				return "#" + docLinkName(link)
+			}
+
+			if sp, ok := p.byPath[link.ImportPath]; ok {
+				url := p.Root + sp.Path
+				if name := docLinkName(link); name != "" {
+					url += "#" + name
+				}
+				return url
+			}
+			return link.DefaultURL("https://pkg.go.dev")
+		},
+	}
+	return template.HTML(pr.HTML(p.parser.Parse(text)))
+}
+
+// Synopsis returns the first sentence of the package comment.
+func (sp *sitePackage) Synopsis() string {
+	return synopsis(sp.Pkg)
+}
+
+// DocText returns the package comment, as packageDocText.
+func (sp *sitePackage) DocText() string {
+	return packageDocText(sp.Pkg)
+}
+
+// siteTemplate is the template of all the pages of a static site. The
+// templates of symbols take the page and the symbol, paired with symbol.
+var siteTemplate = template.Must(template.New("site").Funcs(template.FuncMap{
+	"symbol": func(page *sitePage, sym interface{}) map[string]interface{} {
+		return map[string]interface{}{"page": page, "sym": sym}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; color: #222; line-height: 1.5; }
+header { background: #00758d; padding: .5em 1em; display: flex; gap: 1em; align-items: center; position: relative; }
+header a { color: #fff; font-weight: bold; text-decoration: none; }
+#search { flex: 1; max-width: 30em; padding: .3em .5em; }
+#results { position: absolute; top: 100%; left: 1em; right: 1em; max-width: 40em; margin: 0; padding: 0; list-style: none; background: #fff; box-shadow: 0 2px 6px rgba(0,0,0,.3); z-index: 1; }
+#results li a { display: block; padding: .3em .6em; color: #222; font-weight: normal; }
+#results li small { color: #666; }
+main { max-width: 60em; margin: 0 auto; padding: 1em; }
+pre { background: #f4f4f4; padding: .6em; overflow-x: auto; }
+h3, h4 { font-family: monospace; font-size: 1.05em; }
+.pos { color: #666; font-size: .85em; }
+table { border-collapse: collapse; }
+td { padding: .2em 1em .2em 0; vertical-align: top; }
+</style>
+</head>
+<body>
+<header>
+<a href="{{.Root}}index.html">{{.SiteTitle}}</a>
+<input id="search" type="search" placeholder="Search symbols" autocomplete="off">
+<ul id="results"></ul>
+</header>
+<main>
+{{- with .Package}}
+<h1>Package {{.Name}}</h1>
+{{- if .ImportPath}}
+<pre>import "{{.ImportPath}}"</pre>
+{{- end}}
+{{- if .Sections}}
+<nav>
+<ul>
+{{- range .Sections}}
+<li><a href="#{{.ID}}">{{.Heading}}</a></li>{{end}}
+</ul>
+</nav>
+{{- end}}
+{{$.Doc .DocText}}
+<h2>Index</h2>
+<ul>
+{{- range .Consts}}{{range .Names}}
+<li><a href="#{{.}}">const {{.}}</a></li>{{end}}{{end}}
+{{- range .Vars}}{{range .Names}}
+<li><a href="#{{.}}">var {{.}}</a></li>{{end}}{{end}}
+{{- range .Funcs}}
+<li><a href="#{{.Name}}">func {{.Name}}</a></li>{{end}}
+{{- range $t := .Types}}
+<li><a href="#{{.Name}}">type {{.Name}}</a></li>
+{{- range .Consts}}{{range .Names}}
+<li>&nbsp;&nbsp;<a href="#{{.}}">const {{.}}</a></li>{{end}}{{end}}
+{{- range .Vars}}{{range .Names}}
+<li>&nbsp;&nbsp;<a href="#{{.}}">var {{.}}</a></li>{{end}}{{end}}
+{{- range .Funcs}}
+<li>&nbsp;&nbsp;<a href="#{{.Name}}">func {{.Name}}</a></li>{{end}}
+{{- range .Methods}}
+<li>&nbsp;&nbsp;<a href="#{{$t.Name}}.{{.Name}}">method {{$t.Name}}.{{.Name}}</a></li>{{end}}
+{{- end}}
+</ul>
+{{- if .Consts}}
+<h2>Constants</h2>
+{{- range .Consts}}{{template "value" symbol $ .}}{{end}}
+{{- end}}
+{{- if .Vars}}
+<h2>Variables</h2>
+{{- range .Vars}}{{template "value" symbol $ .}}{{end}}
+{{- end}}
+{{- if .Funcs}}
+<h2>Functions</h2>
+{{- range .Funcs}}
+<h3 id="{{.Name}}">func {{.Name}}</h3>
+{{template "decl" symbol $ .}}
+{{- end}}
+{{- end}}
+{{- if .Types}}
+<h2>Types</h2>
+{{- range $t := .Types}}
+<h3 id="{{.Name}}">type {{.Name}}</h3>
+{{template "decl" symbol $ .}}
+{{- range .Consts}}{{template "value" symbol $ .}}{{end}}
+{{- range .Vars}}{{template "value" symbol $ .}}{{end}}
+{{- range .Funcs}}
+<h4 id="{{.Name}}">func {{.Name}}</h4>
+{{template "decl" symbol $ .}}
+{{- end}}
+{{- range .Methods}}
+<h4 id="{{$t.Name}}.{{.Name}}">method {{$t.Name}}.{{.Name}}</h4>
+{{template "decl" symbol $ .}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- else}}
+<h1>{{.Title}}</h1>
+<table>
+{{- range .Packages}}
+<tr><td><a href="{{.Path}}">{{or .ImportPath .Name}}</a></td><td>{{.Synopsis}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</main>
+<script>var siteRoot = {{.Root}};</script>
+<script src="{{.Root}}search.js"></script>
+<script>
+(function() {
+	var input = document.getElementById("search"), results = document.getElementById("results");
+	input.addEventListener("input", function() {
+		var q = input.value.trim().toLowerCase(), found = [];
+		results.innerHTML = "";
+		if (!q) return;
+		for (var i = 0; i < searchIndex.length && found.length < 20; i++) {
+			if (searchIndex[i].name.toLowerCase().indexOf(q) >= 0) found.push(searchIndex[i]);
+		}
+		found.forEach(function(e) {
+			var li = document.createElement("li"), a = document.createElement("a"), small = document.createElement("small");
+			a.href = siteRoot + e.url;
+			a.textContent = e.kind + " " + e.name + " ";
+			small.textContent = e.package + (e.synopsis ? " - " + e.synopsis : "");
+			a.appendChild(small);
+			li.appendChild(a);
+			results.appendChild(li);
+		});
+	});
+})();
+</script>
+</body>
+</html>
+{{define "decl"}}<pre>{{.sym.Decl}}</pre>
+{{- with .sym.Pos}}{{with .Start}}
+<p class="pos">{{.File}}:{{.Line}}</p>{{end}}{{end}}
+{{.page.Doc .sym.Doc}}{{end}}
+{{define "value"}}{{range .sym.Names}}<span id="{{.}}"></span>{{end}}
+{{template "decl" .}}{{end}}
+`))
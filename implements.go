@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"go/types"
+	"sort"
+)
+
+var withImplements = flag.Bool("implements", false, "type-check packages to list which of their types implement which of their interfaces")
+
+// NewImplementations returns the implementations of the exported
+// interfaces of the package by its exported types, sorted by type and
+// interface names.
+func NewImplementations(info *types.Info) []*Implementation {
+	var ifaces, concrete []*types.TypeName
+	for _, obj := range info.Defs {
+		tn, ok := obj.(*types.TypeName)
+		if !ok || !tn.Exported() || tn.IsAlias() || tn.Parent() != tn.Pkg().Scope() {
+			continue
+		}
+
+		named, ok := tn.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+
+		if iface, ok := named.Underlying().(*types.Interface); ok {
+			if iface.NumMethods() > 0 {
+				ifaces = append(ifaces, tn)
+			}
+			continue
+		}
+		concrete = append(concrete, tn)
+	}
+
+	byName := func(list []*types.TypeName) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	}
+	byName(ifaces)
+	byName(concrete)
+
+	var result = []*Implementation{}
+	for _, t := range concrete {
+		for _, i := range ifaces {
+			if impl := implementation(t.Type(), i.Type().Underlying().(*types.Interface)); impl != nil {
+				impl.Type, impl.Interface = t.Name(), i.Name()
+				result = append(result, impl)
+			}
+		}
+	}
+	return result
+}
+
+// implementation returns how t implements iface, or nil if it has none of
+// its methods.
+func implementation(t types.Type, iface *types.Interface) *Implementation {
+	var (
+		missing  []string
+		byValue  = true
+		matching int
+	)
+
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		switch {
+		case hasMethod(t, m):
+			matching++
+		case hasMethod(types.NewPointer(t), m):
+			matching++
+			byValue = false
+		default:
+			missing = append(missing, m.Name())
+		}
+	}
+
+	if matching == 0 {
+		return nil
+	}
+
+	return &Implementation{
+		Implements: len(missing) == 0,
+		Pointer:    len(missing) == 0 && !byValue,
+		Missing:    missing,
+	}
+}
+
+// hasMethod reports whether the method set of t has a method with the name
+// and signature of m.
+func hasMethod(t types.Type, m *types.Func) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, m.Pkg(), m.Name())
+	fn, ok := obj.(*types.Func)
+	return ok && types.Identical(fn.Type(), m.Type())
+}
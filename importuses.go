@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+)
+
+// importUses returns the number of references to the package of each
+// import in the declarations of its file, including function bodies, so it
+// must be called before they are removed from the AST. Without type
+// information, references are found by name, ignoring any shadowing, and
+// those to dot imports cannot be counted.
+func importUses(src *Source) map[*ast.ImportSpec]int {
+	var uses = make(map[*ast.ImportSpec]int)
+	for _, f := range src.Files {
+		var (
+			byName = make(map[string]*ast.ImportSpec)
+			byPath = make(map[string]*ast.ImportSpec)
+			dots   = make(map[string]*ast.ImportSpec)
+		)
+
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := importName(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+
+			switch name {
+			case "_":
+			case ".":
+				dots[path] = spec
+			default:
+				byName[name] = spec
+				byPath[path] = spec
+			}
+		}
+
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && len(gd.Specs) > 0 {
+				if _, ok := gd.Specs[0].(*ast.ImportSpec); ok {
+					continue
+				}
+			}
+
+			ast.Inspect(decl, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.SelectorExpr:
+					id, ok := n.X.(*ast.Ident)
+					if !ok {
+						return true
+					}
+
+					if src.Info == nil {
+						if spec, ok := byName[id.Name]; ok {
+							uses[spec]++
+						}
+						return true
+					}
+
+					pkg, ok := src.Info.Uses[id].(*types.PkgName)
+					if !ok {
+						return true
+					}
+
+					if spec, ok := byPath[pkg.Imported().Path()]; ok {
+						uses[spec]++
+					}
+					return false
+				case *ast.Ident:
+					if src.Info == nil || len(dots) == 0 {
+						return true
+					}
+
+					obj := src.Info.Uses[n]
+					if obj == nil || obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+						return true
+					}
+
+					if spec, ok := dots[obj.Pkg().Path()]; ok {
+						uses[spec]++
+					}
+				}
+				return true
+			})
+		}
+	}
+	return uses
+}
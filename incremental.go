@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/erizocosmico/godocjson/schema"
+)
+
+var incremental = flag.Bool("incremental", false, "only regenerate the packages of -outdir whose source changed since the last run, removing those that no longer exist, and report them")
+
+// manifestFile is the name of the manifest of the last run written to the
+// directory of -outdir with -incremental.
+const manifestFile = "manifest.json"
+
+// runManifest records the state of the sources of each package in a run,
+// their cache key, so the next incremental run can tell which packages
+// changed.
+type runManifest struct {
+	mut sync.Mutex
+	// Flags are those affecting the output in the run. If they change, all
+	// packages are regenerated.
+	Flags    string
+	Packages map[string]string
+}
+
+var currentRun = &runManifest{Packages: make(map[string]string)}
+
+// incrementalState is the state of the previous run of -incremental, which
+// is compared against the current one.
+type incrementalState struct {
+	prev *runManifest
+	// index is the index of -outdir written in the previous run, and
+	// entries its packages by import path.
+	index   *Index
+	entries map[string]*IndexEntry
+	// removed are the packages of the previous run that no longer exist.
+	removed map[string]bool
+	// skipped are the packages whose sources did not change, which are not
+	// extracted again.
+	skipped map[string]bool
+	mut     sync.Mutex
+}
+
+var incrementalRun incrementalState
+
+func (m *runManifest) record(pkgName, key string) {
+	m.mut.Lock()
+	m.Packages[pkgName] = key
+	m.mut.Unlock()
+}
+
+func manifestPath() string {
+	return filepath.Join(*outDir, manifestFile)
+}
+
+// outputFlags returns the values of the flags affecting the output: those
+// that are part of cache keys along with -git, -since, -since-api, -readme,
+// -doc-overlay and -symbol-index, whose output is not cached but is kept in
+// the files of -outdir. Changes to what the output of those flags is made
+// from are found by outputInputs instead.
+func outputFlags() string {
+	var flags []string
+	flag.VisitAll(func(f *flag.Flag) {
+		switch f.Name {
+		case "git", "since", "since-api", "readme", "doc-overlay", "symbol-index":
+		default:
+			if nonCacheableFlags[f.Name] {
+				return
+			}
+		}
+		flags = append(flags, f.Name+"="+f.Value.String())
+	})
+	return strings.Join(flags, " ")
+}
+
+// outputInputs returns the hash of what the parts of the output of a
+// package that are not cached are made from, besides its sources: its git
+// revision with -git, the release tags of its module with -since, its
+// versions in -since-api, its README with -readme and its translations in
+// -doc-overlay. Packages are regenerated when it changes, as they are when
+// their sources do.
+func outputInputs(pkgName, srcDir string) string {
+	var inputs struct {
+		Git          *GitInfo                     `json:",omitempty"`
+		Tags         []string                     `json:",omitempty"`
+		Since        map[string]string            `json:",omitempty"`
+		Readme       *Readme                      `json:",omitempty"`
+		Translations map[string]map[string]string `json:",omitempty"`
+	}
+
+	if *withGit {
+		inputs.Git = gitInfo(srcDir)
+	}
+
+	switch {
+	case *sinceAPI != "":
+		inputs.Since = apiSince(*sinceAPI)[pkgName]
+	case *withSince:
+		if root, _, prefix, ok := sinceRepository(srcDir); ok {
+			inputs.Tags = releaseTags(root, prefix)
+		}
+	}
+
+	if *withReadme {
+		inputs.Readme = findReadme(srcDir)
+	}
+
+	if *docOverlay != "" {
+		inputs.Translations = make(map[string]map[string]string)
+		for id, translations := range overlayDocs(*docOverlay) {
+			if id == pkgName || strings.HasPrefix(id, pkgName+".") {
+				inputs.Translations[id] = translations
+			}
+		}
+	}
+
+	data, err := json.Marshal(&inputs)
+	if err != nil {
+		return ""
+	}
+	return sourceHash(data)
+}
+
+// startIncremental loads the manifest and index of the previous run in the
+// directory of -outdir, which are empty if there was none, and finds the
+// packages of the previous run that no longer exist, neither given in this
+// run nor found in the sources.
+func startIncremental(pkgNames []string) error {
+	prev := &runManifest{Packages: make(map[string]string)}
+	if data, err := os.ReadFile(manifestPath()); err == nil {
+		if err := json.Unmarshal(data, prev); err != nil {
+			return fmt.Errorf("%s: %w", manifestPath(), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	index := &Index{}
+	if f, err := os.Open(filepath.Join(*outDir, indexFile)); err == nil {
+		index, err = schema.ReadIndex(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Join(*outDir, indexFile), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Everything is regenerated when the output would not be the same.
+	currentRun.Flags = outputFlags()
+	if prev.Flags != currentRun.Flags || index.GeneratorVersion != generatorVersion() {
+		prev.Packages = make(map[string]string)
+	}
+
+	given := make(map[string]bool)
+	for _, name := range pkgNames {
+		given[name] = true
+	}
+
+	var (
+		entries = make(map[string]*IndexEntry)
+		removed = make(map[string]bool)
+	)
+	for _, entry := range index.Packages {
+		if entry.ImportPath == "" {
+			continue
+		}
+
+		entries[entry.ImportPath] = entry
+		if !given[entry.ImportPath] && !packageExists(entry.ImportPath) {
+			removed[entry.ImportPath] = true
+		}
+	}
+
+	incrementalRun = incrementalState{
+		prev:    prev,
+		index:   index,
+		entries: entries,
+		removed: removed,
+		skipped: make(map[string]bool),
+	}
+	return nil
+}
+
+// packageExists reports whether the package with the given import path can
+// still be found and has any files to document.
+func packageExists(pkgName string) bool {
+	srcDir, err := packageSrcDir(pkgName)
+	if err != nil {
+		return false
+	}
+
+	files, err := sourceFiles(srcDir)
+	return err == nil && len(files) > 0
+}
+
+// skipUnchanged records the cache key of a package in the current run,
+// along with the hash of the rest of inputs of its output, and reports
+// whether it can be skipped: neither changed since the previous run, whose
+// file of -outdir is still there.
+func skipUnchanged(pkgName, srcDir, key string) bool {
+	key += " " + outputInputs(pkgName, srcDir)
+	currentRun.record(pkgName, key)
+	if incrementalRun.prev.Packages[pkgName] != key {
+		return false
+	}
+
+	entry, ok := incrementalRun.entries[pkgName]
+	if !ok {
+		return false
+	}
+
+	if _, err := os.Stat(filepath.Join(*outDir, filepath.FromSlash(entry.File))); err != nil {
+		return false
+	}
+
+	debugf("skipping %s: unchanged since the last run", pkgName)
+	incrementalRun.mut.Lock()
+	incrementalRun.skipped[pkgName] = true
+	incrementalRun.mut.Unlock()
+	return true
+}
+
+// carryOver adds to the index the packages of the previous run that were
+// not written in this one and still exist, the ones skipped or not given,
+// and removes the files of those that no longer exist.
+func (w *outDirWriter) carryOver() {
+	written := make(map[string]bool)
+	for _, e := range w.index.Packages {
+		written[e.ImportPath] = true
+	}
+
+	prev := incrementalRun.index
+	for _, e := range prev.Packages {
+		switch {
+		case e.ImportPath == "" || written[e.ImportPath]:
+		case incrementalRun.removed[e.ImportPath]:
+			w.removeFile(e.File)
+		default:
+			w.index.Packages = append(w.index.Packages, e)
+			for id, sym := range prev.Symbols {
+				if sym.File != e.File {
+					continue
+				}
+
+				if w.index.Symbols == nil {
+					w.index.Symbols = make(map[string]*IndexSymbol)
+				}
+				w.index.Symbols[id] = sym
+			}
+		}
+	}
+
+	sort.Slice(w.index.Packages, func(i, j int) bool {
+		return w.index.Packages[i].ImportPath < w.index.Packages[j].ImportPath
+	})
+}
+
+// removeFile removes a file of the directory, along with the directories it
+// leaves empty.
+func (w *outDirWriter) removeFile(name string) {
+	path := filepath.Join(w.dir, filepath.FromSlash(name))
+	debugf("removing %s", path)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		errorf("unable to remove %s: %s", path, err)
+		return
+	}
+
+	for dir := filepath.Dir(path); dir != filepath.Clean(w.dir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+}
+
+// finishIncremental reports which packages were regenerated, skipped and
+// removed, and saves the current run as the baseline for the next one.
+// Packages of the previous run that were not part of this one and still
+// exist are kept as they were.
+func finishIncremental() {
+	var regenerated, removed []string
+	for pkg := range currentRun.Packages {
+		if !incrementalRun.skipped[pkg] {
+			regenerated = append(regenerated, pkg)
+		}
+	}
+
+	for pkg := range incrementalRun.removed {
+		removed = append(removed, pkg)
+	}
+	sort.Strings(regenerated)
+	sort.Strings(removed)
+
+	if len(regenerated) == 0 {
+		infof("nothing to regenerate, %d package(s) unchanged", len(incrementalRun.skipped))
+	} else {
+		infof("regenerated %d package(s), %d unchanged: %s", len(regenerated), len(incrementalRun.skipped), strings.Join(regenerated, ", "))
+	}
+
+	if len(removed) > 0 {
+		infof("removed %d package(s) that no longer exist: %s", len(removed), strings.Join(removed, ", "))
+	}
+
+	for pkg, key := range incrementalRun.prev.Packages {
+		if _, ok := currentRun.Packages[pkg]; !ok && !incrementalRun.removed[pkg] {
+			currentRun.Packages[pkg] = key
+		}
+	}
+
+	data, err := json.MarshalIndent(currentRun, "", "\t")
+	if err == nil {
+		err = os.WriteFile(manifestPath(), append(data, '\n'), 0644)
+	}
+
+	if err != nil {
+		errorf("unable to save run manifest: %s", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"go/doc"
+	"go/types"
+)
+
+// NewInheritedMethods returns the exported methods a type gains by
+// embedding types declared in other packages, such as the Lock and Unlock
+// of an embedded sync.Mutex, which go/doc cannot see, sorted by name as the
+// method sets they come from. Those of embedded types of the package are
+// already in its Methods. It needs type information, so it returns nil if
+// types are not resolved.
+func NewInheritedMethods(typ *doc.Type, src *Source) []*InheritedMethod {
+	ts := typeSpec(typ.Decl)
+	if src.Info == nil || ts == nil {
+		return nil
+	}
+
+	obj, ok := src.Info.Defs[ts.Name].(*types.TypeName)
+	if !ok || obj.Type() == nil {
+		return nil
+	}
+
+	var (
+		t     = obj.Type()
+		value = types.NewMethodSet(t)
+		ptr   = types.NewMethodSet(types.NewPointer(t))
+	)
+	if _, isIface := t.Underlying().(*types.Interface); isIface {
+		ptr = value
+	}
+
+	var result []*InheritedMethod
+	for i := 0; i < ptr.Len(); i++ {
+		sel := ptr.At(i)
+		fn, ok := sel.Obj().(*types.Func)
+		if !ok || !fn.Exported() || fn.Pkg() == nil || fn.Pkg() == obj.Pkg() {
+			continue
+		}
+
+		sig := fn.Type().(*types.Signature)
+		m := &InheritedMethod{
+			Name:      fn.Name(),
+			Package:   fn.Pkg().Path(),
+			Signature: types.TypeString(types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic()), qualifyFully),
+			Level:     len(sel.Index()) - 1,
+			Pointer:   value.Lookup(fn.Pkg(), fn.Name()) == nil,
+		}
+
+		if recv := sig.Recv(); recv != nil {
+			m.Recv = types.TypeString(recv.Type(), qualifyFully)
+		}
+
+		if st, isStruct := t.Underlying().(*types.Struct); isStruct && len(sel.Index()) > 1 {
+			m.Embedded = types.TypeString(st.Field(sel.Index()[0]).Type(), qualifyFully)
+		} else {
+			m.Embedded = m.Recv
+		}
+		result = append(result, m)
+	}
+	return result
+}
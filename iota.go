@@ -0,0 +1,179 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+)
+
+// iotaConst is a constant of a group using iota.
+type iotaConst struct {
+	// Ordinal is the value of iota in its spec, the index of the spec in
+	// the group.
+	Ordinal int
+	// Group are all the constants of the group, including unexported
+	// ones, but not those named _.
+	Group []*ast.Ident
+}
+
+// iotaConsts returns the constants of the groups using iota.
+func iotaConsts(files []*ast.File) map[*ast.Ident]*iotaConst {
+	var consts = make(map[*ast.Ident]*iotaConst)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST || !usesIota(gd) {
+				continue
+			}
+
+			var group []*ast.Ident
+			for index, spec := range gd.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					if name.Name != "_" {
+						group = append(group, name)
+						consts[name] = &iotaConst{Ordinal: index}
+					}
+				}
+			}
+
+			for _, name := range group {
+				consts[name].Group = group
+			}
+		}
+	}
+	return consts
+}
+
+// usesIota reports whether any of the values of the const declaration,
+// including those repeated by specs without values, refer to iota.
+func usesIota(decl *ast.GenDecl) bool {
+	for _, spec := range decl.Specs {
+		for _, v := range spec.(*ast.ValueSpec).Values {
+			if refersTo(v, "iota") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewIotaGroup returns the members of the const declaration, if it uses
+// iota, with their ordinal and value, or nil otherwise. Whether they form a
+// sequence or a bitmask depends on all the constants of the group, so
+// unexported ones between them are not missed. Only groups shifting by iota
+// are bitmasks, as the values of a plain enum such as 0, 1, 2 are powers of
+// two too, and those are never sequences.
+func NewIotaGroup(decl *ast.GenDecl, src *Source) *IotaGroup {
+	if decl.Tok != token.CONST {
+		return nil
+	}
+
+	var (
+		group = &IotaGroup{Members: []*IotaMember{}}
+		all   []*ast.Ident
+	)
+	for _, spec := range decl.Specs {
+		for _, name := range spec.(*ast.ValueSpec).Names {
+			c, ok := src.Iota[name]
+			if !ok {
+				continue
+			}
+
+			m := &IotaMember{Name: name.Name, Ordinal: c.Ordinal}
+			if v := src.constValue(name); v != nil {
+				m.Value = v.ExactString()
+			}
+			group.Members = append(group.Members, m)
+			all = c.Group
+		}
+	}
+
+	if len(group.Members) == 0 {
+		return nil
+	}
+
+	var values = make([]constant.Value, len(all))
+	for i, name := range all {
+		values[i] = src.constValue(name)
+	}
+
+	group.Bitmask = shiftsIota(decl) && isBitmask(values)
+	group.Sequence = !group.Bitmask && isSequence(values)
+	return group
+}
+
+// shiftsIota reports whether any of the values of the const declaration
+// shift by an amount depending on iota, such as 1 << iota.
+func shiftsIota(decl *ast.GenDecl) bool {
+	var found bool
+	for _, spec := range decl.Specs {
+		for _, v := range spec.(*ast.ValueSpec).Values {
+			ast.Inspect(v, func(n ast.Node) bool {
+				if e, ok := n.(*ast.BinaryExpr); ok && e.Op == token.SHL {
+					found = found || refersTo(e.Y, "iota")
+				}
+				return !found
+			})
+		}
+	}
+	return found
+}
+
+// refersTo reports whether the expression refers to the given identifier.
+func refersTo(expr ast.Expr, name string) bool {
+	var found bool
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// isSequence reports whether the values are at least two consecutive
+// integers.
+func isSequence(values []constant.Value) bool {
+	if len(values) < 2 {
+		return false
+	}
+
+	one := constant.MakeInt64(1)
+	for i, v := range values {
+		if v == nil || v.Kind() != constant.Int {
+			return false
+		}
+
+		if i > 0 && !constant.Compare(constant.BinaryOp(values[i-1], token.ADD, one), token.EQL, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBitmask reports whether the values are at least two distinct powers
+// of two, along with an optional zero for none of the flags.
+func isBitmask(values []constant.Value) bool {
+	var (
+		zero  = constant.MakeInt64(0)
+		one   = constant.MakeInt64(1)
+		seen  = make(map[string]bool)
+		flags int
+	)
+	for _, v := range values {
+		if v == nil || v.Kind() != constant.Int || constant.Sign(v) < 0 || seen[v.ExactString()] {
+			return false
+		}
+		seen[v.ExactString()] = true
+
+		if constant.Sign(v) == 0 {
+			continue
+		}
+
+		if !constant.Compare(constant.BinaryOp(v, token.AND, constant.BinaryOp(v, token.SUB, one)), token.EQL, zero) {
+			return false
+		}
+		flags++
+	}
+	return flags >= 2
+}
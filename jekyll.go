@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"strings"
+)
+
+var permalinkBase = flag.String("permalink-base", "/", "path the permalinks of the pages written with -format jekyll start with, followed by the import path")
+
+// jekyllWriter writes the documentation of every package as a Markdown page
+// with the YAML front matter of Jekyll, so it can be published as part of a
+// collection. Symbols have explicit heading IDs, as in kramdown, so doc
+// links to them work.
+type jekyllWriter struct {
+	w *bufio.Writer
+}
+
+func newJekyllWriter(w io.Writer, list bool) packageWriter {
+	return &jekyllWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *jekyllWriter) Write(pkg *Pkg) error {
+	path := pkg.ImportPath
+	if path == "" {
+		path = pkg.Name
+	}
+
+	m := newMarkdownPage(pkg)
+	m.frontMatter(
+		"title", path,
+		"permalink", strings.TrimSuffix(*permalinkBase, "/")+"/"+path+"/",
+		"package", pkg.Name,
+		"import_path", pkg.ImportPath,
+		"synopsis", synopsis(pkg),
+	)
+
+	m.printf("# Package %s\n\n", pkg.Name)
+	if pkg.ImportPath != "" {
+		m.printf("```go\nimport %q\n```\n\n", pkg.ImportPath)
+	}
+	m.doc(packageDocText(pkg), 2)
+
+	m.values("Constants", pkg.Consts, 2)
+	m.values("Variables", pkg.Vars, 2)
+	if len(pkg.Funcs) > 0 {
+		m.printf("## Functions\n\n")
+		m.funcs(pkg.Funcs, "", 3)
+	}
+
+	if len(pkg.Types) > 0 {
+		m.printf("## Types\n\n")
+	}
+	for _, t := range pkg.Types {
+		m.printf("### type %s {#%s}\n\n", t.Name, t.Name)
+		m.decl(t.Decl)
+		m.doc(t.Doc, 4)
+		m.values("", t.Consts, 4)
+		m.values("", t.Vars, 4)
+		m.funcs(t.Funcs, "", 4)
+		m.funcs(t.Methods, t.Name+".", 4)
+	}
+
+	_, err := m.buf.WriteTo(w.w)
+	return err
+}
+
+func (w *jekyllWriter) Close() error {
+	return w.w.Flush()
+}
+
+// markdownPage is the Markdown page of a package being written.
+type markdownPage struct {
+	buf    bytes.Buffer
+	parser *comment.Parser
+}
+
+func newMarkdownPage(pkg *Pkg) *markdownPage {
+	return &markdownPage{parser: newDocParser(pkg, symbolNames(pkg))}
+}
+
+func (m *markdownPage) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&m.buf, format, args...)
+}
+
+// frontMatter writes the given pairs of keys and values as YAML front
+// matter. Values are quoted as JSON strings, which are valid YAML.
+func (m *markdownPage) frontMatter(pairs ...string) {
+	m.printf("---\n")
+	for i := 0; i < len(pairs); i += 2 {
+		value, _ := json.Marshal(pairs[i+1])
+		m.printf("%s: %s\n", pairs[i], value)
+	}
+	m.printf("---\n\n")
+}
+
+func (m *markdownPage) decl(decl string) {
+	m.printf("```go\n%s\n```\n\n", decl)
+}
+
+// doc writes a doc comment as Markdown, with its headings at the given
+// level. Doc links to symbols of the package point to their headings.
+func (m *markdownPage) doc(text string, headingLevel int) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	pr := &comment.Printer{
+		HeadingLevel: headingLevel,
+		DocLinkURL: func(link *comment.DocLink) string {
+			if link.ImportPath == "" {
+				return "#" + docLinkName(link)
+			}
+			return link.DefaultURL("https://pkg.go.dev")
+		},
+	}
+	m.buf.Write(pr.Markdown(m.parser.Parse(text)))
+	m.printf("\n")
+}
+
+func (m *markdownPage) values(title string, list []*Value, level int) {
+	if len(list) > 0 && title != "" {
+		m.printf("%s %s\n\n", strings.Repeat("#", level), title)
+	}
+
+	for _, v := range list {
+		m.decl(v.Decl)
+		m.doc(v.Doc, level+1)
+	}
+}
+
+// funcs writes the functions, or methods if prefix is the name of their
+// type followed by a dot, with headings at the given level.
+func (m *markdownPage) funcs(list []*Func, prefix string, level int) {
+	kind := "func"
+	if prefix != "" {
+		kind = "method"
+	}
+
+	for _, f := range list {
+		m.printf("%s %s %s {#%s}\n\n", strings.Repeat("#", level), kind, prefix+f.Name, prefix+f.Name)
+		m.decl(f.Decl)
+		m.doc(f.Doc, level+1)
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"go/ast"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonSchemaWriter writes, for every package, a JSON Schema with a
+// definition of the wire shape of each exported struct type with json
+// tags, as encoded by encoding/json, and of the types of the package they
+// refer to.
+type jsonSchemaWriter struct {
+	*jsonWriter
+}
+
+func newJSONSchemaWriter(w io.Writer, list bool) packageWriter {
+	return &jsonSchemaWriter{newJSONWriter(w, list)}
+}
+
+func (w *jsonSchemaWriter) Write(pkg *Pkg) error {
+	return w.WriteValue(packageJSONSchema(pkg))
+}
+
+type jsonSchema map[string]interface{}
+
+// packageJSONSchema returns the JSON Schema of the structs of the package,
+// with a definition under $defs for each of them.
+func packageJSONSchema(pkg *Pkg) jsonSchema {
+	g := newSchemaGenerator(pkg, func(name string) string {
+		return "#/$defs/" + name
+	})
+
+	for _, t := range pkg.Types {
+		ts, ok := g.specs[t.Name]
+		if !ok {
+			continue
+		}
+
+		if st, ok := ts.Type.(*ast.StructType); ok && hasJSONTags(st) {
+			g.define(t.Name)
+		}
+	}
+
+	schema := jsonSchema{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   pkg.Name,
+		"$defs":   g.defs,
+	}
+	if pkg.ImportPath != "" {
+		schema["$id"] = pkg.ImportPath
+	}
+	return schema
+}
+
+func hasJSONTags(st *ast.StructType) bool {
+	for _, f := range st.Fields.List {
+		if _, ok := jsonTag(f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTag(f *ast.Field) (string, bool) {
+	if f.Tag == nil {
+		return "", false
+	}
+
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return "", false
+	}
+	return reflect.StructTag(tag).Lookup("json")
+}
+
+type schemaGenerator struct {
+	specs map[string]*ast.TypeSpec
+	docs  map[string]string
+	defs  map[string]jsonSchema
+	// ref returns the target of the references to the definition of the
+	// named type.
+	ref func(name string) string
+}
+
+// newSchemaGenerator returns a generator of the schemas of the types of the
+// package, whose definitions are referenced with the targets returned by
+// ref.
+func newSchemaGenerator(pkg *Pkg, ref func(name string) string) *schemaGenerator {
+	g := &schemaGenerator{
+		specs: make(map[string]*ast.TypeSpec),
+		docs:  make(map[string]string),
+		defs:  make(map[string]jsonSchema),
+		ref:   ref,
+	}
+
+	for _, t := range pkg.Types {
+		decl, ok := parseDecl(t.Decl)
+		if !ok {
+			continue
+		}
+
+		if ts := typeSpec(decl); ts != nil {
+			g.specs[t.Name] = ts
+			g.docs[t.Name] = strings.TrimSpace(t.Doc)
+		}
+	}
+	return g
+}
+
+// define adds the definition of the named type of the package.
+func (g *schemaGenerator) define(name string) {
+	if _, ok := g.defs[name]; ok {
+		return
+	}
+
+	// Reserve the name first, types can refer to themselves.
+	g.defs[name] = jsonSchema{}
+	schema := g.schema(g.specs[name].Type)
+	if schema == nil {
+		schema = jsonSchema{}
+	}
+
+	if doc := g.docs[name]; doc != "" {
+		schema["description"] = doc
+	}
+	g.defs[name] = schema
+}
+
+// schema returns the schema of values of the given type, or nil if they
+// cannot be encoded as JSON.
+func (g *schemaGenerator) schema(expr ast.Expr) jsonSchema {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if _, ok := g.specs[e.Name]; ok {
+			g.define(e.Name)
+			return jsonSchema{"$ref": g.ref(e.Name)}
+		}
+		return basicSchema(e.Name)
+	case *ast.ParenExpr:
+		return g.schema(e.X)
+	case *ast.StarExpr:
+		return g.schema(e.X)
+	case *ast.SelectorExpr:
+		return qualifiedSchema(e)
+	case *ast.ArrayType:
+		if id, ok := e.Elt.(*ast.Ident); ok && id.Name == "byte" && e.Len == nil {
+			return jsonSchema{"type": "string", "contentEncoding": "base64"}
+		}
+
+		items := g.schema(e.Elt)
+		if items == nil {
+			return nil
+		}
+
+		schema := jsonSchema{"type": "array", "items": items}
+		if lit, ok := e.Len.(*ast.BasicLit); ok {
+			if n, err := strconv.Atoi(lit.Value); err == nil {
+				schema["minItems"], schema["maxItems"] = n, n
+			}
+		}
+		return schema
+	case *ast.MapType:
+		values := g.schema(e.Value)
+		if values == nil {
+			return nil
+		}
+		return jsonSchema{"type": "object", "additionalProperties": values}
+	case *ast.StructType:
+		return g.structSchema(e)
+	case *ast.InterfaceType:
+		return jsonSchema{}
+	case *ast.FuncType, *ast.ChanType:
+		return nil
+	default:
+		// Instantiations of generic types cannot be resolved without
+		// type-checking.
+		return jsonSchema{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(st *ast.StructType) jsonSchema {
+	var (
+		props    = make(map[string]interface{})
+		required []string
+	)
+
+	g.addFields(st, props, &required, make(map[*ast.StructType]bool))
+
+	schema := jsonSchema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the properties encoded for the fields of the struct.
+// Fields of embedded structs without a name in their tag are promoted,
+// unless the struct has a field with the same name.
+func (g *schemaGenerator) addFields(st *ast.StructType, props map[string]interface{}, required *[]string, visited map[*ast.StructType]bool) {
+	if visited[st] {
+		return
+	}
+	visited[st] = true
+
+	var embedded []*ast.StructType
+	for _, f := range st.Fields.List {
+		tag, _ := jsonTag(f)
+		name, opts, _ := strings.Cut(tag, ",")
+		if tag == "-" {
+			continue
+		}
+
+		names := f.Names
+		if len(names) == 0 {
+			id := embeddedIdent(f.Type)
+			if id == nil {
+				continue
+			}
+
+			// Only structs declared in the package have their fields
+			// promoted, as an embedded other.Config is not the Config of
+			// specs.
+			if local := declaredIdent(f.Type); name == "" && local != nil {
+				if spec, ok := g.specs[local.Name]; ok {
+					if inner, ok := spec.Type.(*ast.StructType); ok {
+						embedded = append(embedded, inner)
+						continue
+					}
+				}
+			}
+			names = []*ast.Ident{id}
+		}
+
+		for _, id := range names {
+			if !id.IsExported() {
+				continue
+			}
+
+			schema := g.schema(f.Type)
+			if schema == nil {
+				continue
+			}
+
+			if hasTagOption(opts, "string") {
+				switch schema["type"] {
+				case "integer", "number", "boolean":
+					schema = jsonSchema{"type": "string"}
+				}
+			}
+
+			prop := id.Name
+			if name != "" {
+				prop = name
+			}
+			props[prop] = schema
+			if !hasTagOption(opts, "omitempty") && !hasTagOption(opts, "omitzero") {
+				*required = append(*required, prop)
+			}
+		}
+	}
+
+	for _, inner := range embedded {
+		promoted := make(map[string]interface{})
+		var promotedRequired []string
+		g.addFields(inner, promoted, &promotedRequired, visited)
+		added := make(map[string]bool)
+		for prop, schema := range promoted {
+			if _, ok := props[prop]; !ok {
+				props[prop] = schema
+				added[prop] = true
+			}
+		}
+
+		for _, prop := range promotedRequired {
+			if added[prop] {
+				*required = append(*required, prop)
+			}
+		}
+	}
+}
+
+func hasTagOption(opts, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedIdent returns the identifier of the type of an embedded field.
+func embeddedIdent(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.StarExpr:
+		return embeddedIdent(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedIdent(e.X)
+	case *ast.IndexListExpr:
+		return embeddedIdent(e.X)
+	}
+	return nil
+}
+
+// declaredIdent returns the identifier of the type of an embedded field if
+// it is declared in the package, either T or *T.
+func declaredIdent(expr ast.Expr) *ast.Ident {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+
+	id, _ := expr.(*ast.Ident)
+	return id
+}
+
+func basicSchema(name string) jsonSchema {
+	switch name {
+	case "bool":
+		return jsonSchema{"type": "boolean"}
+	case "string":
+		return jsonSchema{"type": "string"}
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+		return jsonSchema{"type": "integer"}
+	case "float32", "float64":
+		return jsonSchema{"type": "number"}
+	case "complex64", "complex128":
+		return nil
+	default:
+		// any, error, type parameters and unexported types, whose shape
+		// is unknown.
+		return jsonSchema{}
+	}
+}
+
+// qualifiedSchema returns the schema of well-known types of other
+// packages, or an empty schema, which accepts anything, for the rest.
+func qualifiedSchema(e *ast.SelectorExpr) jsonSchema {
+	pkg, _ := e.X.(*ast.Ident)
+	if pkg == nil {
+		return jsonSchema{}
+	}
+
+	switch pkg.Name + "." + e.Sel.Name {
+	case "time.Time":
+		return jsonSchema{"type": "string", "format": "date-time"}
+	case "time.Duration":
+		return jsonSchema{"type": "integer"}
+	}
+	return jsonSchema{}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var licenseFileRegexp = regexp.MustCompile(`(?i)^(LICEN[CS]E|COPYING)([-._].*)?$`)
+
+// licenseGuesses are the SPDX identifiers of the most common licenses, along
+// with phrases that must all be present in their text. More specific
+// licenses come first.
+var licenseGuesses = []struct {
+	spdx    string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 2.1"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// findLicenses returns the licenses at the root of the module the given
+// directory belongs to or, if it is not part of a module, in the directory
+// itself.
+func findLicenses(dir string) []*License {
+	if dir == "" {
+		return nil
+	}
+
+	root := moduleRoot(dir)
+	if root == "" {
+		root = dir
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+
+	var licenses []*License
+	for _, e := range entries {
+		if e.IsDir() || !licenseFileRegexp.MatchString(e.Name()) {
+			continue
+		}
+
+		path := filepath.Join(root, e.Name())
+		text, err := os.ReadFile(path)
+		if err != nil {
+			debugf("unable to read license file %s: %s", path, err)
+			continue
+		}
+
+		licenses = append(licenses, &License{
+			SPDX: guessLicense(string(text)),
+			File: relPath(path),
+		})
+	}
+
+	sort.Slice(licenses, func(i, j int) bool {
+		return licenses[i].File < licenses[j].File
+	})
+	return licenses
+}
+
+// guessLicense returns the SPDX identifier of the license with the given
+// text, or an empty string if it is unknown.
+func guessLicense(text string) string {
+	// Normalize whitespace, as license texts are wrapped arbitrarily.
+	text = strings.Join(strings.Fields(text), " ")
+	for _, g := range licenseGuesses {
+		matches := true
+		for _, p := range g.phrases {
+			if !strings.Contains(text, p) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return g.spdx
+		}
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// NewLineCount returns the line count of the given declaration, whose body,
+// if it is a function, can be nil.
+func NewLineCount(decl ast.Node, body *ast.BlockStmt, fset *token.FileSet) *LineCount {
+	end := decl.End()
+	if body != nil {
+		end = body.End()
+	}
+
+	c := &LineCount{Decl: lineSpan(fset, decl.Pos(), end)}
+	if body != nil {
+		c.Body = lineSpan(fset, body.Lbrace, body.Rbrace)
+	}
+	return c
+}
+
+func lineSpan(fset *token.FileSet, start, end token.Pos) int {
+	return fset.Position(end).Line - fset.Position(start).Line + 1
+}
@@ -0,0 +1,47 @@
+package main
+
+import "go/doc/comment"
+
+// NewLinks returns the hyperlinks of a doc comment, or nil if it has none:
+// the URLs written in its text, linked as they are, followed by its link
+// definitions, such as "[Go]: https://go.dev", whether they are used or
+// not. URLs written many times are only listed once.
+func NewLinks(text string) []*Link {
+	var (
+		links []*Link
+		seen  = make(map[string]bool)
+		doc   = new(comment.Parser).Parse(text)
+	)
+
+	var visit func(texts []comment.Text)
+	visit = func(texts []comment.Text) {
+		for _, t := range texts {
+			if l, ok := t.(*comment.Link); ok && l.Auto && !seen[l.URL] {
+				seen[l.URL] = true
+				links = append(links, &Link{URL: l.URL})
+			}
+		}
+	}
+
+	for _, block := range doc.Content {
+		switch b := block.(type) {
+		case *comment.Paragraph:
+			visit(b.Text)
+		case *comment.Heading:
+			visit(b.Text)
+		case *comment.List:
+			for _, item := range b.Items {
+				for _, c := range item.Content {
+					if p, ok := c.(*comment.Paragraph); ok {
+						visit(p.Text)
+					}
+				}
+			}
+		}
+	}
+
+	for _, def := range doc.Links {
+		links = append(links, &Link{URL: def.URL, Text: def.Text, Definition: true})
+	}
+	return links
+}
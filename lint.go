@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/doc"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var enableLint = flag.String("enable-lint", "", "comma-separated linters to run on the documentation of symbols, or all: "+strings.Join(linterNames(), ", "))
+
+// docLinter checks the documentation of a symbol, returning a message for
+// every problem found in it.
+type docLinter interface {
+	Lint(sym *Symbol, text string) []string
+}
+
+// linterDescriptions describe what each of the linters checks.
+var linterDescriptions = map[string]string{
+	"doc-links":    "Doc links to symbols of the package refer to existing ones.",
+	"leading-name": "The first sentence of comments starts with the name of the symbol.",
+	"period":       "Comments end with a period.",
+}
+
+// linters are the available documentation linters, created for each
+// package they lint.
+var linters = map[string]func(pkg *Pkg) docLinter{
+	"doc-links":    newDocLinksLinter,
+	"leading-name": func(*Pkg) docLinter { return leadingNameLinter{} },
+	"period":       func(*Pkg) docLinter { return periodLinter{} },
+}
+
+func linterNames() []string {
+	var names []string
+	for name := range linters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// enabledLinters are the names of the linters given with -enable-lint, in
+// order.
+var enabledLinters []string
+
+// parseLinters parses the list of linters given with -enable-lint.
+func parseLinters(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "all" {
+		return linterNames(), nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if _, ok := linters[name]; !ok {
+			return nil, fmt.Errorf("invalid -enable-lint: unknown linter %q, expecting all or some of %s", name, strings.Join(linterNames(), ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// lintPackage runs the enabled linters on the documentation of all the
+// symbols of the package. Undocumented symbols are not linted, and groups
+// of values only once.
+func lintPackage(pkg *Pkg) []*LintFinding {
+	var (
+		findings = []*LintFinding{}
+		seen     = make(map[*Value]bool)
+		active   = make([]docLinter, len(enabledLinters))
+	)
+	for i, name := range enabledLinters {
+		active[i] = linters[name](pkg)
+	}
+
+	for _, sym := range packageSymbols(pkg) {
+		if sym.Value != nil {
+			if seen[sym.Value] {
+				continue
+			}
+			seen[sym.Value] = true
+		}
+
+		text := strings.TrimSpace(sym.Doc)
+		if text == "" {
+			continue
+		}
+
+		for i, l := range active {
+			for _, msg := range l.Lint(sym, text) {
+				findings = append(findings, &LintFinding{
+					Linter:  enabledLinters[i],
+					Symbol:  sym.Name,
+					Message: msg,
+					Pos:     sym.Pos,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// periodLinter checks that comments end with a period, unless they end
+// with a code block.
+type periodLinter struct{}
+
+func (periodLinter) Lint(sym *Symbol, text string) []string {
+	lines := strings.Split(text, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if unicode.IsSpace(rune(line[0])) || strings.ContainsAny(line[len(line)-1:], ".!?:") {
+			return nil
+		}
+		break
+	}
+	return []string{"comment does not end with a period"}
+}
+
+// leadingNameLinter checks that the first sentence of comments starts with
+// the name of the symbol, optionally preceded by an article. Groups of
+// values are skipped, as their comments are about all of them.
+type leadingNameLinter struct{}
+
+func (leadingNameLinter) Lint(sym *Symbol, text string) []string {
+	if sym.Value != nil && len(sym.Value.Names) > 1 {
+		return nil
+	}
+
+	name := sym.Name[strings.LastIndex(sym.Name, ".")+1:]
+	if startsWithName(new(doc.Package).Synopsis(text), name) {
+		return nil
+	}
+	return []string{fmt.Sprintf("comment should start with the name of the symbol, %s", name)}
+}
+
+var docLinkRegexp = regexp.MustCompile(`\[\*?([\pL_][\pL\pN_]*(?:\.[\pL_][\pL\pN_]*)?)\]`)
+
+// docLinksLinter checks that the doc links to symbols of the package, such
+// as [Name] or [Type.Method], refer to existing ones. Links to other
+// packages are not checked, and neither are those with a lowercase first
+// element, which may be packages.
+type docLinksLinter struct {
+	symbols map[string]bool
+}
+
+func newDocLinksLinter(pkg *Pkg) docLinter {
+	return &docLinksLinter{symbolNames(pkg)}
+}
+
+func (l *docLinksLinter) Lint(sym *Symbol, text string) []string {
+	var msgs []string
+	for _, m := range docLinkRegexp.FindAllStringSubmatchIndex(text, -1) {
+		// Link definitions, such as "[Go]: https://go.dev", are not doc
+		// links.
+		if m[1] < len(text) && text[m[1]] == ':' {
+			continue
+		}
+
+		target := text[m[2]:m[3]]
+		first, _, _ := strings.Cut(target, ".")
+		if !unicode.IsUpper([]rune(first)[0]) {
+			continue
+		}
+
+		if !l.symbols[target] {
+			msgs = append(msgs, fmt.Sprintf("doc link [%s] does not refer to a symbol of the package", target))
+		}
+	}
+	return msgs
+}
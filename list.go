@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var listOnly = flag.Bool("list", false, "only print the import path, directory and number of files of every package matched, without documenting them")
+
+// listPackages prints a line for every one of the given packages with its
+// import path, directory and the number of files that would be parsed,
+// separated by tabs.
+func listPackages(pkgNames []string) {
+	w := bufio.NewWriter(os.Stdout)
+	for _, name := range pkgNames {
+		if isGoFile(name) {
+			path, err := filepath.Abs(name)
+			if err != nil {
+				fatalf("%s", err)
+			}
+			fmt.Fprintf(w, "%s\t%s\t1\n", name, path)
+			continue
+		}
+
+		dir, err := packageSrcDir(name)
+		if err != nil {
+			fatalf("%s: %s", name, err)
+		}
+
+		files, err := sourceFiles(dir)
+		if err != nil {
+			fatalf("%s: %s", name, err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\n", name, dir, len(files))
+	}
+
+	if err := w.Flush(); err != nil {
+		fatalf("%s", err)
+	}
+}
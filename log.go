@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+type logLevel int
+
+const (
+	levelError logLevel = iota
+	levelWarning
+	levelInfo
+	levelDebug
+	levelTrace
+)
+
+var (
+	quiet     = flag.Bool("q", false, "only report errors")
+	verbose   = flag.Bool("v", false, "report debug details, such as the files being parsed")
+	verbose2  = flag.Bool("vv", false, "report even more debug details than -v")
+	logFormat = flag.String("log-format", "text", "format of the messages reported to stderr: text, or json for an event per line")
+)
+
+var logger = &leveledLogger{
+	level:  levelInfo,
+	logger: log.New(os.Stderr, "godocjson: ", 0),
+}
+
+// leveledLogger is a logger that discards all messages above its level.
+type leveledLogger struct {
+	level  logLevel
+	logger *log.Logger
+	json   bool
+}
+
+var levelNames = map[logLevel]string{
+	levelError:   "error",
+	levelWarning: "warning",
+	levelInfo:    "info",
+	levelDebug:   "debug",
+	levelTrace:   "trace",
+}
+
+// logEvent is a message reported with -log-format=json.
+type logEvent struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// setLogLevel sets the level of the logger according to the -q, -v and -vv
+// flags.
+func setLogLevel() {
+	switch {
+	case *quiet:
+		logger.level = levelError
+	case *verbose2:
+		logger.level = levelTrace
+	case *verbose:
+		logger.level = levelDebug
+	}
+}
+
+// setLogFormat sets the format of the logger according to -log-format.
+func setLogFormat() {
+	switch *logFormat {
+	case "text":
+	case "json":
+		logger.json = true
+		logger.logger.SetPrefix("")
+	default:
+		fatalf("invalid -log-format %q: expecting text or json", *logFormat)
+	}
+}
+
+func (l *leveledLogger) logf(level logLevel, prefix, format string, args ...interface{}) {
+	if level > l.level {
+		return
+	}
+	clearProgress()
+	l.output(level, prefix, fmt.Sprintf(format, args...))
+}
+
+// output writes a message of the given level, as a JSON event if
+// requested, without the prefix of its level then.
+func (l *leveledLogger) output(level logLevel, prefix, msg string) {
+	if !l.json {
+		l.logger.Output(4, prefix+msg)
+		return
+	}
+
+	data, _ := json.Marshal(&logEvent{
+		Time:    time.Now().UTC(),
+		Level:   levelNames[level],
+		Message: msg,
+	})
+	l.logger.Output(4, string(data))
+}
+
+func errorf(format string, args ...interface{}) {
+	logger.logf(levelError, "error: ", format, args...)
+}
+
+func fatalf(format string, args ...interface{}) {
+	logger.logf(levelError, "", format, args...)
+	os.Exit(1)
+}
+
+func warnf(format string, args ...interface{}) {
+	logger.logf(levelWarning, "warning: ", format, args...)
+}
+
+func infof(format string, args ...interface{}) {
+	logger.logf(levelInfo, "", format, args...)
+}
+
+func debugf(format string, args ...interface{}) {
+	logger.logf(levelDebug, "debug: ", format, args...)
+}
+
+func tracef(format string, args ...interface{}) {
+	logger.logf(levelTrace, "trace: ", format, args...)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func runLookup(args []string) {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	fs.BoolVar(resolveTypes, "resolve-types", false, "type-check the package to resolve the types of values")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson lookup file.go:line[:column]\n\n"+
+			"Prints the documentation of the symbol declared at the given position.\n\n")
+		fs.PrintDefaults()
+	}
+	rest := parseInterspersed(fs, args)
+	if len(rest) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	file, line, col, err := parseLocation(rest[0])
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	importPath, name, err := enclosingSymbol(file, line, col)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	pkg, err := extract(importPath)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	sym := findSymbol(packageSymbols(pkg), name)
+	if sym == nil {
+		fatalf("%s.%s is not documented", importPath, name)
+	}
+
+	printJSON(sym, nil)
+}
+
+// parseLocation parses a location in the form file:line[:column]. The
+// column is 1 if not given.
+func parseLocation(loc string) (file string, line, col int, err error) {
+	parts := strings.Split(loc, ":")
+	if len(parts) > 2 {
+		if n, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
+			col = n
+			parts = parts[:len(parts)-1]
+		}
+	}
+
+	if len(parts) < 2 {
+		return "", 0, 0, fmt.Errorf("invalid location %q: expecting file:line[:column]", loc)
+	}
+
+	line, err = strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid location %q: expecting file:line[:column]", loc)
+	}
+
+	if col == 0 {
+		col = 1
+	}
+	return strings.Join(parts[:len(parts)-1], ":"), line, col, nil
+}
+
+// enclosingSymbol returns the import path of the package of the given file
+// and the name, as in the symbols of the server, of the top-level
+// declaration enclosing the given position, including its doc comment and
+// body.
+func enclosingSymbol(path string, line, col int) (pkgPath, name string, err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return "", "", err
+	}
+
+	tf := fset.File(f.Pos())
+	if line < 1 || line > tf.LineCount() || col < 1 {
+		return "", "", fmt.Errorf("%s:%d:%d is outside of the file", path, line, col)
+	}
+
+	offset := int(tf.LineStart(line)-token.Pos(tf.Base())) + col - 1
+	if offset > tf.Size() {
+		return "", "", fmt.Errorf("%s:%d:%d is outside of the file", path, line, col)
+	}
+
+	// Indentation belongs to what follows it.
+	for offset < len(src) && (src[offset] == ' ' || src[offset] == '\t') {
+		offset++
+	}
+	pos := tf.Pos(offset)
+
+	if name = declAt(f, pos); name == "" {
+		return "", "", fmt.Errorf("no declaration found at %s:%d:%d", path, line, col)
+	}
+
+	pkgPath, err = importPath(filepath.Dir(path))
+	if err != nil {
+		return "", "", err
+	}
+	return pkgPath, name, nil
+}
+
+// declAt returns the name of the declaration of the file at the given
+// position, or an empty string if there is none.
+func declAt(f *ast.File, pos token.Pos) string {
+	within := func(doc *ast.CommentGroup, node ast.Node) bool {
+		return start(doc, node) <= pos && pos <= node.End()
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !within(d.Doc, d) {
+				continue
+			}
+
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				return embeddedName(d.Recv.List[0].Type) + "." + d.Name.Name
+			}
+			return d.Name.Name
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT || !within(d.Doc, d) || len(d.Specs) == 0 {
+				continue
+			}
+
+			// Positions between specs belong to the one before them, and
+			// the keyword of the declaration to the first one.
+			spec := d.Specs[0]
+			for _, s := range d.Specs {
+				if start(specDoc(s), s) <= pos {
+					spec = s
+				}
+			}
+
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				return s.Name.Name
+			case *ast.ValueSpec:
+				name := s.Names[0]
+				for _, n := range s.Names {
+					if n.Pos() <= pos {
+						name = n
+					}
+				}
+				return name.Name
+			}
+		}
+	}
+	return ""
+}
+
+// start returns the position of a node, or of its doc comment if it has
+// one.
+func start(doc *ast.CommentGroup, node ast.Node) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+	return node.Pos()
+}
+
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc
+	case *ast.ValueSpec:
+		return s.Doc
+	}
+	return nil
+}
+
+// findSymbol returns the symbol with the given name, if any.
+func findSymbol(symbols []*Symbol, name string) *Symbol {
+	for _, sym := range symbols {
+		if sym.Name == name {
+			return sym
+		}
+	}
+	return nil
+}
+
+// lookupHandler serves the documentation of the symbol declared at the
+// position given in the file, line and column parameters of the request,
+// from the corpus it returns.
+type lookupHandler func() *corpus
+
+func (h lookupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	file := q.Get("file")
+	line, err := strconv.Atoi(q.Get("line"))
+	if file == "" || err != nil {
+		http.Error(w, "expecting file and line parameters", http.StatusBadRequest)
+		return
+	}
+
+	col := 1
+	if c := q.Get("column"); c != "" {
+		if col, err = strconv.Atoi(c); err != nil {
+			http.Error(w, "invalid column: "+c, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Only the files of the packages served are parsed.
+	c := h()
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err == nil {
+		dir, err = importPath(dir)
+	}
+	if err != nil || c.byPath[dir] == nil || !strings.HasSuffix(file, ".go") {
+		http.Error(w, "no package served contains "+file, http.StatusNotFound)
+		return
+	}
+
+	importPath, name, err := enclosingSymbol(file, line, col)
+	if r.Context().Err() != nil {
+		// The request timed out or the client went away while parsing.
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	sym := findSymbol(c.symbols[importPath], name)
+	if sym == nil {
+		http.Error(w, fmt.Sprintf("%s.%s is not documented", importPath, name), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sym); err != nil {
+		debugf("unable to write response: %s", err)
+	}
+}
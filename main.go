@@ -1,82 +1,176 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/doc"
 	"go/parser"
-	"go/printer"
 	"go/token"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/erizocosmico/godocjson/schema"
 	parseutil "gopkg.in/src-d/go-parse-utils.v1"
 )
 
-type Pkg struct {
-	Doc        string
-	Name       string
-	ImportPath string
-	Imports    []string
-	Filenames  []string
-	Notes      map[string][]*doc.Note
-
-	Bugs []string
-
-	Consts []*Value
-	Types  []*Type
-	Vars   []*Value
-	Funcs  []*Func
-}
-
-func NewPkg(pkg *doc.Package, fset *token.FileSet) *Pkg {
+func NewPkg(pkg *doc.Package, src *Source) *Pkg {
 	var consts = make([]*Value, len(pkg.Consts))
 	for i, c := range pkg.Consts {
-		consts[i] = NewValue(c, fset)
+		consts[i] = NewValue(c, src)
 	}
 
 	var vars = make([]*Value, len(pkg.Vars))
 	for i, v := range pkg.Vars {
-		vars[i] = NewValue(v, fset)
+		vars[i] = NewValue(v, src)
 	}
 
 	var funcs = make([]*Func, len(pkg.Funcs))
 	for i, f := range pkg.Funcs {
-		funcs[i] = NewFunc(f, fset)
+		funcs[i] = NewFunc(f, src)
 	}
 
-	var types = make([]*Type, len(pkg.Types))
+	var (
+		types = make([]*Type, len(pkg.Types))
+		uses  = NewTypeUses(pkg)
+	)
 	for i, t := range pkg.Types {
-		types[i] = NewType(t, fset)
+		types[i] = NewType(t, src)
+		types[i].UsedBy = uses[t.Name]
 	}
 
 	var files = make([]string, len(pkg.Filenames))
 	for i, f := range pkg.Filenames {
-		files[i] = removeGoPath(f)
-	}
-	return &Pkg{
-		Doc:        pkg.Doc,
-		Name:       pkg.Name,
-		ImportPath: pkg.ImportPath,
-		Imports:    pkg.Imports,
-		Filenames:  files,
-		Notes:      pkg.Notes,
-		Bugs:       pkg.Bugs,
-		Consts:     consts,
-		Types:      types,
-		Vars:       vars,
-		Funcs:      funcs,
+		files[i] = relPath(f)
+	}
+
+	imports := NewImports(src)
+	notes := NewNotes(pkg.Notes, src)
+	p := &Pkg{
+		Doc:         pkg.Doc,
+		DocRaw:      src.rawPackageDoc(),
+		Deprecated:  deprecationNotice(src.packageDoc()),
+		Name:        pkg.Name,
+		ImportPath:  pkg.ImportPath,
+		Imports:     pkg.Imports,
+		ImportSpecs: imports,
+		UsesUnsafe:  importsPackage(imports, "unsafe"),
+		UsesReflect: importsPackage(imports, "reflect"),
+		UsesCgo:     importsPackage(imports, "C"),
+		Filenames:   files,
+		Files:       NewFiles(src),
+		Notes:       notes,
+		Bugs:        pkg.Bugs,
+		BugNotes:    notes["BUG"],
+		Generate:    NewGenerators(src),
+		Directives:  fileDirectives(src),
+		Embeds:      src.Embeds,
+		Stats:       src.Stats,
+		GoVersion:   NewGoVersion(src),
+		Licenses:    findLicenses(src.Dir),
+		Consts:      consts,
+		Types:       types,
+		Vars:        vars,
+		Funcs:       funcs,
+		Errors:      NewErrors(pkg, src),
+		Embeddings:  NewEmbeddings(pkg, src),
+
+		Dependencies:    NewDependencies(src, pkg.Imports),
+		Replaces:        NewReplaces(src, pkg.Imports),
+		Excludes:        NewExcludes(src),
+		Implementations: src.Implementations,
+		CallGraph:       src.CallGraph,
+
+		GeneratorVersion: generatorVersion(),
+		SchemaVersion:    schema.Version,
+	}
+
+	resolveReplacements(p)
+	describeReturnedErrors(p)
+	p.Sections = NewDocSections(packageDocText(p))
+	p.Snippets = NewSnippets(packageDocText(p))
+	p.Links = NewLinks(packageDocText(p))
+	return p
+}
+
+// NewImports returns all the import declarations in the files of the
+// package, in the order they appear.
+func NewImports(src *Source) []*Import {
+	var imports = []*Import{}
+	for _, f := range src.Files {
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			imp := &Import{
+				Path:       path,
+				IsStd:      path != "C" && isStdlib(path),
+				IsInternal: isInternal(path),
+				IsVendored: isVendored(src.Dir, path),
+				Uses:       src.ImportUses[spec],
+				Pos:        NewPos(spec, src.Fset),
+			}
+			if spec.Name != nil {
+				imp.Name = spec.Name.Name
+				imp.IsBlank = imp.Name == "_"
+				imp.IsDot = imp.Name == "."
+			}
+			imports = append(imports, imp)
+		}
 	}
+	return imports
 }
 
-type Pos struct {
-	Start *FilePos
-	End   *FilePos
+// isInternal reports whether the given import path has an internal
+// element.
+func isInternal(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// isVendored reports whether the given import path is resolved to a vendor
+// directory from the given one, which is the case if it is in the vendor
+// directory of the directory or any of its parents, up to the root of its
+// module.
+func isVendored(dir, path string) bool {
+	if dir == "" || isStdlib(path) {
+		return false
+	}
+
+	root := moduleRoot(dir)
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "vendor", filepath.FromSlash(path))); err == nil && fi.IsDir() {
+			return true
+		}
+
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+func importsPackage(imports []*Import, path string) bool {
+	for _, imp := range imports {
+		if imp.Path == path {
+			return true
+		}
+	}
+	return false
 }
 
 func NewPos(node ast.Node, fset *token.FileSet) *Pos {
@@ -86,173 +180,1158 @@ func NewPos(node ast.Node, fset *token.FileSet) *Pos {
 	}
 }
 
-type FilePos struct {
-	Line   int
-	Column int
-	File   string
-}
-
 func NewFilePos(pos token.Pos, fset *token.FileSet) *FilePos {
 	p := fset.Position(pos)
 	return &FilePos{
 		Line:   p.Line,
 		Column: p.Column,
-		File:   removeGoPath(p.Filename),
+		File:   relPath(p.Filename),
 	}
 }
 
-type Type struct {
-	Kind string
-	Doc  string
-	Name string
-	Decl string
-	Pos  *Pos
-
-	Consts  []*Value
-	Vars    []*Value
-	Funcs   []*Func
-	Methods []*Func
-}
-
-func NewType(typ *doc.Type, fset *token.FileSet) *Type {
-	var buf bytes.Buffer
-	printer.Fprint(&buf, fset, typ.Decl)
+func NewType(typ *doc.Type, src *Source) *Type {
+	decl := printDecl(src.Fset, typ.Decl)
 
 	var consts = make([]*Value, len(typ.Consts))
 	for i, c := range typ.Consts {
-		consts[i] = NewValue(c, fset)
+		consts[i] = NewValue(c, src)
 	}
 
 	var vars = make([]*Value, len(typ.Vars))
 	for i, v := range typ.Vars {
-		vars[i] = NewValue(v, fset)
+		vars[i] = NewValue(v, src)
 	}
 
 	var funcs = make([]*Func, len(typ.Funcs))
 	for i, f := range typ.Funcs {
-		funcs[i] = NewFunc(f, fset)
+		funcs[i] = NewFunc(f, src)
 	}
 
 	var methods = make([]*Func, len(typ.Methods))
 	for i, m := range typ.Methods {
-		methods[i] = NewFunc(m, fset)
+		methods[i] = NewFunc(m, src)
 	}
 
 	return &Type{
-		Kind:    "type",
-		Doc:     typ.Doc,
-		Name:    typ.Name,
-		Decl:    buf.String(),
-		Consts:  consts,
-		Vars:    vars,
-		Funcs:   funcs,
-		Methods: methods,
-		Pos:     NewPos(typ.Decl, fset),
+		Kind:       "type",
+		Doc:        typ.Doc,
+		DocRaw:     src.rawDoc(typeSpec(typ.Decl), typ.Decl),
+		Snippets:   NewSnippets(typ.Doc),
+		Links:      NewLinks(typ.Doc),
+		Deprecated: deprecationNotice(typ.Doc),
+		Name:       typ.Name,
+		Decl:       decl,
+		Tokens:     NewDeclTokens(decl),
+		AST:        src.declAST(typ.Decl, typeSpec(typ.Decl)),
+		Fields:     structFields(typ.Decl, src),
+		Directives: NewDirectives(src.docs(typeSpec(typ.Decl), typ.Decl), src.Fset),
+		Enum:       NewEnum(typ, src),
+		Consts:     consts,
+		Vars:       vars,
+		Funcs:      funcs,
+		Methods:    methods,
+		Inherited:  NewInheritedMethods(typ, src),
+		Pos:        NewPos(typ.Decl, src.Fset),
+		Generated:  src.isGenerated(typ.Decl),
+		Lines:      NewLineCount(typ.Decl, nil, src.Fset),
 	}
 }
 
-type Value struct {
-	Kind  string
-	Doc   string
-	Names []string
-	Decl  string
-	Pos   *Pos
+func NewValue(val *doc.Value, src *Source) *Value {
+	decl := printDecl(src.Fset, val.Decl)
+	return &Value{
+		Kind:       val.Decl.Tok.String(),
+		Doc:        val.Doc,
+		DocRaw:     src.rawDoc(val.Decl),
+		Snippets:   NewSnippets(val.Doc),
+		Links:      NewLinks(val.Doc),
+		Deprecated: deprecationNotice(val.Doc),
+		Names:      val.Names,
+		Decl:       decl,
+		Decls:      valueDecls(src.Fset, val.Decl, val.Names),
+		Tokens:     NewDeclTokens(decl),
+		AST:        src.declAST(val.Decl),
+		Pos:        NewPos(val.Decl, src.Fset),
+		Generated:  src.isGenerated(val.Decl),
+		NamePos:    namePositions(val.Decl, src.Fset),
+		Types:      src.valueTypes(val.Decl),
+		Directives: NewDirectives(src.docs(valueNodes(val.Decl)...), src.Fset),
+		Embeds:     NewEmbeds(val.Decl, src),
+		Iota:       NewIotaGroup(val.Decl, src),
+	}
 }
 
-func NewValue(val *doc.Value, fset *token.FileSet) *Value {
-	var buf bytes.Buffer
-	printer.Fprint(&buf, fset, val.Decl)
-	return &Value{
-		Kind:  "value",
-		Doc:   val.Doc,
-		Names: val.Names,
-		Decl:  buf.String(),
-		Pos:   NewPos(val.Decl, fset),
+func NewFunc(fn *doc.Func, src *Source) *Func {
+	decl := printDecl(src.Fset, fn.Decl)
+
+	params := NewFields(fn.Decl.Type.Params, src)
+	describeParams(fn.Doc, params)
+	variadic := len(params) > 0 && params[len(params)-1].IsVariadic
+	results := NewFields(fn.Decl.Type.Results, src)
+	errResult := errorResult(results)
+	describeResults(fn.Doc, results, errResult)
+
+	var metrics *Metrics
+	if *withMetrics {
+		metrics = NewMetrics(fn.Decl, src.Bodies[fn.Decl], src.Fset)
+	}
+
+	return &Func{
+		Kind:       "func",
+		Doc:        fn.Doc,
+		DocRaw:     src.rawDoc(fn.Decl),
+		Snippets:   NewSnippets(fn.Doc),
+		Links:      NewLinks(fn.Doc),
+		Deprecated: deprecationNotice(fn.Doc),
+		Name:       fn.Name,
+		Recv:       fn.Recv,
+		Orig:       fn.Orig,
+		Level:      fn.Level,
+		Decl:       decl,
+		Tokens:     NewDeclTokens(decl),
+		AST:        src.declAST(fn.Decl),
+		Params:     params,
+		Results:    results,
+		IsVariadic: variadic,
+		Pos:        NewPos(fn.Decl, src.Fset),
+		Generated:  src.isGenerated(fn.Decl),
+		Lines:      NewLineCount(fn.Decl, src.Bodies[fn.Decl], src.Fset),
+
+		ReturnsError: errResult >= 0,
+		ErrorResult:  errResult,
+
+		Directives: NewDirectives(src.docs(fn.Decl), src.Fset),
+		Metrics:    metrics,
+	}
+}
+
+// errorResult returns the index of the last result of type error, or -1 if
+// there is none.
+func errorResult(results []*Field) int {
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i].Type == "error" {
+			return i
+		}
 	}
+	return -1
 }
 
-type Func struct {
-	Kind string
-	Doc  string
-	Name string
-	Decl string
+// NewFields returns a Field for every name in the given list of parameters
+// or results.
+func NewFields(list *ast.FieldList, src *Source) []*Field {
+	var fields = []*Field{}
+	if list == nil {
+		return fields
+	}
 
-	Recv  string
-	Orig  string
-	Level int
+	for _, f := range list.List {
+		typ := src.typeString(f.Type)
+		_, variadic := f.Type.(*ast.Ellipsis)
+		if len(f.Names) == 0 {
+			fields = append(fields, &Field{Type: typ, IsVariadic: variadic})
+			continue
+		}
 
-	Pos *Pos
+		for _, n := range f.Names {
+			fields = append(fields, &Field{Name: n.Name, Type: typ, IsVariadic: variadic})
+		}
+	}
+	return fields
 }
 
-func NewFunc(fn *doc.Func, fset *token.FileSet) *Func {
-	var buf bytes.Buffer
-	printer.Fprint(&buf, fset, fn.Decl)
-	return &Func{
-		Kind:  "func",
-		Doc:   fn.Doc,
-		Name:  fn.Name,
-		Recv:  fn.Recv,
-		Orig:  fn.Orig,
-		Level: fn.Level,
-		Decl:  buf.String(),
-		Pos:   NewPos(fn.Decl, fset),
+// NewStructFields returns a Field for every field of the given struct.
+func NewStructFields(st *ast.StructType, src *Source) []*Field {
+	var fields = []*Field{}
+	for _, f := range st.Fields.List {
+		typ, tag := src.typeString(f.Type), fieldTag(f)
+		if len(f.Names) == 0 {
+			fields = append(fields, &Field{Name: embeddedName(f.Type), Type: typ, Embedded: true, Tag: tag})
+			continue
+		}
+
+		for _, n := range f.Names {
+			fields = append(fields, &Field{Name: n.Name, Type: typ, Tag: tag})
+		}
+	}
+	return fields
+}
+
+// fieldTag returns the tag of a struct field, unquoted.
+func fieldTag(f *ast.Field) string {
+	if f.Tag == nil {
+		return ""
+	}
+
+	tag, _ := strconv.Unquote(f.Tag.Value)
+	return tag
+}
+
+// embeddedName returns the name of an embedded field with the given type.
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	}
+	return ""
+}
+
+// namePositions returns the position of every name declared in the given
+// const or var declaration, or nil for those without one.
+func namePositions(decl *ast.GenDecl, fset *token.FileSet) []*Pos {
+	var result = []*Pos{}
+	for _, spec := range decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		for _, n := range vs.Names {
+			var pos *Pos
+			if n.Pos().IsValid() {
+				pos = NewPos(n, fset)
+			}
+			result = append(result, pos)
+		}
+	}
+	return result
+}
+
+// typeSpec returns the spec of the type declared in decl.
+func typeSpec(decl *ast.GenDecl) *ast.TypeSpec {
+	for _, spec := range decl.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); ok {
+			return ts
+		}
+	}
+	return nil
+}
+
+// valueNodes returns the given const or var declaration along with all its
+// specs.
+func valueNodes(decl *ast.GenDecl) []ast.Node {
+	var nodes = []ast.Node{decl}
+	for _, spec := range decl.Specs {
+		nodes = append(nodes, spec)
+	}
+	return nodes
+}
+
+// structFields returns the fields of the type declared in decl, if it is a
+// struct, or nil otherwise.
+func structFields(decl *ast.GenDecl, src *Source) []*Field {
+	if ts := typeSpec(decl); ts != nil {
+		if st, ok := ts.Type.(*ast.StructType); ok {
+			return NewStructFields(st, src)
+		}
+	}
+	return nil
+}
+
+var (
+	pathBase    = flag.String("path-base", "gopath", "root file paths are relative to: module, gopath or absolute")
+	pathStyle   = flag.String("path-style", "slash", "separator of the elements of file paths: slash, so they are the same on every platform, or native")
+	showVersion = flag.Bool("version", false, "print the version and exit")
+	fromStdin   = flag.Bool("stdin", false, "document a single Go file read from the standard input, same as giving - as the package")
+	packageName = flag.String("package-name", "", "package to document in directories containing more than one")
+)
+
+// stdinFilename is the name given to the file read from the standard input.
+const stdinFilename = "<stdin>"
+
+// commands are the subcommands available, which receive the rest of the
+// arguments. They are only run if given as the first argument, so packages
+// named like them, such as html, are documented if after -- or any flag.
+var commands = map[string]func(args []string){
+	"changelog": runChangelog,
+	"check":     runCheck,
+	"graph":     runGraph,
+	"html":      runHTML,
+	"lookup":    runLookup,
+	"merge":     runMerge,
+	"proxy":     runProxy,
+	"search":    runSearch,
+	"serve":     runServe,
+}
+
+func runCLI() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Parse()
+	setLogLevel()
+	setLogFormat()
+	if *showVersion {
+		printVersion()
+		return
+	}
+
+	if *printTypes {
+		if err := writeTypeScript(os.Stdout); err != nil {
+			fatalf("%s", err)
+		}
+		return
+	}
+
+	switch *pathBase {
+	case "module", "gopath", "absolute":
+	default:
+		fatalf("invalid -path-base %q: expecting module, gopath or absolute", *pathBase)
+	}
+
+	switch *pathStyle {
+	case "slash", "native":
+	default:
+		fatalf("invalid -path-style %q: expecting slash or native", *pathStyle)
+	}
+
+	switch *withDependencies {
+	case "", "direct", "all":
+	default:
+		fatalf("invalid -dependencies %q: expecting direct or all", *withDependencies)
+	}
+
+	if err := checkPrefixReplacements(); err != nil {
+		fatalf("%s", err)
+	}
+
+	if err := applyEnvFlags(); err != nil {
+		fatalf("%s", err)
+	}
+
+	if *concurrency < 0 {
+		fatalf("invalid -concurrency %d: expecting a positive number", *concurrency)
+	}
+
+	if *declMaxWidth < 0 {
+		fatalf("invalid -decl-max-width %d: expecting a positive number", *declMaxWidth)
+	}
+
+	if *declIndent < 0 {
+		fatalf("invalid -decl-indent %d: expecting a positive number", *declIndent)
+	}
+
+	switch *fieldCase {
+	case "pascal", "camel", "snake":
+	default:
+		fatalf("invalid -field-case %q: expecting pascal, camel or snake", *fieldCase)
+	}
+
+	if _, ok := formats[*format]; !ok {
+		fatalf("invalid -format %q: expecting one of %s", *format, strings.Join(formatNames(), ", "))
+	}
+
+	if *format != "json" && *queryFlag != "" {
+		fatalf("-query can only be used with the json format")
+	}
+
+	if *withSymbolIndex && *outDir == "" {
+		fatalf("-symbol-index can only be used with -outdir")
+	}
+
+	if *outDir != "" && *queryFlag != "" {
+		fatalf("-query cannot be used with -outdir")
+	}
+
+	if *format == "gob" && (*fieldCase != "pascal" || *noPos || *fieldsFlag != "") {
+		fatalf("-field-case, -no-pos and -fields cannot be used with the gob format, which is made of the types of the schema package as they are")
+	}
+
+	if *format != "json" && *execPlugin != "" {
+		fatalf("-exec-plugin can only be used with the json format")
+	}
+
+	if *fieldsFlag != "" {
+		var err error
+		if symbolFields, err = parseSymbolFields(*fieldsFlag); err != nil {
+			fatalf("%s", err)
+		}
+	}
+
+	// SARIF logs are made of the findings of the linters, so all of them
+	// are run unless some are chosen.
+	if *format == "sarif" && *enableLint == "" {
+		*enableLint = "all"
+	}
+
+	if *enableLint != "" {
+		var err error
+		if enabledLinters, err = parseLinters(*enableLint); err != nil {
+			fatalf("%s", err)
+		}
+	}
+
+	if *excludeSymbols != "" || *excludeKinds != "" {
+		var err error
+		if exclusion, err = parseExclusion(*excludeSymbols, *excludeKinds); err != nil {
+			fatalf("%s", err)
+		}
+	}
+
+	var q *query
+	if *queryFlag != "" {
+		var err error
+		if q, err = parseQuery(*queryFlag); err != nil {
+			fatalf("%s", err)
+		}
+	}
+
+	if *fromStdin || (flag.NArg() == 1 && flag.Arg(0) == "-") {
+		if *batchFile != "" {
+			fatalf("-batch cannot be used when documenting the standard input")
+		}
+
+		pkg, err := extractStdin()
+		if err != nil {
+			exitPolicy.fatal(err)
+		}
+
+		writePackages(false, q, func(emit func(*Pkg) error) error {
+			return emit(pkg)
+		})
+		return
+	}
+
+	if *zipFile != "" {
+		if flag.NArg() > 0 || *batchFile != "" {
+			fatalf("unexpected arguments: -zip documents all the packages in the archive")
+		}
+
+		writePackages(true, q, func(emit func(*Pkg) error) error {
+			return extractZip(*zipFile, "", "", emit)
+		})
+		return
+	}
+
+	args := flag.Args()
+	if *batchFile != "" {
+		batch, err := readBatch(*batchFile)
+		if err != nil {
+			fatalf("unable to read -batch: %s", err)
+		}
+		args = append(args, batch...)
+	}
+
+	if len(args) < 1 {
+		fatalf("unexpected number of arguments: expecting at least one argument with a package name")
+	}
+
+	for _, arg := range args {
+		if arg == "" {
+			fatalf("package name cannot be empty")
+		}
+	}
+
+	if *listOnly {
+		pkgNames, err := expandPatterns(args)
+		if err != nil {
+			fatalf("%s", err)
+		}
+
+		listPackages(pkgNames)
+		return
+	}
+
+	if *batchFile == "" && len(args) == 2 && !isPattern(args[0]) && isSymbol(args[1]) {
+		printSymbol(args[0], args[1], q)
+		return
+	}
+
+	if *incremental && *outDir == "" {
+		fatalf("-incremental requires -outdir")
+	}
+
+	pkgNames, err := expandPatterns(args)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if withDeps > 0 {
+		pkgNames = addDependencies(pkgNames, int(withDeps))
+	}
+
+	if *incremental {
+		if err := startIncremental(pkgNames); err != nil {
+			fatalf("unable to read the previous run: %s", err)
+		}
+	}
+
+	// A single package is printed as is, but as soon as more than one could
+	// be matched the output is always a list.
+	list := *batchFile != "" || len(args) > 1 || isPattern(args[0]) || withDeps > 0
+	writePackages(list, q, func(emit func(*Pkg) error) error {
+		return extractAll(context.Background(), pkgNames, emit)
+	})
+
+	if *incremental {
+		finishIncremental()
+	}
+}
+
+// writePackages writes all the packages passed by extract to its emit
+// function to the standard output. If list is false, only one package is
+// expected. If q is not nil, only the part of the output matching it is
+// written.
+func writePackages(list bool, q *query, extract func(emit func(*Pkg) error) error) {
+	if *openAPIFile != "" {
+		extract = withOpenAPI(extract, *openAPIFile)
+	}
+
+	if q != nil {
+		// Queries need the whole document, so the output cannot be
+		// streamed.
+		var docs = []interface{}{}
+		err := extract(func(pkg *Pkg) error {
+			exitPolicy.check(pkg)
+			var doc interface{} = pkg
+			if *execPlugin != "" {
+				out, err := runPlugin(pkg)
+				if err != nil {
+					return err
+				}
+				doc = out
+			}
+
+			docs = append(docs, doc)
+			return nil
+		})
+		if err != nil {
+			exitPolicy.fatal(err)
+		}
+
+		var doc interface{} = docs
+		if !list && len(docs) == 1 {
+			doc = docs[0]
+		}
+		printJSON(doc, q)
+		exitPolicy.exit()
+		return
+	}
+
+	var w packageWriter
+	if *outDir != "" {
+		w = newOutDirWriter(*outDir)
+	} else {
+		w = newPackageWriter(os.Stdout, list)
+	}
+
+	err := extract(func(pkg *Pkg) error {
+		exitPolicy.check(pkg)
+		return w.Write(pkg)
+	})
+	if err != nil {
+		exitPolicy.fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		fatalf("%s", err)
+	}
+	exitPolicy.exit()
+}
+
+// printSymbol prints the declaration of a single symbol of a package.
+func printSymbol(arg, name string, q *query) {
+	pkgName, err := importPath(arg)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	pkg, err := extract(pkgName)
+	if err != nil {
+		fatalf("%s: %s", pkgName, err)
+	}
+
+	sym, ok := lookupSymbol(pkg, name)
+	if !ok {
+		fatalf("no symbol %s in package %s", name, pkgName)
+	}
+
+	printJSON(sym, q)
+}
+
+// printJSON prints the given value as JSON, only the part matched by q if
+// it is not nil.
+func printJSON(v interface{}, q *query) {
+	v = outputValue(v)
+	if q != nil {
+		var err error
+		if v, err = q.eval(v); err != nil {
+			fatalf("%s", err)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		fatalf("%s", err)
+	}
+}
+
+const concurrencyUsage = "maximum number of packages parsed at the same time, the number of CPUs by default"
+
+var concurrency = flag.Int("concurrency", 0, concurrencyUsage)
+
+func init() {
+	flag.IntVar(concurrency, "j", 0, concurrencyUsage)
+}
+
+// extractAll extracts the documentation of all the given packages using a
+// pool of workers, as many as -concurrency allows. Packages are passed to
+// emit in the same order they were given as soon as they are ready, so they
+// can be written and released instead of keeping all of them in memory.
+// Once ctx is done, no more packages are started, the ones in progress are
+// finished and emitted, and its error is returned.
+func extractAll(ctx context.Context, pkgNames []string, emit func(*Pkg) error) error {
+	type result struct {
+		i   int
+		pkg *Pkg
+		err error
+	}
+
+	var (
+		jobs    = make(chan int)
+		results = make(chan result)
+		done    = make(chan struct{})
+		wg      sync.WaitGroup
+		workers = runtime.NumCPU()
+	)
+
+	if *concurrency > 0 {
+		workers = *concurrency
+	}
+
+	if workers > len(pkgNames) {
+		workers = len(pkgNames)
+	}
+
+	// Packages finishing out of order need to wait until the ones before
+	// them are emitted, so the number of packages in flight is bounded to
+	// keep memory usage flat.
+	inFlight := make(chan struct{}, 2*workers)
+
+	p := newProgress(len(pkgNames))
+	defer p.stop()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				p.started(pkgNames[j])
+				pkg, err := extract(pkgNames[j])
+				p.finished()
+				results <- result{j, pkg, err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range pkgNames {
+			select {
+			case inFlight <- struct{}{}:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		err     error
+		next    int
+		pending = make(map[int]*Pkg)
+	)
+
+	for r := range results {
+		if err != nil {
+			continue
+		}
+
+		if r.err != nil {
+			err = fmt.Errorf("%s: %w", pkgNames[r.i], r.err)
+			close(done)
+			continue
+		}
+
+		// Packages skipped with -incremental are nil, and are not emitted.
+		pending[r.i] = r.pkg
+		for pkg, ok := pending[next]; ok; pkg, ok = pending[next] {
+			if pkg != nil {
+				if err = emit(pkg); err != nil {
+					close(done)
+					break
+				}
+			}
+
+			delete(pending, next)
+			next++
+			<-inFlight
+		}
+	}
+
+	if err == nil {
+		err = ctx.Err()
 	}
+	return err
 }
 
-func main() {
-	if len(os.Args) != 2 {
-		log.Fatal("unexpected number of arguments: expecting one argument with a package name")
+// extract parses the package with the given name and builds its
+// documentation. If a cache directory was given, the result is served from
+// it when the package source did not change. With -incremental, there is no
+// result for packages that did not change since the last run.
+func extract(pkgName string) (*Pkg, error) {
+	if extractionSlots != nil {
+		extractionSlots <- struct{}{}
+		defer func() { <-extractionSlots }()
+	}
+
+	if isGoFile(pkgName) {
+		return extractFile(pkgName)
+	}
+
+	srcDir, err := packageSrcDir(pkgName)
+	if err != nil {
+		return nil, err
+	}
+
+	tracef("resolved package %s to directory %s", pkgName, srcDir)
+
+	files, err := sourceFiles(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []string
+	if *withExamples {
+		if tests, err = testFiles(srcDir); err != nil {
+			return nil, err
+		}
 	}
 
-	pkgName := os.Args[1]
-	if pkgName == "" {
-		log.Fatal("-pkg cannot be empty")
+	var key string
+	if *cacheDir != "" || *incremental {
+		key, err = cacheKey(pkgName, srcDir, append(files[:len(files):len(files)], tests...))
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	// Unchanged packages are not extracted nor passed to emit at all, as
+	// their output is already in -outdir.
+	if *incremental && skipUnchanged(pkgName, srcDir, key) {
+		return nil, nil
+	}
+
+	if *cacheDir != "" {
+		pkg, ok := loadCached(key)
+		stats.observeCache(ok)
+		if ok {
+			debugf("using cached documentation of %s", pkgName)
+			addUncached(pkg, srcDir)
+			pkg.Hash = documentHash(pkg)
+			return pkg, nil
+		}
+	}
+
+	start := time.Now()
 	fset := token.NewFileSet()
-	pkg, err := parsePackage(pkgName, fset)
+	pkg, hashes, parseErrors, err := parsePackage(fset, srcDir, files)
+	if err != nil {
+		return nil, err
+	}
+
+	testASTs, err := parseTestFiles(fset, srcDir, tests)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	result, err := buildPkg(pkgName, fset, srcDir, pkg, testASTs, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ParseErrors = parseErrors
+	stats.observeParse(pkgName, time.Since(start))
+	if *cacheDir != "" {
+		storeCached(key, result)
+	}
+
+	addUncached(result, srcDir)
+	result.Hash = documentHash(result)
+	return result, nil
+}
+
+// addUncached adds to the documentation of a package what is not cached
+// with it, as it is not part of the package source: the git revision, the
+// versions symbols appeared in, the translations and the README.
+func addUncached(pkg *Pkg, srcDir string) {
+	addGitInfo(pkg, srcDir)
+	addSince(pkg, srcDir)
+	addTranslations(pkg)
+	addReadme(pkg, srcDir)
+}
+
+// buildPkg builds the documentation of the given parsed package, whose
+// files are in srcDir and have the given hashes. The examples of the given
+// test files are attached to the symbols they document.
+func buildPkg(pkgName string, fset *token.FileSet, srcDir string, pkg *ast.Package, tests []*ast.File, hashes map[string]string) (*Pkg, error) {
+	src := NewSource(fset, srcDir, pkg)
+	src.Hashes = hashes
+	if *resolveTypes || *withImplements || *withCallGraph {
+		// This needs to happen before building the documentation, as doc.New
+		// strips unexported declarations from the AST.
+		info, err := checkTypes(pkgName, fset, pkg)
+		if err != nil {
+			return nil, err
+		}
+
+		if *resolveTypes {
+			src.Info = info
+		}
+
+		if *withImplements {
+			src.Implementations = NewImplementations(info)
+		}
+
+		if *withCallGraph {
+			src.CallGraph = NewCallGraph(src, info)
+		}
 	}
 
+	// Function bodies are needed too, so this cannot wait until the
+	// documentation is built.
+	src.Features = languageFeatures(src)
+	src.ImportUses = importUses(src)
+
 	docPkg := doc.New(pkg, pkgName, 0)
-	docPkg.Filter(func(name string) bool {
-		return !strings.HasPrefix(name, "Test")
-	})
+	tracef("found %d types, %d funcs, %d consts and %d vars in %s", len(docPkg.Types), len(docPkg.Funcs), len(docPkg.Consts), len(docPkg.Vars), pkgName)
+	if exclusion != nil {
+		exclusion.filter(docPkg)
+	}
+
+	result := NewPkg(docPkg, src)
+	if len(tests) > 0 {
+		attachExamples(result, doc.Examples(tests...), fset)
+	}
+
+	if *withDocScore {
+		scoreDocs(result)
+	}
+
+	if *withPkgsiteURLs {
+		setPkgsiteURLs(result, "")
+	}
+
+	if len(enabledLinters) > 0 {
+		result.Lint = lintPackage(result)
+	}
+	return result, nil
+}
 
-	bytes, err := json.MarshalIndent(NewPkg(docPkg, fset), "", "\t")
+// extractStdin builds the documentation of a single Go file read from the
+// standard input.
+func extractStdin() (*Pkg, error) {
+	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	fmt.Println(string(bytes))
+	return extractFiles(map[string][]byte{stdinFilename: data})
 }
 
-func parsePackage(pkgName string, fset *token.FileSet) (*ast.Package, error) {
-	srcDir, err := parseutil.DefaultGoPath.Abs(pkgName)
+// extractFile builds the documentation of a single Go file as if it was a
+// package on its own, without an import path.
+func extractFile(path string) (*Pkg, error) {
+	path, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
 
-	pkgs, err := parser.ParseDir(fset, srcDir, func(fi os.FileInfo) bool {
-		return !strings.HasSuffix(fi.Name(), "_test.go")
-	}, parser.ParseComments)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var pkg *ast.Package
-	for name, p := range pkgs {
-		if !strings.HasSuffix(name, "_test") {
-			pkg = p
+	return extractFiles(map[string][]byte{path: data})
+}
+
+// extractFiles builds the documentation of the package made of the given
+// files, indexed by name, which are never read from disk. Test files are
+// ignored.
+func extractFiles(sources map[string][]byte) (*Pkg, error) {
+	var names []string
+	for name := range sources {
+		if !strings.HasSuffix(name, "_test.go") {
+			names = append(names, name)
 		}
 	}
+	sort.Strings(names)
+
+	var (
+		fset      = token.NewFileSet()
+		pkg       *ast.Package
+		hashes    = make(map[string]string)
+		errs      []*ParseError
+		lastError error
+	)
+
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, sources[name], parser.ParseComments)
+		if err != nil {
+			list, ok := newParseErrors(err)
+			if !ok || *strict {
+				return nil, err
+			}
+
+			errs = append(errs, list...)
+			lastError = err
+			continue
+		}
+
+		if pkg == nil {
+			pkg = &ast.Package{Name: f.Name.Name, Files: make(map[string]*ast.File)}
+		} else if pkg.Name != f.Name.Name {
+			return nil, fmt.Errorf("found packages %s and %s", pkg.Name, f.Name.Name)
+		}
+		pkg.Files[name] = f
+		hashes[name] = sourceHash(sources[name])
+	}
+
+	if pkg == nil && lastError != nil {
+		return nil, lastError
+	}
 
 	if pkg == nil {
+		return nil, errors.New("no Go files given")
+	}
+
+	// There is no import path for files that do not live in a package
+	// directory.
+	result, err := buildPkg("", fset, "", pkg, nil, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ParseErrors = errs
+	result.Hash = documentHash(result)
+	return result, nil
+}
+
+// addGitInfo sets the git revision of the package if requested.
+func addGitInfo(pkg *Pkg, srcDir string) {
+	if *withGit {
+		pkg.Git = gitInfo(srcDir)
+	}
+}
+
+// sourceFiles returns the names of the files in the given directory that
+// will be parsed.
+func sourceFiles(srcDir string) ([]string, error) {
+	return goFiles(srcDir, false)
+}
+
+// testFiles returns the names of the test files in the given directory
+// that match the build constraints.
+func testFiles(srcDir string) ([]string, error) {
+	return goFiles(srcDir, true)
+}
+
+func goFiles(srcDir string, tests bool) ([]string, error) {
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+
+		if strings.HasSuffix(name, "_test.go") != tests {
+			if !tests {
+				debugf("skipping test file %s", filepath.Join(srcDir, name))
+			}
+			continue
+		}
+
+		if ok, err := build.Default.MatchFile(srcDir, name); err != nil || !ok {
+			debugf("skipping %s: excluded by build constraints", filepath.Join(srcDir, name))
+			continue
+		}
+
+		if *skipGenerated && !tests && isGeneratedFile(filepath.Join(srcDir, name), nil) {
+			debugf("skipping generated file %s", filepath.Join(srcDir, name))
+			continue
+		}
+
+		files = append(files, name)
+	}
+
+	return files, nil
+}
+
+// parsePackage parses the given files of the directory and returns the
+// package they declare, along with the hashes of their contents by path and
+// the errors of the files that could not be parsed. It only fails on syntax
+// errors if none of the files could be parsed.
+func parsePackage(fset *token.FileSet, srcDir string, files []string) (*ast.Package, map[string]string, []*ParseError, error) {
+	var (
+		pkgs      = make(map[string]*ast.Package)
+		hashes    = make(map[string]string)
+		errs      []*ParseError
+		lastError error
+	)
+
+	for _, name := range files {
+		path := filepath.Join(srcDir, name)
+		debugf("parsing %s", path)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		f, err := parser.ParseFile(fset, path, data, parser.ParseComments)
+		if err != nil {
+			// Files with syntax errors are left out, so the rest of the
+			// package can still be documented.
+			list, ok := newParseErrors(err)
+			if !ok || *strict {
+				return nil, nil, nil, err
+			}
+
+			errorf("%s", err)
+			errs = append(errs, list...)
+			lastError = err
+			continue
+		}
+		hashes[path] = sourceHash(data)
+
+		p, ok := pkgs[f.Name.Name]
+		if !ok {
+			p = &ast.Package{Name: f.Name.Name, Files: make(map[string]*ast.File)}
+			pkgs[f.Name.Name] = p
+		}
+		p.Files[path] = f
+	}
+
+	if len(pkgs) == 0 && lastError != nil {
+		return nil, nil, nil, lastError
+	}
+
+	pkg, err := selectPackage(pkgs)
+	return pkg, hashes, errs, err
+}
+
+// selectPackage returns the package to document among those declared in
+// the files of a directory, ignoring external test packages. If there is
+// more than one, the one given with -package-name is chosen.
+func selectPackage(pkgs map[string]*ast.Package) (*ast.Package, error) {
+	var names []string
+	for name := range pkgs {
+		if strings.HasSuffix(name, "_test") {
+			tracef("ignoring external test package %s", name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	switch {
+	case len(names) == 0:
 		return nil, errors.New("no package found at given package name")
+	case len(names) == 1:
+		return pkgs[names[0]], nil
+	case *packageName == "":
+		return nil, fmt.Errorf("found packages %s, use -package-name to choose one", strings.Join(names, ", "))
+	}
+
+	if p, ok := pkgs[*packageName]; ok && !strings.HasSuffix(*packageName, "_test") {
+		return p, nil
+	}
+	return nil, fmt.Errorf("no package %s found, available packages: %s", *packageName, strings.Join(names, ", "))
+}
+
+// relPath returns the path of a file as it is output, relative to the root
+// given with -path-base, with its prefix rewritten as requested and, unless
+// native paths are requested, with forward slashes.
+func relPath(path string) string {
+	path = rewritePrefix(filepath.ToSlash(basePath(path)))
+	if *pathStyle == "native" {
+		return filepath.FromSlash(path)
+	}
+	return path
+}
+
+func basePath(path string) string {
+	switch *pathBase {
+	case "module":
+		if root := moduleRoot(filepath.Dir(path)); root != "" {
+			if rel, err := filepath.Rel(root, path); err == nil {
+				return rel
+			}
+		}
+		return path
+	case "absolute":
+		return path
+	default:
+		return removeGoPath(path)
+	}
+}
+
+var moduleRoots = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// moduleRoot returns the directory containing the go.mod file of the module
+// the given directory belongs to, or an empty string if there is none.
+func moduleRoot(dir string) string {
+	moduleRoots.Lock()
+	root, ok := moduleRoots.m[dir]
+	moduleRoots.Unlock()
+	if ok {
+		return root
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = moduleRoot(parent)
 	}
 
-	return pkg, nil
+	moduleRoots.Lock()
+	moduleRoots.m[dir] = root
+	moduleRoots.Unlock()
+	return root
 }
 
 func removeGoPath(path string) string {
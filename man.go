@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"strings"
+)
+
+// manWriter writes the documentation of every package as a man page of
+// section 3, in troff with the man macros, with a subsection for each of
+// its exported symbols.
+type manWriter struct {
+	w *bufio.Writer
+}
+
+func newManWriter(w io.Writer, list bool) packageWriter {
+	return &manWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *manWriter) Write(pkg *Pkg) error {
+	m := &manPage{parser: newDocParser(pkg, symbolNames(pkg))}
+
+	m.printf(".TH %s 3 \"\" %s \"Go Packages\"\n", manQuote(pkg.Name), manQuote("godocjson "+generatorVersion()))
+	m.printf(".SH NAME\n")
+	name := pkg.Name
+	if s := strings.TrimSuffix(synopsis(pkg), "."); s != "" {
+		name += " \\- " + manEscape(s)
+	}
+	m.printf("%s\n", name)
+
+	if pkg.ImportPath != "" {
+		m.printf(".SH SYNOPSIS\n")
+		m.code(fmt.Sprintf("import %q", pkg.ImportPath))
+	}
+
+	if text := packageDocText(pkg); strings.TrimSpace(text) != "" {
+		m.printf(".SH DESCRIPTION\n")
+		m.doc(text)
+	}
+
+	m.values("CONSTANTS", pkg.Consts)
+	m.values("VARIABLES", pkg.Vars)
+	if len(pkg.Funcs) > 0 {
+		m.printf(".SH FUNCTIONS\n")
+		m.funcs(pkg.Funcs, "")
+	}
+
+	if len(pkg.Types) > 0 {
+		m.printf(".SH TYPES\n")
+	}
+	for _, t := range pkg.Types {
+		m.printf(".SS %s\n", manQuote("type "+t.Name))
+		m.code(t.Decl)
+		m.doc(t.Doc)
+		m.values("", t.Consts)
+		m.values("", t.Vars)
+		m.funcs(t.Funcs, "")
+		m.funcs(t.Methods, t.Name+".")
+	}
+
+	_, err := io.WriteString(w.w, m.buf.String())
+	return err
+}
+
+func (w *manWriter) Close() error {
+	return w.w.Flush()
+}
+
+// manPage is the man page of a package being written.
+type manPage struct {
+	buf    strings.Builder
+	parser *comment.Parser
+}
+
+func (m *manPage) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&m.buf, format, args...)
+}
+
+// code writes a block of code, indented and without filling its lines.
+func (m *manPage) code(text string) {
+	m.printf(".PP\n.RS\n.nf\n")
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		m.printf("%s\n", manEscapeLine(line))
+	}
+	m.printf(".fi\n.RE\n")
+}
+
+func (m *manPage) values(title string, list []*Value) {
+	if len(list) > 0 && title != "" {
+		m.printf(".SH %s\n", title)
+	}
+
+	for _, v := range list {
+		m.code(v.Decl)
+		m.doc(v.Doc)
+	}
+}
+
+// funcs writes the functions, or methods if prefix is the name of their
+// type followed by a dot.
+func (m *manPage) funcs(list []*Func, prefix string) {
+	kind := "func "
+	if prefix != "" {
+		kind = "method "
+	}
+
+	for _, f := range list {
+		m.printf(".SS %s\n", manQuote(kind+prefix+f.Name))
+		m.code(f.Decl)
+		m.doc(f.Doc)
+	}
+}
+
+// doc writes a doc comment as paragraphs, with its headings in bold, code
+// blocks as code and lists indented.
+func (m *manPage) doc(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	for _, block := range m.parser.Parse(text).Content {
+		switch b := block.(type) {
+		case *comment.Heading:
+			m.printf(".PP\n.B %s\n", manQuote(manText(b.Text)))
+		case *comment.Paragraph:
+			m.printf(".PP\n%s\n", manEscapeLine(manText(b.Text)))
+		case *comment.Code:
+			m.code(b.Text)
+		case *comment.List:
+			for _, item := range b.Items {
+				bullet := `\(bu`
+				if item.Number != "" {
+					bullet = item.Number + "."
+				}
+
+				m.printf(".IP %s 4\n", bullet)
+				for j, c := range item.Content {
+					if p, ok := c.(*comment.Paragraph); ok {
+						if j > 0 {
+							m.printf(".IP\n")
+						}
+						m.printf("%s\n", manEscapeLine(manText(p.Text)))
+					}
+				}
+			}
+		}
+	}
+}
+
+// manText returns the plain text of a span of a doc comment, with the URLs
+// of links after their text.
+func manText(text []comment.Text) string {
+	var buf strings.Builder
+	for _, t := range text {
+		switch t := t.(type) {
+		case comment.Plain:
+			buf.WriteString(string(t))
+		case comment.Italic:
+			buf.WriteString(string(t))
+		case *comment.Link:
+			buf.WriteString(manText(t.Text))
+			if !t.Auto {
+				buf.WriteString(" <" + t.URL + ">")
+			}
+		case *comment.DocLink:
+			buf.WriteString(manText(t.Text))
+		}
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// manEscape escapes the backslashes of the text, which start troff escape
+// sequences.
+func manEscape(s string) string {
+	return strings.ReplaceAll(s, `\`, `\e`)
+}
+
+// manEscapeLine escapes a line of text so no part of it is taken as a
+// request, which start with a dot or an apostrophe.
+func manEscapeLine(s string) string {
+	s = manEscape(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// manQuote returns the text as a quoted argument of a macro.
+func manQuote(s string) string {
+	return `"` + strings.ReplaceAll(manEscape(s), `"`, `\(dq`) + `"`
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Corpus is the documentation of many packages merged into a single
+// document. Metadata shared by many packages, such as the licenses of a
+// module, is only stored once and referred to by its index.
+type Corpus struct {
+	// GeneratorVersion is the version of godocjson that generated the
+	// packages. It is only set, and removed from the packages, if they were
+	// all generated by the same version.
+	GeneratorVersion string `json:",omitempty"`
+	// Licenses are the distinct sets of licenses of the packages.
+	Licenses [][]*License
+	// Revisions are the distinct revisions the packages were generated at.
+	Revisions []*GitInfo
+	// Packages are all the packages, sorted by import path.
+	Packages []*CorpusPackage
+	// Symbols maps the names of all the symbols, with methods in the form
+	// "Type.Method", to the packages declaring them.
+	Symbols map[string][]*SymbolRef
+}
+
+// CorpusPackage is an entry of the package table of a corpus.
+type CorpusPackage struct {
+	ImportPath string
+	Name       string
+	// Licenses and Revision are indexes in the Licenses and Revisions of
+	// the corpus, or -1 if the package has none.
+	Licenses int
+	Revision int
+	// Doc is the documentation of the package, without the metadata moved
+	// to the corpus.
+	Doc *Pkg
+}
+
+// SymbolRef is a symbol declared in a package of the corpus.
+type SymbolRef struct {
+	// Package is the index of the package in the package table.
+	Package int
+	Kind    string
+}
+
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("o", "", "file to write the corpus to, instead of the standard output")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson merge [-o corpus.json] files...\n\n"+
+			"Merges documents generated by godocjson into a single indexed corpus.\n\n")
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var pkgs []*Pkg
+	for _, arg := range files {
+		paths := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			// For shells that do not expand globs.
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				fatalf("%s: %s", arg, err)
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			list, err := loadPackages(path)
+			if err != nil {
+				fatalf("unable to read %s: %s", path, err)
+			}
+			pkgs = append(pkgs, list...)
+		}
+	}
+
+	corpus := mergePackages(pkgs)
+	if *out == "" {
+		printJSON(corpus, nil)
+		return
+	}
+
+	data, err := json.MarshalIndent(corpus, "", "\t")
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+		fatalf("%s", err)
+	}
+	infof("merged %d packages into %s", len(corpus.Packages), *out)
+}
+
+// parseInterspersed parses the flags of the set, which, unlike with
+// fs.Parse, can come after the positional arguments, and returns the
+// positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// mergePackages builds the corpus of the given packages. If a package is
+// given more than once, the last one is kept.
+func mergePackages(pkgs []*Pkg) *Corpus {
+	var byPath = make(map[string]*Pkg)
+	for _, pkg := range pkgs {
+		if _, ok := byPath[pkg.ImportPath]; ok {
+			infof("package %s found more than once, keeping the last one", pkg.ImportPath)
+		}
+		byPath[pkg.ImportPath] = pkg
+	}
+
+	pkgs = make([]*Pkg, 0, len(byPath))
+	for _, pkg := range byPath {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].ImportPath < pkgs[j].ImportPath
+	})
+
+	c := &Corpus{
+		Licenses:  [][]*License{},
+		Revisions: []*GitInfo{},
+		Packages:  []*CorpusPackage{},
+		Symbols:   make(map[string][]*SymbolRef),
+	}
+
+	sameVersion := len(pkgs) > 0
+	for _, pkg := range pkgs {
+		sameVersion = sameVersion && pkg.GeneratorVersion == pkgs[0].GeneratorVersion
+	}
+	if sameVersion {
+		c.GeneratorVersion = pkgs[0].GeneratorVersion
+	}
+
+	for i, pkg := range pkgs {
+		// The package is modified, so copy it not to change the input.
+		doc := *pkg
+		entry := &CorpusPackage{
+			ImportPath: pkg.ImportPath,
+			Name:       pkg.Name,
+			Licenses:   -1,
+			Revision:   -1,
+			Doc:        &doc,
+		}
+
+		if len(pkg.Licenses) > 0 {
+			entry.Licenses = c.licenseSet(pkg.Licenses)
+			doc.Licenses = nil
+		}
+
+		if pkg.Git != nil {
+			entry.Revision = c.revision(pkg.Git)
+			doc.Git = nil
+		}
+
+		if sameVersion {
+			doc.GeneratorVersion = ""
+		}
+
+		for _, sym := range packageSymbols(pkg) {
+			c.Symbols[sym.Name] = append(c.Symbols[sym.Name], &SymbolRef{Package: i, Kind: sym.Kind})
+		}
+		c.Packages = append(c.Packages, entry)
+	}
+
+	return c
+}
+
+// packages returns the packages of the corpus as they were before being
+// merged, with their metadata back in them.
+func (c *Corpus) packages() []*Pkg {
+	var pkgs = make([]*Pkg, len(c.Packages))
+	for i, entry := range c.Packages {
+		pkg := *entry.Doc
+		if entry.Licenses >= 0 && entry.Licenses < len(c.Licenses) {
+			pkg.Licenses = c.Licenses[entry.Licenses]
+		}
+
+		if entry.Revision >= 0 && entry.Revision < len(c.Revisions) {
+			pkg.Git = c.Revisions[entry.Revision]
+		}
+
+		if pkg.GeneratorVersion == "" {
+			pkg.GeneratorVersion = c.GeneratorVersion
+		}
+		pkgs[i] = &pkg
+	}
+	return pkgs
+}
+
+func (c *Corpus) licenseSet(licenses []*License) int {
+	for i, set := range c.Licenses {
+		if reflect.DeepEqual(set, licenses) {
+			return i
+		}
+	}
+
+	c.Licenses = append(c.Licenses, licenses)
+	return len(c.Licenses) - 1
+}
+
+func (c *Corpus) revision(git *GitInfo) int {
+	for i, rev := range c.Revisions {
+		if *rev == *git {
+			return i
+		}
+	}
+
+	c.Revisions = append(c.Revisions, git)
+	return len(c.Revisions) - 1
+}
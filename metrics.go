@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/token"
+)
+
+var withMetrics = flag.Bool("metrics", false, "emit size and complexity metrics for every function")
+
+// NewMetrics computes the metrics of the given function, whose body can
+// be nil.
+func NewMetrics(decl *ast.FuncDecl, body *ast.BlockStmt, fset *token.FileSet) *Metrics {
+	m := &Metrics{Complexity: 1}
+	end := decl.End()
+	if body != nil {
+		end = body.End()
+	}
+	m.Lines = fset.Position(end).Line - fset.Position(decl.Pos()).Line + 1
+
+	if body == nil {
+		return m
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt, *ast.EmptyStmt:
+			return true
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			m.Complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				m.Complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				m.Complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				m.Complexity++
+			}
+		}
+
+		if _, ok := n.(ast.Stmt); ok {
+			m.Statements++
+		}
+		return true
+	})
+
+	return m
+}
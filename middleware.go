@@ -0,0 +1,292 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// corsHandler adds the CORS headers to the responses of a handler so it can
+// be called from the pages of the allowed origins, and answers preflight
+// requests itself.
+type corsHandler struct {
+	next http.Handler
+	// origins are the allowed origins, or "*" for any.
+	origins map[string]bool
+}
+
+// newCORSHandler returns a handler allowing the given comma-separated
+// origins, or the handler itself if there are none.
+func newCORSHandler(next http.Handler, origins string) http.Handler {
+	if origins == "" {
+		return next
+	}
+
+	return &corsHandler{next: next, origins: parseOrigins(origins)}
+}
+
+// parseOrigins returns the set of the given comma-separated origins.
+func parseOrigins(origins string) map[string]bool {
+	var set = make(map[string]bool)
+	for _, o := range strings.Split(origins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			set[o] = true
+		}
+	}
+	return set
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	w.Header().Add("Vary", "Origin")
+	if origin != "" && (h.origins["*"] || h.origins[origin]) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
+	}
+
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		if w.Header().Get("Access-Control-Allow-Origin") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-None-Match, If-Modified-Since")
+			w.Header().Set("Access-Control-Max-Age", "600")
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
+
+// newTimeoutHandler returns a handler cancelling the context of the
+// requests taking longer than the timeout, which are answered with a 503
+// Service Unavailable, or the handler itself if there is no timeout.
+func newTimeoutHandler(next http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+	return http.TimeoutHandler(next, timeout, "request timed out")
+}
+
+// rateLimiter rejects the requests over the rate allowed, either for all the
+// clients together or for each of them, with a 429 Too Many Requests
+// telling when to retry. Clients are told apart by their IP address and,
+// if they have one, their bearer token, as all of them may share the same.
+type rateLimiter struct {
+	global *tokenBucket
+	// rate is the rate allowed to each client, if limited.
+	rate    float64
+	mu      sync.Mutex
+	clients map[string]*tokenBucket
+}
+
+// maxRateLimitClients is the number of clients above which those that have
+// been idle long enough to be allowed their whole burst again are
+// forgotten.
+const maxRateLimitClients = 10000
+
+// newRateLimiter returns a limiter allowing the given requests per second
+// in total and per client, where 0 means no limit, or nil if neither is
+// limited.
+func newRateLimiter(global, perClient float64) *rateLimiter {
+	if global <= 0 && perClient <= 0 {
+		return nil
+	}
+
+	l := &rateLimiter{rate: perClient, clients: make(map[string]*tokenBucket)}
+	if global > 0 {
+		l.global = newTokenBucket(global, time.Now())
+	}
+	return l
+}
+
+// handler returns a handler limiting the requests to the given one, which
+// count towards the same limits as those of the rest of handlers of the
+// limiter. A nil limiter returns the handler itself.
+func (l *rateLimiter) handler(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait := l.take(rateLimitClient(r)); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take returns 0 if the client can make a request now, or how long it has
+// to wait otherwise. The limit of the client is checked first, and the
+// request only counts towards the global limit if it is allowed, so clients
+// over their limit do not use up that of the rest.
+func (l *rateLimiter) take(client string) time.Duration {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var b *tokenBucket
+	if l.rate > 0 {
+		var ok bool
+		if b, ok = l.clients[client]; !ok {
+			if len(l.clients) >= maxRateLimitClients {
+				l.forgetIdle(now)
+			}
+
+			b = newTokenBucket(l.rate, now)
+			l.clients[client] = b
+		}
+	}
+
+	if wait := b.wait(now); wait > 0 {
+		return wait
+	}
+
+	if wait := l.global.wait(now); wait > 0 {
+		return wait
+	}
+
+	b.take()
+	l.global.take()
+	return 0
+}
+
+func (l *rateLimiter) forgetIdle(now time.Time) {
+	for client, b := range l.clients {
+		if b.refill(now) >= b.burst {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// rateLimitClient returns the key of the client of the request.
+func rateLimitClient(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if token, ok := requestToken(r); ok {
+		return "ip " + host + " token " + token
+	}
+	return "ip " + host
+}
+
+// tokenBucket allows bursts of requests as large as the rate per second,
+// refilled at that rate.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	burst := math.Max(1, math.Ceil(rate))
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// refill adds the tokens accumulated since the last time and returns how
+// many there are.
+func (b *tokenBucket) refill(now time.Time) float64 {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	return b.tokens
+}
+
+// wait returns 0 if there is a token to take, or how long it takes for the
+// next one otherwise. A nil bucket has no limit.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if b == nil || b.refill(now) >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// take takes a token, which there must be according to wait.
+func (b *tokenBucket) take() {
+	if b != nil {
+		b.tokens--
+	}
+}
+
+// authHandler only lets requests with the given bearer token through.
+type authHandler struct {
+	next  http.Handler
+	token []byte
+}
+
+// newAuthHandler returns a handler requiring the given token, or the
+// handler itself if there is none.
+func newAuthHandler(next http.Handler, token string) http.Handler {
+	if token == "" {
+		return next
+	}
+	return &authHandler{next: next, token: []byte(token)}
+}
+
+// wsTokenProtocol is the prefix of the WebSocket subprotocol carrying the
+// token of a client, as browsers cannot set the headers of the handshake.
+const wsTokenProtocol = "bearer."
+
+// requestToken returns the token of a request, given in its Authorization
+// header or, for WebSocket handshakes, either as a subprotocol prefixed with
+// wsTokenProtocol or in the access_token query parameter.
+func requestToken(r *http.Request) (string, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token), true
+	}
+
+	if !headerContains(r.Header, "Upgrade", "websocket") {
+		return "", false
+	}
+
+	for _, p := range webSocketProtocols(r) {
+		if token, ok := strings.CutPrefix(p, wsTokenProtocol); ok {
+			return token, true
+		}
+	}
+
+	if token := r.URL.Query().Get("access_token"); token != "" {
+		return token, true
+	}
+	return "", false
+}
+
+// readToken reads the token in the given file, if any.
+func readToken(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("no token found in %s", file)
+	}
+	return token, nil
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := requestToken(r)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), h.token) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="godocjson"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "go/doc"
+
+// NewNotes returns the notes of the package by their marker, with the
+// positions go/doc gives them resolved.
+func NewNotes(notes map[string][]*doc.Note, src *Source) map[string][]*Note {
+	var result = make(map[string][]*Note, len(notes))
+	for marker, list := range notes {
+		result[marker] = make([]*Note, len(list))
+		for i, n := range list {
+			result[marker][i] = &Note{
+				UID:  n.UID,
+				Body: n.Body,
+				Pos: &Pos{
+					Start: NewFilePos(n.Pos, src.Fset),
+					End:   NewFilePos(n.End, src.Fset),
+				},
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"go/doc"
+	"go/parser"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var openAPIFile = flag.String("openapi", "", "also write an OpenAPI 3 document of the HTTP routes annotated in the doc comments of handlers, such as GET /users/{id}, to the given file")
+
+var (
+	// routeRegexp matches the lines of doc comments annotating the route
+	// of a handler, with the syntax of the patterns of http.ServeMux.
+	routeRegexp = regexp.MustCompile(`^(GET|HEAD|POST|PUT|PATCH|DELETE|OPTIONS|TRACE)\s+(/\S*)$`)
+	// requestRegexp and responseRegexp match the lines giving the types of
+	// the body of the requests and responses of a handler, such as
+	// "Request: CreateUser" or "Response 201: User".
+	requestRegexp  = regexp.MustCompile(`^Request:\s*(\S+)$`)
+	responseRegexp = regexp.MustCompile(`^Response(?:\s+([1-5][0-9][0-9]))?:\s*(\S+)$`)
+	// pathParamRegexp matches the wildcards of routes, including those
+	// matching the rest of the path, such as {path...}.
+	pathParamRegexp = regexp.MustCompile(`\{(\w+)(\.\.\.)?\}`)
+
+	blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+// withOpenAPI returns an extract function that also writes the OpenAPI
+// document of all the packages passed by the given one to its emit
+// function to path, once they all are.
+func withOpenAPI(extract func(emit func(*Pkg) error) error, path string) func(emit func(*Pkg) error) error {
+	return func(emit func(*Pkg) error) error {
+		api := newOpenAPI()
+		err := extract(func(pkg *Pkg) error {
+			api.add(pkg)
+			return emit(pkg)
+		})
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(api.doc, "", "\t")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, append(data, '\n'))
+	}
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]jsonSchema `json:"schemas,omitempty"`
+	} `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string     `json:"name"`
+	In       string     `json:"in"`
+	Required bool       `json:"required"`
+	Schema   jsonSchema `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema jsonSchema `json:"schema"`
+}
+
+// openAPI builds an OpenAPI document from the handlers of packages, the
+// functions and methods whose doc comments have lines with the routes they
+// serve, such as "GET /users/{id}", and optionally the types of the JSON
+// bodies of their requests and responses, such as "Request: NewUser" and
+// "Response 201: User". The types must be declared in the package of the
+// handler, and become the schemas of the document, generated as those of
+// the jsonschema format, with references to the schemas of the document.
+type openAPI struct {
+	doc *openAPIDoc
+	// generators are the generators of the schemas of the types of each
+	// package, by its import path.
+	generators map[string]*schemaGenerator
+	// schemas are the names of the schemas of the types of each package,
+	// by the import path and name of the type.
+	schemas map[string]string
+	ids     map[string]int
+}
+
+func newOpenAPI() *openAPI {
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Version: "0.0.0"},
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	doc.Components.Schemas = make(map[string]jsonSchema)
+	return &openAPI{
+		doc:        doc,
+		generators: make(map[string]*schemaGenerator),
+		schemas:    make(map[string]string),
+		ids:        make(map[string]int),
+	}
+}
+
+// add adds the routes of the handlers of a package to the document. The
+// document is named after the first package with routes, and gets its
+// version from its git tag, if any.
+func (a *openAPI) add(pkg *Pkg) {
+	for _, sym := range packageSymbols(pkg) {
+		if sym.Func == nil {
+			continue
+		}
+
+		h := parseHandlerDoc(sym.Doc)
+		if len(h.routes) == 0 {
+			continue
+		}
+
+		if a.doc.Info.Title == "" {
+			a.doc.Info.Title = pkg.ImportPath
+			if pkg.Git != nil && pkg.Git.Tag != "" {
+				a.doc.Info.Version = pkg.Git.Tag
+			}
+		}
+
+		for _, r := range h.routes {
+			a.addOperation(pkg, sym, h, r)
+		}
+	}
+}
+
+func (a *openAPI) addOperation(pkg *Pkg, sym *Symbol, h *handlerDoc, r route) {
+	op := &openAPIOperation{
+		OperationID: a.operationID(sym.Name),
+		Summary:     new(doc.Package).Synopsis(h.description),
+		Description: h.description,
+		Tags:        []string{pkg.Name},
+		Deprecated:  sym.Func.Deprecated != "",
+		Responses:   make(map[string]*openAPIResponse),
+	}
+
+	// {$} only matches the end of the path, which is where paths of
+	// OpenAPI always end.
+	path := pathParamRegexp.ReplaceAllString(strings.TrimSuffix(r.path, "{$}"), "{$1}")
+	for _, m := range pathParamRegexp.FindAllStringSubmatch(r.path, -1) {
+		op.Parameters = append(op.Parameters, &openAPIParameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   jsonSchema{"type": "string"},
+		})
+	}
+
+	if h.request != "" {
+		op.RequestBody = &openAPIBody{
+			Required: true,
+			Content:  jsonContent(a.schemaOf(pkg, h.request)),
+		}
+	}
+
+	for _, resp := range h.responses {
+		code, _ := strconv.Atoi(resp.status)
+		op.Responses[resp.status] = &openAPIResponse{
+			Description: http.StatusText(code),
+			Content:     jsonContent(a.schemaOf(pkg, resp.typ)),
+		}
+	}
+
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &openAPIResponse{Description: http.StatusText(http.StatusOK)}
+	}
+
+	if a.doc.Paths[path] == nil {
+		a.doc.Paths[path] = make(map[string]*openAPIOperation)
+	}
+	a.doc.Paths[path][strings.ToLower(r.method)] = op
+}
+
+// operationID returns the name of the handler, which must be unique, so
+// those of handlers with many routes are numbered after the first.
+func (a *openAPI) operationID(name string) string {
+	a.ids[name]++
+	if n := a.ids[name]; n > 1 {
+		return name + "_" + strconv.Itoa(n)
+	}
+	return name
+}
+
+func jsonContent(schema jsonSchema) map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+// schemaOf returns the schema of the values of the given type, as written
+// in the documentation of a package. Types of the package are referenced
+// as schemas of the document, which are added the first time they are,
+// and types that cannot be represented, such as interfaces or types of
+// other packages, accept any value.
+func (a *openAPI) schemaOf(pkg *Pkg, typ string) jsonSchema {
+	// Types are fully-qualified if they are resolved.
+	typ = strings.ReplaceAll(typ, pkg.ImportPath+".", "")
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return jsonSchema{}
+	}
+
+	g, ok := a.generators[pkg.ImportPath]
+	if !ok {
+		g = newSchemaGenerator(pkg, func(name string) string {
+			return "#/components/schemas/" + a.schemaName(pkg, name)
+		})
+		a.generators[pkg.ImportPath] = g
+	}
+
+	schema := g.schema(expr)
+	for name, def := range g.defs {
+		a.doc.Components.Schemas[a.schemaName(pkg, name)] = def
+	}
+
+	if schema == nil {
+		return jsonSchema{}
+	}
+	return schema
+}
+
+// schemaName returns the name of the schema of a type of the package,
+// choosing it the first time. Types with the same name in other packages
+// are qualified by the name of their package.
+func (a *openAPI) schemaName(pkg *Pkg, name string) string {
+	key := pkg.ImportPath + "." + name
+	if schemaName, ok := a.schemas[key]; ok {
+		return schemaName
+	}
+
+	schemaName := name
+	if _, taken := a.doc.Components.Schemas[schemaName]; taken {
+		schemaName = pkg.Name + "." + name
+	}
+
+	a.schemas[key] = schemaName
+	// The name is reserved before the schema is built, as it may refer to
+	// itself.
+	a.doc.Components.Schemas[schemaName] = jsonSchema{}
+	return schemaName
+}
+
+// handlerDoc are the annotations of the doc comment of a handler, and the
+// rest of the comment as the description of its operations.
+type handlerDoc struct {
+	routes      []route
+	request     string
+	responses   []response
+	description string
+}
+
+type route struct {
+	method, path string
+}
+
+type response struct {
+	status, typ string
+}
+
+func parseHandlerDoc(text string) *handlerDoc {
+	var (
+		h    handlerDoc
+		rest []string
+	)
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := routeRegexp.FindStringSubmatch(trimmed); m != nil {
+			h.routes = append(h.routes, route{m[1], m[2]})
+		} else if m := requestRegexp.FindStringSubmatch(trimmed); m != nil {
+			h.request = m[1]
+		} else if m := responseRegexp.FindStringSubmatch(trimmed); m != nil {
+			status := m[1]
+			if status == "" {
+				status = "200"
+			}
+			h.responses = append(h.responses, response{status, m[2]})
+		} else {
+			rest = append(rest, line)
+		}
+	}
+
+	// The annotations may leave several blank lines between paragraphs.
+	h.description = strings.TrimSpace(blankLinesRegexp.ReplaceAllString(strings.Join(rest, "\n"), "\n\n"))
+	return &h
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/doc"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	outDir          = flag.String("outdir", "", "write each package to <outdir>/<import/path>.json, or the extension of the format, instead of the standard output")
+	withSymbolIndex = flag.Bool("symbol-index", false, "include in the index of -outdir the exported symbols of all the packages, to link them across packages")
+)
+
+// formatExtensions are the extensions of the files written with -outdir
+// for the formats that are not JSON.
+var formatExtensions = map[string]string{
+	"apisummary": ".txt",
+	"cbor":       ".cbor",
+	"csv":        ".csv",
+	"dot":        ".dot",
+	"esbulk":     ".ndjson",
+	"gob":        ".gob",
+	"jekyll":     ".md",
+	"man":        ".3",
+	"parquet":    ".parquet",
+	"sarif":      ".sarif",
+	"text":       ".txt",
+	"typesense":  ".jsonl",
+	"xml":        ".xml",
+}
+
+// indexFile is the name of the index written along with the packages.
+const indexFile = "index.json"
+
+// outDirWriter writes every package to its own file in a directory, named
+// after its import path, along with an index of all of them.
+type outDirWriter struct {
+	dir   string
+	index *Index
+}
+
+func newOutDirWriter(dir string) *outDirWriter {
+	return &outDirWriter{
+		dir:   dir,
+		index: &Index{GeneratorVersion: generatorVersion(), Packages: []*IndexEntry{}},
+	}
+}
+
+func (w *outDirWriter) Write(pkg *Pkg) error {
+	name := pkg.ImportPath
+	if name == "" {
+		name = pkg.Name
+	}
+
+	ext, ok := formatExtensions[*format]
+	if !ok {
+		ext = ".json"
+	}
+
+	// Packages such as that of a module named "index" would
+	// overwrite the files of the directory itself.
+	if file := name + ext; file == indexFile || file == manifestFile {
+		return fmt.Errorf("%s: cannot be written to %s, which is the file of the %s", name, file, strings.TrimSuffix(file, ".json"))
+	}
+
+	var buf bytes.Buffer
+	pw := newPackageWriter(&buf, false)
+	if err := pw.Write(pkg); err != nil {
+		return err
+	}
+
+	if err := pw.Close(); err != nil {
+		return err
+	}
+
+	w.index.Packages = append(w.index.Packages, &IndexEntry{
+		ImportPath: pkg.ImportPath,
+		Name:       pkg.Name,
+		Synopsis:   synopsis(pkg),
+		File:       name + ext,
+		Hash:       sourceHash(buf.Bytes()),
+	})
+
+	if *withSymbolIndex {
+		w.indexSymbols(pkg, name+ext)
+	}
+	return w.writeFile(filepath.FromSlash(name)+ext, buf.Bytes())
+}
+
+// indexSymbols adds the exported symbols of the package, documented in the
+// given file, to the index.
+func (w *outDirWriter) indexSymbols(pkg *Pkg, file string) {
+	if w.index.Symbols == nil {
+		w.index.Symbols = make(map[string]*IndexSymbol)
+	}
+
+	for _, sym := range packageSymbols(pkg) {
+		// Methods of unexported types are not exported either.
+		typ, method, isMethod := strings.Cut(sym.Name, ".")
+		if !token.IsExported(typ) || (isMethod && !token.IsExported(method)) {
+			continue
+		}
+
+		w.index.Symbols[pkg.ImportPath+"."+sym.Name] = &IndexSymbol{
+			ImportPath: pkg.ImportPath,
+			Name:       sym.Name,
+			Kind:       sym.Kind,
+			File:       file,
+		}
+	}
+}
+
+// writeFile writes a file of the directory. Files that did not change are
+// not written again, so their modification time can still be relied on.
+func (w *outDirWriter) writeFile(name string, data []byte) error {
+	path := filepath.Join(w.dir, name)
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		debugf("%s did not change", path)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	debugf("writing %s", path)
+	return os.WriteFile(path, data, 0644)
+}
+
+func (w *outDirWriter) Close() error {
+	if *incremental {
+		w.carryOver()
+	}
+
+	data, err := json.MarshalIndent(outputValue(w.index), "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := w.writeFile(indexFile, append(data, '\n')); err != nil {
+		return err
+	}
+
+	infof("wrote %d package(s) to %s", len(w.index.Packages), w.dir)
+	return nil
+}
+
+// synopsis returns the first sentence of the package comment.
+func synopsis(pkg *Pkg) string {
+	return new(doc.Package).Synopsis(packageDocText(pkg))
+}
+
+// packageDocText returns the package comment, which is taken from its files
+// if the documentation of the package has none.
+func packageDocText(pkg *Pkg) string {
+	text := pkg.Doc
+	for _, f := range pkg.Files {
+		if text != "" {
+			break
+		}
+		text = f.Doc
+	}
+	return text
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"sort"
+	"strings"
+)
+
+var format = flag.String("format", "json", "output format: "+strings.Join(formatNames(), ", "))
+
+// packageWriter writes the documentation of packages in some output format,
+// one at a time as they are extracted.
+type packageWriter interface {
+	Write(*Pkg) error
+	// Close finishes the output.
+	Close() error
+}
+
+// formats are the available output formats. If list is true, many packages
+// may be written, otherwise only one.
+var formats = map[string]func(w io.Writer, list bool) packageWriter{
+	"json": func(w io.Writer, list bool) packageWriter {
+		return newJSONWriter(w, list)
+	},
+	"apisummary":  newAPISummaryWriter,
+	"cbor":        newCBORWriter,
+	"csv":         newCSVWriter,
+	"dot":         newDOTWriter,
+	"esbulk":      newESBulkWriter,
+	"gob":         newGobWriter,
+	"jekyll":      newJekyllWriter,
+	"jsonschema":  newJSONSchemaWriter,
+	"man":         newManWriter,
+	"meilisearch": newMeilisearchWriter,
+	"parquet":     newParquetWriter,
+	"sarif":       newSARIFWriter,
+	"text":        newTextWriter,
+	"xml":         newXMLWriter,
+	"typesense":   newTypesenseWriter,
+}
+
+func formatNames() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newPackageWriter returns a writer for the format given with -format.
+func newPackageWriter(w io.Writer, list bool) packageWriter {
+	if *execPlugin != "" {
+		return &pluginWriter{newJSONWriter(w, list)}
+	}
+	return formats[*format](w, list)
+}
+
+// jsonWriter writes packages as JSON as they are extracted, so the whole
+// output never needs to be held in memory at once.
+type jsonWriter struct {
+	w    *bufio.Writer
+	list bool
+	n    int
+}
+
+// newJSONWriter returns a writer of packages to w. If list is true, the
+// packages are written as a JSON array, otherwise a single package object
+// is expected.
+func newJSONWriter(w io.Writer, list bool) *jsonWriter {
+	return &jsonWriter{w: bufio.NewWriter(w), list: list}
+}
+
+func (w *jsonWriter) Write(pkg *Pkg) error {
+	return w.WriteValue(pkg)
+}
+
+// WriteValue writes any value in place of a package.
+func (w *jsonWriter) WriteValue(v interface{}) error {
+	v = outputValue(v)
+	if !w.list {
+		enc := json.NewEncoder(w.w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(v)
+	}
+
+	sep := ",\n\t"
+	if w.n == 0 {
+		sep = "[\n\t"
+	}
+	w.n++
+
+	if _, err := w.w.WriteString(sep); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "\t", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.w.Write(data)
+	return err
+}
+
+// Close finishes the output and flushes it.
+func (w *jsonWriter) Close() error {
+	if w.list {
+		end := "\n]\n"
+		if w.n == 0 {
+			end = "[]\n"
+		}
+
+		if _, err := w.w.WriteString(end); err != nil {
+			return err
+		}
+	}
+
+	return w.w.Flush()
+}
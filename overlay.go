@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var docOverlay = flag.String("doc-overlay", "", "directory of JSON files named after a locale, e.g. es.json, mapping the IDs of packages and symbols, such as example.com/foo.Client.Do, to their translated documentation, added as their Translations")
+
+var overlayFiles struct {
+	sync.Once
+	// docs are the translations of every package and symbol, by their ID
+	// and locale.
+	docs map[string]map[string]string
+}
+
+// overlayDocs returns the translations in the overlay files of the
+// directory, by the ID of the package or symbol they document and their
+// locale, the name of their file.
+func overlayDocs(dir string) map[string]map[string]string {
+	overlayFiles.Do(func() {
+		overlayFiles.docs = make(map[string]map[string]string)
+		paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			fatalf("%s: %s", dir, err)
+		}
+
+		for _, p := range paths {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				fatalf("unable to read %s: %s", p, err)
+			}
+
+			var docs map[string]string
+			if err := json.Unmarshal(data, &docs); err != nil {
+				fatalf("unable to read %s: %s", p, err)
+			}
+
+			locale := strings.TrimSuffix(filepath.Base(p), ".json")
+			for id, text := range docs {
+				if overlayFiles.docs[id] == nil {
+					overlayFiles.docs[id] = make(map[string]string)
+				}
+				overlayFiles.docs[id][locale] = text
+			}
+		}
+	})
+	return overlayFiles.docs
+}
+
+// addTranslations sets the Translations of the package and its symbols
+// from the overlay files, if requested. Symbols are identified by their
+// name qualified with the import path of the package, as in the index of
+// -outdir, and groups of constants and variables by any of their names.
+func addTranslations(pkg *Pkg) {
+	if *docOverlay == "" {
+		return
+	}
+
+	docs := overlayDocs(*docOverlay)
+	pkg.Translations = docs[pkg.ImportPath]
+	for _, sym := range packageSymbols(pkg) {
+		translations := docs[pkg.ImportPath+"."+sym.Name]
+		if translations == nil {
+			continue
+		}
+
+		switch {
+		case sym.Type != nil:
+			sym.Type.Translations = translations
+		case sym.Func != nil:
+			sym.Func.Translations = translations
+		case sym.Value != nil && sym.Value.Translations == nil:
+			sym.Value.Translations = translations
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	parseutil "gopkg.in/src-d/go-parse-utils.v1"
+)
+
+// isPattern reports whether the given argument matches several packages.
+func isPattern(arg string) bool {
+	return arg == "..." || strings.HasSuffix(arg, "/...") || hasGlob(arg)
+}
+
+// isLocal reports whether the given argument is a directory in the file
+// system rather than an import path.
+func isLocal(arg string) bool {
+	return arg == "." || arg == ".." ||
+		strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") ||
+		filepath.IsAbs(arg)
+}
+
+// expandPatterns returns the import paths of all the packages matched by the
+// given arguments. Arguments can be import paths or directories, optionally
+// ending in "/..." to match all the packages below them, or shell patterns
+// of either.
+func expandPatterns(args []string) ([]string, error) {
+	var (
+		result []string
+		seen   = make(map[string]bool)
+	)
+
+	add := func(pkg string) {
+		if !seen[pkg] {
+			seen[pkg] = true
+			result = append(result, pkg)
+		}
+	}
+
+	for _, arg := range args {
+		if !isPattern(arg) {
+			pkg, err := importPath(arg)
+			if err != nil {
+				return nil, err
+			}
+			add(pkg)
+			continue
+		}
+
+		if hasGlob(arg) {
+			pkgs, err := expandGlob(arg)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(pkgs) == 0 {
+				warnf("%q matched no packages", arg)
+			}
+
+			for _, pkg := range pkgs {
+				add(pkg)
+			}
+			continue
+		}
+
+		base := strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "/")
+		if base == "" {
+			base = "."
+		}
+
+		dir, err := packageDir(base)
+		if err != nil {
+			return nil, err
+		}
+
+		pkgs, err := packagesBelow(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pkgs) == 0 {
+			warnf("%q matched no packages", arg)
+		}
+
+		for _, pkg := range pkgs {
+			add(pkg)
+		}
+	}
+
+	return result, nil
+}
+
+// isGoFile reports whether the given argument is a single Go file, which is
+// documented as a package of its own.
+func isGoFile(arg string) bool {
+	if !strings.HasSuffix(arg, ".go") {
+		return false
+	}
+
+	fi, err := os.Stat(arg)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// importPath returns the import path for the given argument, which may be
+// either an import path or a directory. Go files are returned as they are.
+func importPath(arg string) (string, error) {
+	if isGoFile(arg) || !isLocal(arg) {
+		return arg, nil
+	}
+
+	dir, err := filepath.Abs(arg)
+	if err != nil {
+		return "", err
+	}
+
+	return dirImportPath(dir)
+}
+
+// packageDir returns the directory for the given argument, which may be
+// either an import path or a directory.
+func packageDir(arg string) (string, error) {
+	if isLocal(arg) {
+		return filepath.Abs(arg)
+	}
+	return packageSrcDir(arg)
+}
+
+// dirImportPath returns the import path of the package in the given
+// directory: the path of the module it is in, declared in its go.mod file,
+// followed by the directory relative to the module, or, if it is not in a
+// module, its path relative to GOPATH. The module is remembered, so its
+// packages can be found later by import path.
+func dirImportPath(dir string) (string, error) {
+	if root := moduleRoot(dir); root != "" {
+		if mod := modulePath(root); mod != "" {
+			addModule(mod, root)
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return "", err
+			}
+
+			if rel == "." {
+				return mod, nil
+			}
+			return mod + "/" + filepath.ToSlash(rel), nil
+		}
+	}
+
+	if pkg, ok := gopathImportPath(dir); ok {
+		return pkg, nil
+	}
+	return "", fmt.Errorf("directory %s is neither in a module nor in GOPATH", dir)
+}
+
+// modules are the roots of the modules packages were found in, by module
+// path.
+var modules = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// addModule remembers the root of a module, and of the modules it replaces
+// with local directories in its go.mod file, so their packages are found
+// in them, as the go command does.
+func addModule(path, root string) {
+	modules.Lock()
+	defer modules.Unlock()
+
+	_, known := modules.m[path]
+	modules.m[path] = root
+	if known {
+		return
+	}
+
+	mod, err := readGoMod(root)
+	if err != nil {
+		return
+	}
+
+	for _, r := range mod.Replaces {
+		if dir := r.dir(root); dir != "" {
+			if _, ok := modules.m[r.Path]; !ok {
+				debugf("module %s is replaced with %s", r.Path, dir)
+				modules.m[r.Path] = dir
+			}
+		}
+	}
+}
+
+// packageSrcDir returns the directory of the package with the given import
+// path, which is either in one of the modules packages were found in by
+// directory, the module of the working directory, GOROOT or GOPATH.
+func packageSrcDir(pkg string) (string, error) {
+	if wd, err := os.Getwd(); err == nil {
+		if root := moduleRoot(wd); root != "" {
+			if mod := modulePath(root); mod != "" {
+				addModule(mod, root)
+			}
+		}
+	}
+
+	modules.Lock()
+	var mod, root string
+	for path, dir := range modules.m {
+		if (pkg == path || strings.HasPrefix(pkg, path+"/")) && len(path) > len(mod) {
+			mod, root = path, dir
+		}
+	}
+	modules.Unlock()
+
+	if mod != "" {
+		dir := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(pkg, mod)))
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir, nil
+		}
+	}
+
+	if dir, ok := stdlibSrcDir(pkg); ok {
+		return dir, nil
+	}
+	return parseutil.DefaultGoPath.Abs(pkg)
+}
+
+// gopathImportPath returns the import path of the package in the given
+// directory if it is inside GOPATH.
+func gopathImportPath(dir string) (string, bool) {
+	for _, p := range parseutil.DefaultGoPath {
+		src := filepath.Join(p, "src")
+		rel, err := filepath.Rel(src, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return filepath.ToSlash(rel), true
+	}
+	return "", false
+}
+
+// packagesBelow returns the import paths of all the packages in the given
+// directory or any of its subdirectories.
+func packagesBelow(root string) ([]string, error) {
+	dirs, err := packageDirsBelow(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []string
+	for _, dir := range dirs {
+		pkg, err := dirImportPath(dir)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	sort.Strings(pkgs)
+	return pkgs, nil
+}
+
+// packageDirsBelow returns the directories with packages in the given one
+// or any of its subdirectories. As the go tool does, vendor and testdata
+// directories, as well as those starting with "." or "_", are ignored, and
+// so are subdirectories with a go.mod file, as they are other modules.
+func packageDirsBelow(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() {
+			return nil
+		}
+
+		name := fi.Name()
+		if path != root && (name == "vendor" || name == "testdata" ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+
+		if path != root {
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		if hasGoFiles(path) {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// hasGoFiles reports whether the given directory contains any non-test Go
+// file matching the current build constraints, which is not generated if
+// generated files are skipped.
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		if ok, err := build.Default.MatchFile(dir, name); err == nil && ok {
+			if !*skipGenerated || !isGeneratedFile(filepath.Join(dir, name), nil) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
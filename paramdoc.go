@@ -0,0 +1,106 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// listItemRegexp matches the items of lists describing parameters,
+	// such as "  - ctx: the context of the request", optionally without
+	// the marker of the item or separated by a dash.
+	listItemRegexp = regexp.MustCompile(`^(\s*)(?:[-*+•]\s+|\d+[.)]\s+)?` + "`?" + `(\w+)` + "`?" + `\s*(?::|\s-|\s–|\s—)\s+(\S.*)$`)
+	// paramSentenceRegexp matches the sentences mentioning a parameter as
+	// "the name parameter" or "the name argument".
+	paramSentenceRegexp = regexp.MustCompile(`\b[Tt]he ` + "`?" + `(\w+)` + "`?" + ` (?:parameter|argument)s?\b`)
+)
+
+// describeParams sets the Doc of the named parameters described in the doc
+// comment of their function, either in a list with an item for each of
+// them, such as "name: description", or in a sentence mentioning "the name
+// parameter", which is its description. Items take precedence over
+// sentences.
+func describeParams(text string, params []*Field) {
+	var byName = make(map[string]*Field)
+	for _, p := range params {
+		if p.Name != "" && p.Name != "_" {
+			byName[p.Name] = p
+		}
+	}
+
+	if len(byName) == 0 {
+		return
+	}
+
+	for name, desc := range listDescriptions(text, byName) {
+		byName[name].Doc = desc
+	}
+
+	for _, sentence := range docSentences(text) {
+		for _, m := range paramSentenceRegexp.FindAllStringSubmatch(sentence, -1) {
+			if p, ok := byName[m[1]]; ok && p.Doc == "" {
+				p.Doc = sentence
+			}
+		}
+	}
+}
+
+// listDescriptions returns the descriptions of the items of the lists of a
+// comment whose names are among the given ones, by name. Descriptions go on
+// in the lines below their item that are indented more than it, and are
+// joined with spaces.
+func listDescriptions(text string, names map[string]*Field) map[string]string {
+	var (
+		result = make(map[string]string)
+		name   string
+		indent int
+	)
+	for _, line := range strings.Split(text, "\n") {
+		if m := listItemRegexp.FindStringSubmatch(line); m != nil {
+			if _, ok := names[m[2]]; ok {
+				name, indent = m[2], len(m[1])
+				result[name] = strings.TrimSpace(m[3])
+				continue
+			}
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		if name != "" && trimmed != "" && len(line)-len(trimmed) > indent {
+			result[name] += " " + strings.TrimSpace(trimmed)
+			continue
+		}
+		name = ""
+	}
+	return result
+}
+
+// docSentences returns the sentences of the paragraphs of a comment, those
+// of code blocks and lists excluded, with their lines joined.
+func docSentences(text string) []string {
+	var sentences []string
+	for _, para := range strings.Split(text, "\n\n") {
+		if strings.HasPrefix(para, " ") || strings.HasPrefix(para, "\t") {
+			continue
+		}
+
+		para = strings.Join(strings.Fields(para), " ")
+		for para != "" {
+			end := sentenceEnd(para)
+			sentences = append(sentences, strings.TrimSpace(para[:end]))
+			para = strings.TrimSpace(para[end:])
+		}
+	}
+	return sentences
+}
+
+// sentenceEnd returns the index right after the end of the first sentence
+// of the text, a period, question or exclamation mark followed by a space,
+// or its end.
+func sentenceEnd(text string) int {
+	for i := 0; i < len(text)-1; i++ {
+		if strings.IndexByte(".?!", text[i]) >= 0 && text[i+1] == ' ' {
+			return i + 1
+		}
+	}
+	return len(text)
+}
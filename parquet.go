@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Parquet physical types and other values of its metadata.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired     = 0
+	parquetUTF8         = 0
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetDataPage     = 0
+	parquetUncompressed = 0
+)
+
+// parquetMagic is at the start and the end of Parquet files.
+const parquetMagic = "PAR1"
+
+// parquetWriter writes the symbols of all the packages as a Parquet file
+// with the same columns as the csv format, all of them required and with
+// their values in plain encoding and not compressed, in a single row group
+// with a page per column. Parquet files cannot be streamed, so they are only
+// written when all the packages are.
+type parquetWriter struct {
+	w    *bufio.Writer
+	rows []*symbolRow
+}
+
+func newParquetWriter(w io.Writer, list bool) packageWriter {
+	return &parquetWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *parquetWriter) Write(pkg *Pkg) error {
+	w.rows = append(w.rows, symbolRows(pkg)...)
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if _, err := w.w.Write(encodeParquet(w.rows)); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// parquetColumn is a column of a Parquet file with its values encoded.
+type parquetColumn struct {
+	name string
+	typ  int32
+	data []byte
+}
+
+func encodeParquet(rows []*symbolRow) []byte {
+	var str = func(f func(*symbolRow) string) []byte {
+		var b bytes.Buffer
+		for _, row := range rows {
+			s := f(row)
+			binary.Write(&b, binary.LittleEndian, uint32(len(s)))
+			b.WriteString(s)
+		}
+		return b.Bytes()
+	}
+
+	var int64s = func(f func(*symbolRow) int) []byte {
+		b := make([]byte, 8*len(rows))
+		for i, row := range rows {
+			binary.LittleEndian.PutUint64(b[8*i:], uint64(f(row)))
+		}
+		return b
+	}
+
+	// Booleans are packed, starting from the least significant bit.
+	exported := make([]byte, (len(rows)+7)/8)
+	for i, row := range rows {
+		if row.Exported {
+			exported[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	columns := []*parquetColumn{
+		{symbolColumns[0], parquetByteArray, str(func(r *symbolRow) string { return r.ImportPath })},
+		{symbolColumns[1], parquetByteArray, str(func(r *symbolRow) string { return r.Kind })},
+		{symbolColumns[2], parquetByteArray, str(func(r *symbolRow) string { return r.Name })},
+		{symbolColumns[3], parquetBoolean, exported},
+		{symbolColumns[4], parquetInt64, int64s(func(r *symbolRow) int { return r.DocLength })},
+		{symbolColumns[5], parquetByteArray, str(func(r *symbolRow) string { return r.File })},
+		{symbolColumns[6], parquetInt64, int64s(func(r *symbolRow) int { return r.Line })},
+	}
+
+	var (
+		out     = bytes.NewBufferString(parquetMagic)
+		offsets = make([]int64, len(columns))
+		sizes   = make([]int64, len(columns))
+	)
+	for i, c := range columns {
+		var h thriftCompact
+		h.begin()
+		h.i32(1, parquetDataPage)
+		h.i32(2, int32(len(c.data)))
+		h.i32(3, int32(len(c.data)))
+		h.structField(5)
+		h.i32(1, int32(len(rows)))
+		h.i32(2, parquetPlain)
+		h.i32(3, parquetRLE)
+		h.i32(4, parquetRLE)
+		h.end()
+		h.end()
+
+		offsets[i] = int64(out.Len())
+		sizes[i] = int64(h.buf.Len() + len(c.data))
+		out.Write(h.buf.Bytes())
+		out.Write(c.data)
+	}
+
+	var m thriftCompact
+	m.begin()
+	m.i32(1, 1)
+	m.list(2, thriftStruct, len(columns)+1)
+	m.begin()
+	m.str(4, "schema")
+	m.i32(5, int32(len(columns)))
+	m.end()
+	for _, c := range columns {
+		m.begin()
+		m.i32(1, c.typ)
+		m.i32(3, parquetRequired)
+		m.str(4, c.name)
+		if c.typ == parquetByteArray {
+			m.i32(6, parquetUTF8)
+		}
+		m.end()
+	}
+	m.i64(3, int64(len(rows)))
+
+	var total int64
+	m.list(4, thriftStruct, 1)
+	m.begin()
+	m.list(1, thriftStruct, len(columns))
+	for i, c := range columns {
+		total += sizes[i]
+		m.begin()
+		m.i64(2, offsets[i])
+		m.structField(3)
+		m.i32(1, c.typ)
+		m.list(2, thriftI32, 1)
+		m.varint(zigzag(parquetPlain))
+		m.list(3, thriftBinary, 1)
+		m.binary(c.name)
+		m.i32(4, parquetUncompressed)
+		m.i64(5, int64(len(rows)))
+		m.i64(6, sizes[i])
+		m.i64(7, sizes[i])
+		m.i64(9, offsets[i])
+		m.end()
+		m.end()
+	}
+	m.i64(2, total)
+	m.i64(3, int64(len(rows)))
+	m.end()
+	m.str(6, "godocjson "+generatorVersion())
+	m.end()
+
+	out.Write(m.buf.Bytes())
+	binary.Write(out, binary.LittleEndian, uint32(m.buf.Len()))
+	out.WriteString(parquetMagic)
+	return out.Bytes()
+}
+
+// Types of the Thrift compact protocol.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftCompact encodes structs in the Thrift compact protocol, in which
+// the metadata of Parquet files is written. Fields must be written in
+// increasing order of their IDs.
+type thriftCompact struct {
+	buf bytes.Buffer
+	// last are the IDs of the last field written of each struct being
+	// written, as fields are encoded relative to the previous one.
+	last []int16
+}
+
+// begin starts a struct, which is ended with end.
+func (t *thriftCompact) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftCompact) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftCompact) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftCompact) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftCompact) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftCompact) str(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.binary(s)
+}
+
+// structField starts a field with a struct, which is ended with end.
+func (t *thriftCompact) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// list starts a field with a list of n elements of the given type, which
+// are written next, structs starting with begin.
+func (t *thriftCompact) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+
+	t.buf.WriteByte(0xf0 | elem)
+	t.varint(uint64(n))
+}
+
+func (t *thriftCompact) binary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftCompact) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	t.buf.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParquetWriter checks that the footer and metadata of the Parquet file
+// of some packages describe its columns and pages, and that the values of
+// the pages are those of the rows of the symbols.
+func TestParquetWriter(t *testing.T) {
+	var pkgs []*Pkg
+	for _, pkgName := range []string{"container/list", "sync"} {
+		pkg, err := extract(pkgName)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	var buf bytes.Buffer
+	w := newParquetWriter(&buf, true)
+	var rows []*symbolRow
+	for _, pkg := range pkgs {
+		if err := w.Write(pkg); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		rows = append(rows, symbolRows(pkg)...)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte(parquetMagic)) || !bytes.HasSuffix(data, []byte(parquetMagic)) {
+		t.Fatalf("file does not start and end with %s", parquetMagic)
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-size : len(data)-8]
+	meta, err := readThriftStruct(bytes.NewReader(footer))
+	if err != nil {
+		t.Fatalf("unable to read the metadata: %s", err)
+	}
+
+	if meta[1] != int64(1) {
+		t.Errorf("expecting version 1, got %v", meta[1])
+	}
+
+	if meta[3] != int64(len(rows)) {
+		t.Errorf("expecting %d rows, got %v", len(rows), meta[3])
+	}
+
+	if created, _ := meta[6].(string); !strings.HasPrefix(created, "godocjson ") {
+		t.Errorf("unexpected created_by %q", created)
+	}
+
+	schema := meta[2].([]interface{})
+	if len(schema) != len(symbolColumns)+1 {
+		t.Fatalf("expecting %d schema elements, got %d", len(symbolColumns)+1, len(schema))
+	}
+
+	root := schema[0].(thriftFields)
+	if root[4] != "schema" || root[5] != int64(len(symbolColumns)) {
+		t.Errorf("unexpected root of the schema: %v", root)
+	}
+
+	wantTypes := []int64{parquetByteArray, parquetByteArray, parquetByteArray, parquetBoolean, parquetInt64, parquetByteArray, parquetInt64}
+	for i, name := range symbolColumns {
+		elem := schema[i+1].(thriftFields)
+		if elem[4] != name || elem[1] != wantTypes[i] || elem[3] != int64(parquetRequired) {
+			t.Errorf("unexpected schema element of column %s: %v", name, elem)
+		}
+	}
+
+	groups := meta[4].([]interface{})
+	if len(groups) != 1 {
+		t.Fatalf("expecting a row group, got %d", len(groups))
+	}
+
+	group := groups[0].(thriftFields)
+	if group[3] != int64(len(rows)) {
+		t.Errorf("expecting %d rows in the row group, got %v", len(rows), group[3])
+	}
+
+	chunks := group[1].([]interface{})
+	if len(chunks) != len(symbolColumns) {
+		t.Fatalf("expecting %d column chunks, got %d", len(symbolColumns), len(chunks))
+	}
+
+	var (
+		total   int64
+		columns = make([][]interface{}, len(chunks))
+	)
+	for i, chunk := range chunks {
+		cm := chunk.(thriftFields)[3].(thriftFields)
+		name := symbolColumns[i]
+		if !reflect.DeepEqual(cm[3], []interface{}{name}) || cm[1] != wantTypes[i] || cm[5] != int64(len(rows)) {
+			t.Errorf("unexpected metadata of column %s: %v", name, cm)
+		}
+
+		offset, chunkSize := cm[9].(int64), cm[7].(int64)
+		total += chunkSize
+		r := bytes.NewReader(data[offset : offset+chunkSize])
+		page, err := readThriftStruct(r)
+		if err != nil {
+			t.Fatalf("unable to read the page header of column %s: %s", name, err)
+		}
+
+		header := page[5].(thriftFields)
+		if page[1] != int64(parquetDataPage) || header[1] != int64(len(rows)) || page[2] != page[3] || page[3] != int64(r.Len()) {
+			t.Errorf("unexpected page header of column %s: %v", name, page)
+		}
+
+		columns[i], err = readPlainValues(r, wantTypes[i], len(rows))
+		if err != nil {
+			t.Fatalf("unable to read the values of column %s: %s", name, err)
+		}
+	}
+
+	if group[2] != total {
+		t.Errorf("expecting a total size of %d, got %v", total, group[2])
+	}
+
+	for i, row := range rows {
+		want := []interface{}{row.ImportPath, row.Kind, row.Name, row.Exported, int64(row.DocLength), row.File, int64(row.Line)}
+		for j := range columns {
+			if columns[j][i] != want[j] {
+				t.Errorf("row %d, column %s: expecting %v, got %v", i, symbolColumns[j], want[j], columns[j][i])
+			}
+		}
+	}
+}
+
+// thriftFields are the fields of a struct in the Thrift compact protocol by
+// their ID, with integers as int64, binaries as strings, lists as slices and
+// structs as thriftFields.
+type thriftFields map[int16]interface{}
+
+func readThriftStruct(r *bytes.Reader) (thriftFields, error) {
+	var (
+		fields = make(thriftFields)
+		last   int16
+	)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == 0 {
+			return fields, nil
+		}
+
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			id = int16(unzigzag(v))
+		}
+		last = id
+
+		if fields[id], err = readThriftValue(r, b&0x0f); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readThriftValue(r *bytes.Reader, typ byte) (interface{}, error) {
+	switch typ {
+	case thriftI32, thriftI64:
+		v, err := binary.ReadUvarint(r)
+		return unzigzag(v), err
+	case thriftBinary:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, n)
+		_, err = io.ReadFull(r, data)
+		return string(data), err
+	case thriftList:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		n := uint64(b >> 4)
+		if n == 15 {
+			if n, err = binary.ReadUvarint(r); err != nil {
+				return nil, err
+			}
+		}
+
+		var list = make([]interface{}, n)
+		for i := range list {
+			if list[i], err = readThriftValue(r, b&0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftStruct:
+		return readThriftStruct(r)
+	}
+	return nil, fmt.Errorf("unexpected type %d", typ)
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// readPlainValues reads n values of the given type in plain encoding.
+func readPlainValues(r *bytes.Reader, typ int64, n int) ([]interface{}, error) {
+	var values = make([]interface{}, n)
+	switch typ {
+	case parquetByteArray:
+		for i := range values {
+			var size uint32
+			if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+				return nil, err
+			}
+
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			values[i] = string(data)
+		}
+	case parquetInt64:
+		for i := range values {
+			var v int64
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+	case parquetBoolean:
+		bits := make([]byte, (n+7)/8)
+		if _, err := io.ReadFull(r, bits); err != nil {
+			return nil, err
+		}
+
+		for i := range values {
+			values[i] = bits[i/8]&(1<<(i%8)) != 0
+		}
+	default:
+		return nil, fmt.Errorf("unexpected type %d", typ)
+	}
+
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("%d bytes left after the values", r.Len())
+	}
+	return values, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"go/scanner"
+)
+
+// newParseErrors returns the errors in err if it is a syntax error, as
+// returned by the parser, or false otherwise.
+func newParseErrors(err error) ([]*ParseError, bool) {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return nil, false
+	}
+
+	var result []*ParseError
+	for _, e := range list {
+		result = append(result, &ParseError{
+			Pos: &FilePos{
+				Line:   e.Pos.Line,
+				Column: e.Pos.Column,
+				File:   relPath(e.Pos.Filename),
+			},
+			Message: e.Msg,
+		})
+	}
+	return result, true
+}
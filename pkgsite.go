@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"go/token"
+)
+
+var withPkgsiteURLs = flag.Bool("pkgsite-urls", false, "include the URLs of the documentation of the package and its symbols in pkg.go.dev")
+
+// pkgsiteBaseURL is the URL of pkg.go.dev.
+const pkgsiteBaseURL = "https://pkg.go.dev/"
+
+// setPkgsiteURLs sets the URLs of the documentation of the package and all
+// its exported symbols in pkg.go.dev, of the latest version of the package
+// if version is empty. Symbols are linked with the anchors pkg.go.dev gives
+// them: their name, or Type.Method for methods. Commands have no
+// documentation of their symbols, so only the package is linked.
+func setPkgsiteURLs(pkg *Pkg, version string) {
+	if pkg.ImportPath == "" {
+		return
+	}
+
+	pkg.PkgsiteURL = pkgsiteBaseURL + pkg.ImportPath
+	if version != "" {
+		pkg.PkgsiteURL += "@" + version
+	}
+
+	if pkg.Name == "main" {
+		return
+	}
+
+	anchor := func(name string) string {
+		if !token.IsExported(name) {
+			return ""
+		}
+		return pkg.PkgsiteURL + "#" + name
+	}
+
+	values := func(list []*Value) {
+		for _, v := range list {
+			v.PkgsiteURLs = make([]string, len(v.Names))
+			for i, name := range v.Names {
+				v.PkgsiteURLs[i] = anchor(name)
+			}
+		}
+	}
+
+	funcs := func(list []*Func) {
+		for _, f := range list {
+			f.PkgsiteURL = anchor(f.Name)
+		}
+	}
+
+	values(pkg.Consts)
+	values(pkg.Vars)
+	funcs(pkg.Funcs)
+	for _, t := range pkg.Types {
+		t.PkgsiteURL = anchor(t.Name)
+		values(t.Consts)
+		values(t.Vars)
+		funcs(t.Funcs)
+
+		// Methods of unexported types are not documented.
+		for _, m := range t.Methods {
+			if t.PkgsiteURL != "" && token.IsExported(m.Name) {
+				m.PkgsiteURL = t.PkgsiteURL + "." + m.Name
+			}
+		}
+	}
+}
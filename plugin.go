@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var execPlugin = flag.String("exec-plugin", "", "command the JSON of each package is piped through, its output is used instead")
+
+// runPlugin pipes the JSON documentation of a package through the command
+// given with -exec-plugin and returns what the command wrote to its
+// standard output, which must be valid JSON as well.
+func runPlugin(pkg *Pkg) (json.RawMessage, error) {
+	data, err := json.Marshal(outputValue(pkg))
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", *execPlugin)
+	} else {
+		cmd = exec.Command("sh", "-c", *execPlugin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "GODOCJSON_IMPORT_PATH="+pkg.ImportPath)
+
+	debugf("running plugin for %s", pkg.ImportPath)
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin failed for %s: %s: %s", pkg.ImportPath, err, msg)
+		}
+		return nil, fmt.Errorf("plugin failed for %s: %s", pkg.ImportPath, err)
+	}
+
+	if !json.Valid(stdout.Bytes()) {
+		return nil, fmt.Errorf("plugin output for %s is not valid JSON", pkg.ImportPath)
+	}
+
+	return json.RawMessage(stdout.Bytes()), nil
+}
+
+// pluginWriter pipes every package through the plugin before writing its
+// output as JSON.
+type pluginWriter struct {
+	*jsonWriter
+}
+
+func (w *pluginWriter) Write(pkg *Pkg) error {
+	out, err := runPlugin(pkg)
+	if err != nil {
+		return err
+	}
+	return w.WriteValue(out)
+}
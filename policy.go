@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"go/scanner"
+	"os"
+	"strings"
+)
+
+var (
+	failOnEmpty       = flag.Bool("fail-on-empty", false, "exit with status 4 if any package has no exported symbols")
+	failOnParseError  = flag.Bool("fail-on-parse-error", false, "exit with status 3 if any file cannot be parsed")
+	failOnMissingDocs = flag.Bool("fail-on-missing-docs", false, "exit with status 5 if any package or exported symbol is not documented")
+)
+
+// Exit statuses of the conditions that can be turned into failures. When
+// several of them are met, the status is the lowest one.
+const (
+	exitParseError  = 3
+	exitEmpty       = 4
+	exitMissingDocs = 5
+)
+
+// policy records the failure conditions met by the packages documented.
+type policy struct {
+	status int
+}
+
+var exitPolicy policy
+
+func (p *policy) fail(status int) {
+	if p.status == 0 || status < p.status {
+		p.status = status
+	}
+}
+
+// check records the failure conditions met by the given package, reporting
+// each of them.
+func (p *policy) check(pkg *Pkg) {
+	name := pkg.ImportPath
+	if name == "" {
+		name = pkg.Name
+	}
+
+	if *failOnParseError && len(pkg.ParseErrors) > 0 {
+		p.fail(exitParseError)
+	}
+
+	symbols := packageSymbols(pkg)
+	if *failOnEmpty && len(symbols) == 0 {
+		errorf("%s has no exported symbols", name)
+		p.fail(exitEmpty)
+	}
+
+	if *failOnMissingDocs {
+		var missing []string
+		if synopsis(pkg) == "" {
+			missing = append(missing, "package "+pkg.Name)
+		}
+
+		for _, sym := range symbols {
+			if sym.Doc == "" {
+				missing = append(missing, sym.Name)
+			}
+		}
+
+		if len(missing) > 0 {
+			errorf("%s has undocumented symbols: %s", name, strings.Join(missing, ", "))
+			p.fail(exitMissingDocs)
+		}
+	}
+}
+
+// fatal reports an error that stopped the documentation of the packages,
+// such as none of the files of a package being parsed, exiting with the
+// status of the parse errors if it is one of them and they are to be turned
+// into failures.
+func (p *policy) fatal(err error) {
+	status := 1
+	var list scanner.ErrorList
+	if *failOnParseError && errors.As(err, &list) {
+		status = exitParseError
+	}
+
+	if *strict {
+		strictFatal(newStrictError(err), status)
+	}
+
+	errorf("%s", err)
+	os.Exit(status)
+}
+
+// exit exits with the status of the failure conditions met, if any.
+func (p *policy) exit() {
+	if p.status != 0 {
+		os.Exit(p.status)
+	}
+}
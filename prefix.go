@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// stringList is a flag that can be given many times, keeping every value.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+var (
+	trimPrefixes    stringList
+	replacePrefixes stringList
+)
+
+func init() {
+	flag.Var(&trimPrefixes, "trim-prefix", "prefix to remove from file paths, such as the module cache directory; can be given many times")
+	flag.Var(&replacePrefixes, "replace-prefix", "old=new replacement of the prefix of file paths; can be given many times")
+}
+
+// checkPrefixReplacements checks that every -replace-prefix is of the form
+// old=new.
+func checkPrefixReplacements() error {
+	for _, r := range replacePrefixes {
+		if old, _, ok := strings.Cut(r, "="); !ok || old == "" {
+			return fmt.Errorf("invalid -replace-prefix %q: expecting old=new", r)
+		}
+	}
+	return nil
+}
+
+// rewritePrefix rewrites the given slash-separated path with the first of
+// the prefixes given with -replace-prefix it has, then removes the first
+// of those given with -trim-prefix it has. Prefixes are given with forward
+// slashes too, and so are matched on any platform.
+func rewritePrefix(path string) string {
+	for _, r := range replacePrefixes {
+		old, new, _ := strings.Cut(r, "=")
+		if rest, ok := strings.CutPrefix(path, toSlash(old)); ok {
+			path = toSlash(new) + rest
+			break
+		}
+	}
+
+	for _, p := range trimPrefixes {
+		if rest, ok := strings.CutPrefix(path, toSlash(p)); ok {
+			path = strings.TrimLeft(rest, "/")
+			break
+		}
+	}
+	return path
+}
+
+// toSlash replaces the backslashes of a path given in a flag, which may
+// have been written for Windows, with forward slashes.
+func toSlash(path string) string {
+	return strings.ReplaceAll(path, `\`, "/")
+}
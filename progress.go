@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var noProgress = flag.Bool("no-progress", false, "do not report the progress of runs documenting many packages")
+
+// progressInterval is how often progress is reported when the standard
+// error is not a terminal, so logs are not flooded.
+const progressInterval = 10 * time.Second
+
+// progress reports to the standard error how many of the packages of a run
+// were documented, which one is being documented and the time elapsed. On
+// a terminal, it is a single line updated in place, otherwise, or if
+// messages are logged as JSON, a message is logged every progressInterval.
+// A nil progress reports nothing.
+type progress struct {
+	mu       sync.Mutex
+	total    int
+	done     int
+	current  string
+	start    time.Time
+	reported time.Time
+	tty      bool
+	shown    bool
+}
+
+// activeProgress is the progress being reported, if any, which is cleared
+// before logging anything so messages are not mixed with it.
+var activeProgress struct {
+	sync.Mutex
+	p *progress
+}
+
+// newProgress returns the progress of a run documenting the given number
+// of packages, which is nil if there is only one, if progress is disabled
+// with -no-progress or if only errors are to be reported.
+func newProgress(total int) *progress {
+	if *noProgress || total < 2 || logger.level == levelError {
+		return nil
+	}
+
+	p := &progress{total: total, start: time.Now(), tty: !logger.json && isTerminal(os.Stderr)}
+	p.reported = p.start
+	activeProgress.Lock()
+	activeProgress.p = p
+	activeProgress.Unlock()
+	return p
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// started records that the package with the given name is being
+// documented.
+func (p *progress) started(name string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.current = name
+	p.mu.Unlock()
+	p.report()
+}
+
+// finished records that one more package was documented.
+func (p *progress) finished() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.done++
+	p.mu.Unlock()
+	p.report()
+}
+
+func (p *progress) report() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elapsed := time.Since(p.start).Round(time.Second)
+	msg := fmt.Sprintf("%d/%d packages, %s, %s elapsed", p.done, p.total, p.current, elapsed)
+	if p.tty {
+		fmt.Fprintf(os.Stderr, "\r\033[K%s", msg)
+		p.shown = true
+		return
+	}
+
+	if time.Since(p.reported) >= progressInterval {
+		p.reported = time.Now()
+		logger.output(levelInfo, "", msg)
+	}
+}
+
+// clear removes the progress line from the terminal, if it is shown.
+func (p *progress) clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// stop stops reporting the progress.
+func (p *progress) stop() {
+	if p == nil {
+		return
+	}
+
+	activeProgress.Lock()
+	activeProgress.p = nil
+	activeProgress.Unlock()
+	p.clear()
+}
+
+// clearProgress removes the progress line from the terminal, if any, before
+// logging a message.
+func clearProgress() {
+	activeProgress.Lock()
+	p := activeProgress.p
+	activeProgress.Unlock()
+	if p != nil {
+		p.clear()
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	omitEmpty = flag.Bool("omit-empty", false, "leave out empty lists, strings and positions from the output")
+	noPos     = flag.Bool("no-pos", false, "leave out all the positions from the output")
+)
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// outputValue returns the given value as it is to be written: with the
+// fields of all the structs in it named following the naming convention
+// given with -field-case, only those of symbols selected with -fields,
+// without positions with -no-pos and, with -omit-empty, without those that
+// are empty, as objects encoded in the same way as encoding/json does. Fields
+// with an explicit name in their json tag and the keys of maps are left as
+// they are. Unless any of them is requested, the value is returned
+// unchanged.
+func outputValue(v interface{}) interface{} {
+	if *fieldCase == "pascal" && !*omitEmpty && !*noPos && symbolFields == nil {
+		return v
+	}
+	return project(reflect.ValueOf(v))
+}
+
+func project(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if v.Type().Implements(marshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return project(v.Elem())
+	case reflect.Struct:
+		obj := new(jsonObject)
+		projectFields(obj, v)
+		return obj
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		var m = make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = project(iter.Value())
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		var list = make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = project(v.Index(i))
+		}
+		return list
+	}
+	return v.Interface()
+}
+
+// projectFields adds the fields of the struct to the object, following the
+// rules of encoding/json for their tags and embedded structs.
+func projectFields(obj *jsonObject, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+
+			if fv.Kind() == reflect.Struct {
+				projectFields(obj, fv)
+				continue
+			}
+		}
+
+		if !f.IsExported() || !keepField(t, f.Name) {
+			continue
+		}
+
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+
+		if *omitEmpty && isEmptyOutput(fv) {
+			continue
+		}
+
+		if *noPos && posTypes[f.Type] {
+			continue
+		}
+
+		if name == "" {
+			name = recaseName(f.Name, *fieldCase)
+		}
+		obj.keys = append(obj.keys, name)
+		obj.values = append(obj.values, project(fv))
+	}
+}
+
+// isEmptyValue reports whether the value is omitted by encoding/json from
+// fields tagged with omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isEmptyOutput reports whether the value is omitted with -omit-empty: an
+// empty string, slice or map, a nil pointer or a position in no file.
+func isEmptyOutput(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return true
+		}
+	}
+
+	switch p := v.Interface().(type) {
+	case *Pos:
+		return isZeroPos(p.Start) && isZeroPos(p.End)
+	case *FilePos:
+		return isZeroPos(p)
+	}
+	return false
+}
+
+// posTypes are the types of the fields left out with -no-pos.
+var posTypes = map[reflect.Type]bool{
+	reflect.TypeOf(&Pos{}):     true,
+	reflect.TypeOf([]*Pos{}):   true,
+	reflect.TypeOf(&FilePos{}): true,
+}
+
+func isZeroPos(p *FilePos) bool {
+	return p == nil || p.Line == 0
+}
+
+// MarshalJSON encodes the object with its keys in order. The objects, lists
+// and maps in it are written to the same buffer as they are found, instead
+// of each of them being encoded on its own and then copied into the one
+// containing it.
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeJSON writes the JSON encoding of a value returned by project. The
+// keys of maps are sorted, as encoding/json does, and the rest of values are
+// encoded by it.
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case *jsonObject:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSONMember(buf, key, v.values[i]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case map[string]interface{}:
+		var keys = make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSONMember(buf, key, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
+
+func writeJSONMember(buf *bytes.Buffer, key string, v interface{}) error {
+	if err := writeJSON(buf, key); err != nil {
+		return err
+	}
+	buf.WriteByte(':')
+	return writeJSON(buf, v)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the buckets of the
+// request latency histogram.
+var latencyBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// serverMetrics are the counters of the server, exposed in the Prometheus text
+// format at /metrics.
+type serverMetrics struct {
+	mu sync.Mutex
+	// requests are the number of requests served, by status code.
+	requests map[int]int
+	// latencies are the number of requests in each of the latencyBuckets,
+	// with the last one counting those above all of them.
+	latencies   []int
+	latencySum  float64
+	cacheHits   int
+	cacheMisses int
+	// parseDurations are the seconds it took to build the documentation of
+	// each package.
+	parseDurations map[string]float64
+}
+
+// stats are the metrics of this process. They are always collected, but
+// only served by the server.
+var stats = newServerMetrics()
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		requests:       make(map[int]int),
+		latencies:      make([]int, len(latencyBuckets)+1),
+		parseDurations: make(map[string]float64),
+	}
+}
+
+func (m *serverMetrics) observeRequest(code int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[code]++
+	m.latencySum += d.Seconds()
+	i := sort.SearchFloat64s(latencyBuckets, d.Seconds())
+	m.latencies[i]++
+}
+
+// observeCache records whether the documentation of a package was found in
+// the cache.
+func (m *serverMetrics) observeCache(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+func (m *serverMetrics) observeParse(pkgName string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.parseDurations[pkgName] = d.Seconds()
+}
+
+// instrument returns a handler recording the status and latency of the
+// requests served by the given one.
+func (m *serverMetrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		m.observeRequest(rec.code, time.Since(start))
+	})
+}
+
+func (m *serverMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.writeTo(w)
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format.
+func (m *serverMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP godocjson_http_requests_total Number of HTTP requests served, by status code.")
+	fmt.Fprintln(w, "# TYPE godocjson_http_requests_total counter")
+	var codes []int
+	for code := range m.requests {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "godocjson_http_requests_total{code=\"%d\"} %d\n", code, m.requests[code])
+	}
+
+	fmt.Fprintln(w, "# HELP godocjson_http_request_duration_seconds Latency of the HTTP requests.")
+	fmt.Fprintln(w, "# TYPE godocjson_http_request_duration_seconds histogram")
+	var count int
+	for i, le := range latencyBuckets {
+		count += m.latencies[i]
+		fmt.Fprintf(w, "godocjson_http_request_duration_seconds_bucket{le=\"%s\"} %d\n", formatFloat(le), count)
+	}
+	count += m.latencies[len(latencyBuckets)]
+	fmt.Fprintf(w, "godocjson_http_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	fmt.Fprintf(w, "godocjson_http_request_duration_seconds_sum %s\n", formatFloat(m.latencySum))
+	fmt.Fprintf(w, "godocjson_http_request_duration_seconds_count %d\n", count)
+
+	fmt.Fprintln(w, "# HELP godocjson_cache_hits_total Number of packages whose documentation was found in the cache.")
+	fmt.Fprintln(w, "# TYPE godocjson_cache_hits_total counter")
+	fmt.Fprintf(w, "godocjson_cache_hits_total %d\n", m.cacheHits)
+	fmt.Fprintln(w, "# HELP godocjson_cache_misses_total Number of packages whose documentation was not found in the cache.")
+	fmt.Fprintln(w, "# TYPE godocjson_cache_misses_total counter")
+	fmt.Fprintf(w, "godocjson_cache_misses_total %d\n", m.cacheMisses)
+
+	fmt.Fprintln(w, "# HELP godocjson_package_parse_duration_seconds Time it took to build the documentation of each package.")
+	fmt.Fprintln(w, "# TYPE godocjson_package_parse_duration_seconds gauge")
+	var pkgs []string
+	for pkg := range m.parseDurations {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		fmt.Fprintf(w, "godocjson_package_parse_duration_seconds{package=%s} %s\n", strconv.Quote(pkg), formatFloat(m.parseDurations[pkg]))
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// statusRecorder is a response writer keeping the status code written.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxModuleZipSize is the maximum size of a module zip, as enforced by the
+// go command.
+const maxModuleZipSize = 500 << 20
+
+// defaultModuleProxy is the module proxy used when GOPROXY is not set.
+const defaultModuleProxy = "https://proxy.golang.org"
+
+// errModuleNotFound is returned when the module proxy does not have the
+// module or version requested.
+var errModuleNotFound = errors.New("module not found")
+
+// proxyServer serves the documentation of any version of any module,
+// downloading it from a module proxy the first time it is requested and
+// caching it on disk from then on. The paths of the requests are:
+//
+//   - /module@version: the package at the root of the module.
+//   - /module@version/dir: the package in a directory of the module.
+//   - /module@version/...: all the packages of the module.
+//
+// The latest version of a module is resolved by the proxy, redirecting to
+// the path with the version it reports.
+type proxyServer struct {
+	// proxy is the URL of the module proxy, or empty if modules are never
+	// downloaded.
+	proxy  string
+	dir    string
+	client *http.Client
+	// ctx is the context of the downloads, which are shared by all the
+	// requests of the same module and outlive them.
+	ctx      context.Context
+	mu       sync.Mutex
+	inflight map[string]*moduleFetch
+}
+
+// moduleFetch is the documentation of a module being downloaded and
+// extracted, available when done is closed.
+type moduleFetch struct {
+	done chan struct{}
+	pkgs []*Pkg
+	err  error
+}
+
+func (s *proxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mod, rest, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "@")
+	version, dir, _ := strings.Cut(rest, "/")
+	if !ok || !validModulePath(mod) || !validModuleVersion(version) {
+		http.Error(w, "expecting a path like /module@version/dir", http.StatusBadRequest)
+		return
+	}
+
+	if version == "latest" {
+		latest, err := s.latest(r.Context(), mod)
+		if err != nil {
+			s.fail(w, mod, version, err)
+			return
+		}
+
+		target := "/" + mod + "@" + latest
+		if dir != "" {
+			target += "/" + dir
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	pkgs, err := s.module(r.Context(), mod, version)
+	if err != nil {
+		s.fail(w, mod, version, err)
+		return
+	}
+
+	var (
+		result interface{}
+		hash   string
+	)
+	if dir == "..." {
+		hashes := make([]string, len(pkgs))
+		for i, pkg := range pkgs {
+			hashes[i] = pkg.Hash
+		}
+		result, hash = pkgs, sourceHash([]byte(strings.Join(hashes, "\n")))
+	} else {
+		importPath := mod
+		if dir != "" {
+			importPath += "/" + strings.TrimSuffix(dir, "/")
+		}
+
+		for _, pkg := range pkgs {
+			if pkg.ImportPath == importPath {
+				result, hash = pkg, pkg.Hash
+			}
+		}
+
+		if result == nil {
+			http.Error(w, fmt.Sprintf("no package %s in %s@%s", importPath, mod, version), http.StatusNotFound)
+			return
+		}
+	}
+
+	// Versions of modules never change, so neither does their
+	// documentation.
+	etag := `"` + hash + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if notModified(r, etag, time.Time{}) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(outputValue(result)); err != nil {
+		debugf("unable to write response: %s", err)
+	}
+}
+
+func (s *proxyServer) fail(w http.ResponseWriter, mod, version string, err error) {
+	switch {
+	case errors.Is(err, errModuleNotFound):
+		http.Error(w, fmt.Sprintf("%s@%s: %s", mod, version, err), http.StatusNotFound)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		errorf("%s@%s: %s", mod, version, err)
+		http.Error(w, fmt.Sprintf("%s@%s: %s", mod, version, err), http.StatusBadGateway)
+	}
+}
+
+// module returns the documentation of all the packages of a module, from
+// the cache or downloading the module if it is not there. Requests for a
+// module being downloaded wait for it instead of downloading it again.
+func (s *proxyServer) module(ctx context.Context, mod, version string) ([]*Pkg, error) {
+	path := s.cachePath(mod, version)
+	if pkgs, ok := loadCachedModule(path); ok {
+		return pkgs, nil
+	}
+
+	key := mod + "@" + version
+	s.mu.Lock()
+	f, ok := s.inflight[key]
+	if !ok {
+		f = &moduleFetch{done: make(chan struct{})}
+		s.inflight[key] = f
+		go func() {
+			f.pkgs, f.err = s.fetch(mod, version)
+			if f.err == nil {
+				storeCachedModule(path, f.pkgs)
+			}
+
+			s.mu.Lock()
+			delete(s.inflight, key)
+			s.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.pkgs, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// cachePath returns the path of the file the documentation of a module is
+// cached in, which depends on the flags affecting it as well.
+func (s *proxyServer) cachePath(mod, version string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "version:%s\n", generatorVersion())
+	fmt.Fprintf(h, "module:%s@%s\n", mod, version)
+	flag.VisitAll(func(f *flag.Flag) {
+		if !nonCacheableFlags[f.Name] {
+			fmt.Fprintf(h, "flag:%s=%s\n", f.Name, f.Value)
+		}
+	})
+
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(s.dir, "modules", key[:2], key+".json")
+}
+
+// fetch downloads the zip of a module from the proxy and documents all its
+// packages.
+func (s *proxyServer) fetch(mod, version string) ([]*Pkg, error) {
+	if s.proxy == "" {
+		return nil, fmt.Errorf("%w: it is not cached and GOPROXY is off", errModuleNotFound)
+	}
+
+	infof("downloading %s@%s", mod, version)
+	resp, err := s.get(s.ctx, "/"+escapeModulePath(mod)+"/@v/"+escapeModulePath(version)+".zip")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "module.*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(resp.Body, maxModuleZipSize+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to download module: %w", err)
+	}
+
+	if n > maxModuleZipSize {
+		return nil, fmt.Errorf("module zip is larger than %d bytes", maxModuleZipSize)
+	}
+
+	if extractionSlots != nil {
+		extractionSlots <- struct{}{}
+		defer func() { <-extractionSlots }()
+	}
+
+	pkgs := []*Pkg{}
+	err = extractZip(tmp.Name(), mod, version, func(pkg *Pkg) error {
+		pkgs = append(pkgs, pkg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	infof("documented %d package(s) of %s@%s", len(pkgs), mod, version)
+	return pkgs, nil
+}
+
+// latest returns the latest version of a module, according to the proxy.
+func (s *proxyServer) latest(ctx context.Context, mod string) (string, error) {
+	if s.proxy == "" {
+		return "", fmt.Errorf("%w: the latest version cannot be resolved with GOPROXY off", errModuleNotFound)
+	}
+
+	resp, err := s.get(ctx, "/"+escapeModulePath(mod)+"/@latest")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var info struct{ Version string }
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("invalid response of the module proxy: %w", err)
+	}
+
+	if !validModuleVersion(info.Version) || info.Version == "latest" {
+		return "", fmt.Errorf("invalid version %q reported by the module proxy", info.Version)
+	}
+	return info.Version, nil
+}
+
+// get requests a path of the module proxy, failing unless the response is
+// successful.
+func (s *proxyServer) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.proxy+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		// Proxies answer both for modules and versions that do not exist.
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			return nil, errModuleNotFound
+		}
+		return nil, fmt.Errorf("module proxy returned %s for %s", resp.Status, path)
+	}
+	return resp, nil
+}
+
+// loadCachedModule returns the cached documentation of a module, if any.
+func loadCachedModule(path string) ([]*Pkg, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errorf("unable to read cached module %s: %s", path, err)
+		}
+		return nil, false
+	}
+
+	var pkgs []*Pkg
+	if err := json.Unmarshal(data, &pkgs); err != nil {
+		errorf("ignoring corrupt cached module %s: %s", path, err)
+		return nil, false
+	}
+
+	return pkgs, true
+}
+
+// storeCachedModule saves the documentation of a module. Failing to do so is
+// not fatal, as the module will just be downloaded again.
+func storeCachedModule(path string, pkgs []*Pkg) {
+	data, err := json.Marshal(pkgs)
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+
+	if err != nil {
+		errorf("unable to cache module in %s: %s", path, err)
+	}
+}
+
+// moduleProxyURL returns the URL of the first module proxy in a GOPROXY
+// list, or an empty string if it is off. "direct" entries are skipped, as
+// modules are only downloaded from proxies.
+func moduleProxyURL(goproxy string) string {
+	if goproxy == "" {
+		return defaultModuleProxy
+	}
+
+	for _, p := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		switch p = strings.TrimSpace(p); p {
+		case "off":
+			return ""
+		case "direct", "":
+		default:
+			return strings.TrimSuffix(p, "/")
+		}
+	}
+	return ""
+}
+
+// escapeModulePath escapes a module path or version as in the URLs of
+// module proxies, replacing each upper-case letter with an exclamation mark
+// followed by the letter in lower case.
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// validModulePath reports whether a module path is made of non-empty
+// elements of the characters allowed in them.
+func validModulePath(mod string) bool {
+	if mod == "" {
+		return false
+	}
+
+	for _, elem := range strings.Split(mod, "/") {
+		if elem == "" || elem == "." || elem == ".." || strings.HasPrefix(elem, ".") {
+			return false
+		}
+
+		for _, r := range elem {
+			if !isModuleChar(r) && r != '~' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validModuleVersion reports whether a version looks like a semantic
+// version, or is "latest".
+func validModuleVersion(version string) bool {
+	if version == "latest" {
+		return true
+	}
+
+	if !strings.HasPrefix(version, "v") || strings.Contains(version, "..") {
+		return false
+	}
+
+	for _, r := range version {
+		if !isModuleChar(r) && r != '+' {
+			return false
+		}
+	}
+	return true
+}
+
+func isModuleChar(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '.' || r == '_'
+}
+
+func runProxy(args []string) {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	corsOrigins := fs.String("cors-origin", "", "comma-separated origins allowed to call the server from a browser, or * for any")
+	tokenFile := fs.String("token-file", "", "file with the bearer token requests must be authorized with")
+	fs.StringVar(cacheDir, "cache-dir", "", "directory where the documentation of the modules is cached, the godocjson directory of the user cache by default")
+	addEnvFlags(fs)
+	requestTimeout := fs.Duration("request-timeout", 5*time.Minute, "maximum time to serve a request, including downloading and documenting the module, or 0 for no limit")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 30*time.Second, "maximum time to wait for the requests in progress after receiving SIGTERM or SIGINT")
+	maxExtractions := fs.Int("max-extractions", 0, "maximum number of modules documented at the same time, or 0 for no limit")
+	rateLimit := fs.Float64("rate-limit", 0, "maximum requests per second from all the clients together, or 0 for no limit")
+	clientRateLimit := fs.Float64("client-rate-limit", 0, "maximum requests per second from each client, told apart by their IP address and token, or 0 for no limit")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson proxy [-addr host:port] [-cache-dir dir]\n\n"+
+			"Serves the documentation of any version of any module at /module@version,\n"+
+			"/module@version/dir for the packages in its directories or /module@version/...\n"+
+			"for all of them. Modules are downloaded from the GOPROXY the first time they\n"+
+			"are requested, and served from the cache from then on.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyEnvFlags(); err != nil {
+		fatalf("%s", err)
+	}
+
+	if fs.NArg() > 0 {
+		fatalf("unexpected arguments: the modules to document are given in the requests")
+	}
+
+	if *maxExtractions < 0 {
+		fatalf("invalid -max-extractions %d: expecting a number greater than or equal to 0", *maxExtractions)
+	}
+
+	if *rateLimit < 0 || *clientRateLimit < 0 {
+		fatalf("invalid -rate-limit or -client-rate-limit: expecting a number greater than or equal to 0")
+	}
+
+	if *maxExtractions > 0 {
+		extractionSlots = make(chan struct{}, *maxExtractions)
+	}
+
+	dir := *cacheDir
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			fatalf("unable to find the cache directory, set one with -cache-dir: %s", err)
+		}
+		dir = filepath.Join(userDir, "godocjson")
+	}
+
+	token, err := readToken(*tokenFile)
+	if err != nil {
+		fatalf("unable to read token: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &proxyServer{
+		proxy:    moduleProxyURL(os.Getenv("GOPROXY")),
+		dir:      dir,
+		client:   &http.Client{Timeout: 10 * time.Minute},
+		ctx:      ctx,
+		inflight: make(map[string]*moduleFetch),
+	}
+	if s.proxy == "" {
+		warnf("GOPROXY is off: only the modules already cached in %s are served", dir)
+	}
+
+	limiter := newRateLimiter(*rateLimit, *clientRateLimit)
+	mux := http.NewServeMux()
+	mux.Handle("/", stats.instrument(newTimeoutHandler(newCORSHandler(newAuthHandler(limiter.handler(s), token), *corsOrigins), *requestTimeout)))
+	mux.Handle("/metrics", newAuthHandler(stats, token))
+
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	infof("serving the documentation of the modules of %s at http://%s", s.proxy, *addr)
+	select {
+	case err := <-errc:
+		fatalf("%s", err)
+	case <-ctx.Done():
+	}
+
+	infof("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errorf("unable to finish the requests in progress: %s", err)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var queryFlag = flag.String("query", "", "only output the part of the document matched by the given path expression, e.g. .Types[Name=Client].Methods")
+
+// query is a parsed path expression. Expressions are made of steps:
+//
+//	.Field      selects a field of an object, or of every object in a list
+//	[N]         selects the Nth element of a list
+//	[Field=V]   keeps the elements of a list whose field equals V
+//	[]          selects every element of a list
+//
+// Field names are matched case-insensitively. Whenever a step can match
+// several values, such as selecting a field from a list, the result is a
+// list.
+type query struct {
+	steps []queryStep
+}
+
+type queryStep struct {
+	kind  queryStepKind
+	field string
+	index int
+	value string
+}
+
+type queryStepKind int
+
+const (
+	stepField queryStepKind = iota
+	stepIndex
+	stepFilter
+	stepIter
+)
+
+func parseQuery(expr string) (*query, error) {
+	q := new(query)
+	rest := strings.TrimSpace(expr)
+	if rest == "." {
+		return q, nil
+	}
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+
+			field := rest[1 : end+1]
+			if field == "" {
+				return nil, fmt.Errorf("invalid query %q: empty field name", expr)
+			}
+
+			q.steps = append(q.steps, queryStep{kind: stepField, field: field})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid query %q: missing ]", expr)
+			}
+
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if inner == "" {
+				q.steps = append(q.steps, queryStep{kind: stepIter})
+				continue
+			}
+
+			if k, v, ok := strings.Cut(inner, "="); ok {
+				q.steps = append(q.steps, queryStep{
+					kind:  stepFilter,
+					field: strings.TrimSpace(k),
+					value: strings.Trim(strings.TrimSpace(v), `"`),
+				})
+				continue
+			}
+
+			n, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query %q: invalid index %q", expr, inner)
+			}
+			q.steps = append(q.steps, queryStep{kind: stepIndex, index: n})
+		default:
+			return nil, fmt.Errorf("invalid query %q: unexpected %q", expr, rest[0])
+		}
+	}
+
+	return q, nil
+}
+
+// eval returns the part of v matched by the query. v is encoded as JSON
+// first, so it can be any value.
+func (q *query) eval(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	var plural bool
+	values := []interface{}{root}
+	for _, s := range q.steps {
+		var next []interface{}
+		for _, v := range values {
+			switch s.kind {
+			case stepField:
+				switch v := v.(type) {
+				case map[string]interface{}:
+					if f, ok := lookupField(v, s.field); ok {
+						next = append(next, f)
+					}
+				case []interface{}:
+					plural = true
+					for _, e := range v {
+						if obj, ok := e.(map[string]interface{}); ok {
+							if f, ok := lookupField(obj, s.field); ok {
+								next = append(next, f)
+							}
+						}
+					}
+				}
+			case stepIndex:
+				if list, ok := v.([]interface{}); ok && s.index >= 0 && s.index < len(list) {
+					next = append(next, list[s.index])
+				}
+			case stepFilter:
+				plural = true
+				list, ok := v.([]interface{})
+				if !ok {
+					list = []interface{}{v}
+				}
+
+				for _, e := range list {
+					if obj, ok := e.(map[string]interface{}); ok {
+						if f, ok := lookupField(obj, s.field); ok && formatQueryValue(f) == s.value {
+							next = append(next, e)
+						}
+					}
+				}
+			case stepIter:
+				plural = true
+				if list, ok := v.([]interface{}); ok {
+					next = append(next, list...)
+				}
+			}
+		}
+		values = next
+	}
+
+	if plural {
+		if values == nil {
+			values = []interface{}{}
+		}
+		return values, nil
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values[0], nil
+}
+
+func lookupField(obj map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := obj[name]; ok {
+		return v, true
+	}
+
+	for k, v := range obj {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func formatQueryValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"strings"
+)
+
+var rawComments = flag.Bool("raw-comments", false, "include the original text of doc comments, with comment markers and directives, as DocRaw")
+
+// rawDoc returns the original text of the doc comment of the first of the
+// nodes that has one. It is empty unless raw comments are requested.
+func (s *Source) rawDoc(nodes ...ast.Node) string {
+	if !*rawComments {
+		return ""
+	}
+
+	if docs := s.docs(nodes...); len(docs) > 0 {
+		return rawText(docs[0])
+	}
+	return ""
+}
+
+// rawPackageDoc returns the original text of the package comments of all
+// the files, which, like go/doc does with their text, are concatenated.
+func (s *Source) rawPackageDoc() string {
+	if !*rawComments {
+		return ""
+	}
+
+	var texts []string
+	for _, f := range s.Files {
+		if doc := s.Docs[f]; doc != nil {
+			texts = append(texts, rawText(doc))
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func rawText(cg *ast.CommentGroup) string {
+	var lines = make([]string, len(cg.List))
+	for i, c := range cg.List {
+		lines[i] = c.Text
+	}
+	return strings.Join(lines, "\n")
+}
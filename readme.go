@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+var withReadme = flag.Bool("readme", false, "include the README of the package directory or, if it has none, of its module, as its Readme")
+
+var (
+	readmeFileRegexp = regexp.MustCompile(`(?i)^README(\.(md|markdown|txt))?$`)
+	// docIndexRegexp matches the files of doc directories that introduce
+	// the package as a README would.
+	docIndexRegexp = regexp.MustCompile(`(?i)^(README|index)\.(md|markdown)$`)
+)
+
+// addReadme sets the README of the package if requested.
+func addReadme(pkg *Pkg, srcDir string) {
+	if *withReadme {
+		pkg.Readme = findReadme(srcDir)
+	}
+}
+
+// findReadme returns the README of the given directory, either at its root
+// or in its doc directory, or else that of the module it belongs to, or nil
+// if there is none.
+func findReadme(dir string) *Readme {
+	if dir == "" {
+		return nil
+	}
+
+	dirs := []string{dir}
+	if root := moduleRoot(dir); root != "" && root != dir {
+		dirs = append(dirs, root)
+	}
+
+	for _, d := range dirs {
+		path := readmeFile(d, readmeFileRegexp)
+		if path == "" {
+			path = readmeFile(filepath.Join(d, "doc"), docIndexRegexp)
+		}
+
+		if path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			debugf("unable to read README %s: %s", path, err)
+			continue
+		}
+
+		return &Readme{File: relPath(path), Content: string(content)}
+	}
+	return nil
+}
+
+// readmeFile returns the path of the first file of the directory, by name,
+// matching the given regexp, or an empty string if there is none.
+func readmeFile(dir string, re *regexp.Regexp) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && re.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	// Files with an extension, such as README.md, come before a plain
+	// README, as Markdown is what doc portals render.
+	sort.Slice(names, func(i, j int) bool {
+		if mi, mj := filepath.Ext(names[i]) != "", filepath.Ext(names[j]) != ""; mi != mj {
+			return mi
+		}
+		return names[i] < names[j]
+	})
+	return filepath.Join(dir, names[0])
+}
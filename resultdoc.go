@@ -0,0 +1,120 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// resultSentenceRegexp matches the sentences mentioning a named
+	// result as "the name result" or "the name return value".
+	resultSentenceRegexp = regexp.MustCompile(`\b[Tt]he ` + "`?" + `(\w+)` + "`?" + ` (?:results?|return values?)\b`)
+	// returnsRegexp matches the sentences describing what a function
+	// returns, such as "It returns the number of bytes written."
+	returnsRegexp = regexp.MustCompile(`^(?:It|\w+) returns\b|^Returns\b`)
+	// errorNameRegexp matches the names of errors of other packages
+	// mentioned in comments, such as io.EOF or [fs.ErrNotExist].
+	errorNameRegexp = regexp.MustCompile(`^[a-z]\w*\.(?:Err\w*|EOF)$`)
+)
+
+// describeResults sets the Doc of the results described in the doc comment
+// of their function. Named results are described as parameters, in a list
+// or in a sentence mentioning "the name result". Otherwise, the first
+// sentence saying what the function returns describes its only result
+// that is not an error, and the first one saying when it returns an error
+// describes its error result.
+func describeResults(text string, results []*Field, errResult int) {
+	var named = make(map[string]*Field)
+	for _, r := range results {
+		if r.Name != "" && r.Name != "_" {
+			named[r.Name] = r
+		}
+	}
+
+	for name, desc := range listDescriptions(text, named) {
+		named[name].Doc = desc
+	}
+
+	var other *Field
+	for i, r := range results {
+		if i == errResult {
+			continue
+		}
+
+		if other != nil {
+			other = nil
+			break
+		}
+		other = r
+	}
+
+	for _, sentence := range docSentences(text) {
+		for _, m := range resultSentenceRegexp.FindAllStringSubmatch(sentence, -1) {
+			if r, ok := named[m[1]]; ok && r.Doc == "" {
+				r.Doc = sentence
+			}
+		}
+
+		if !returnsRegexp.MatchString(sentence) {
+			continue
+		}
+
+		if errResult >= 0 && strings.Contains(sentence, "error") {
+			if r := results[errResult]; r.Doc == "" {
+				r.Doc = sentence
+			}
+		} else if other != nil && other.Doc == "" {
+			other.Doc = sentence
+		}
+	}
+}
+
+// describeReturnedErrors sets the Errors of the functions and methods of a
+// package returning an error: those mentioned in their doc comments, either
+// errors of the package or sentinel errors of other packages, such as
+// io.EOF, with the sentence or list item mentioning them as their
+// description.
+func describeReturnedErrors(pkg *Pkg) {
+	var known = make(map[string]bool)
+	for _, e := range pkg.Errors {
+		known[e.Name] = true
+	}
+
+	for _, sym := range packageSymbols(pkg) {
+		fn := sym.Func
+		if fn == nil || !fn.ReturnsError {
+			continue
+		}
+
+		var seen = make(map[string]bool)
+		for _, sentence := range append(docSentences(fn.Doc), listItems(fn.Doc)...) {
+			for _, word := range strings.FieldsFunc(sentence, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.'
+			}) {
+				name := strings.Trim(word, ".")
+				if seen[name] || !known[name] && !errorNameRegexp.MatchString(name) {
+					continue
+				}
+
+				seen[name] = true
+				fn.Errors = append(fn.Errors, &ReturnedError{Name: name, Doc: sentence})
+			}
+		}
+	}
+}
+
+// listItemMarkerRegexp matches the markers of the items of lists.
+var listItemMarkerRegexp = regexp.MustCompile(`^\s+(?:[-*+•]|\d+[.)])\s+`)
+
+// listItems returns the text of the items of the lists of a comment,
+// without their markers.
+func listItems(text string) []string {
+	var items []string
+	for _, line := range strings.Split(text, "\n") {
+		if loc := listItemMarkerRegexp.FindStringIndex(line); loc != nil {
+			items = append(items, strings.TrimSpace(line[loc[1]:]))
+		}
+	}
+	return items
+}
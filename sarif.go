@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// sarifWriter writes the findings of the documentation linters of all the
+// packages as a single SARIF log, once they are all linted.
+type sarifWriter struct {
+	w       io.Writer
+	results []*sarifResult
+}
+
+func newSARIFWriter(w io.Writer, list bool) packageWriter {
+	return &sarifWriter{w: w, results: []*sarifResult{}}
+}
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name    string       `json:"name"`
+			Version string       `json:"version"`
+			Rules   []*sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId"`
+	Level     string           `json:"level"`
+	Message   sarifMessage     `json:"message"`
+	Locations []*sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn,omitempty"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+func (w *sarifWriter) Write(pkg *Pkg) error {
+	for _, f := range pkg.Lint {
+		r := &sarifResult{
+			RuleID:  f.Linter,
+			Level:   "warning",
+			Message: sarifMessage{f.Symbol + ": " + f.Message},
+		}
+
+		if f.Pos != nil && !isZeroPos(f.Pos.Start) {
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = f.Pos.Start.File
+			loc.PhysicalLocation.Region.StartLine = f.Pos.Start.Line
+			loc.PhysicalLocation.Region.StartColumn = f.Pos.Start.Column
+			r.Locations = []*sarifLocation{&loc}
+		}
+		w.results = append(w.results, r)
+	}
+	return nil
+}
+
+func (w *sarifWriter) Close() error {
+	run := &sarifRun{Results: w.results}
+	run.Tool.Driver.Name = "godocjson"
+	run.Tool.Driver.Version = generatorVersion()
+	for _, name := range enabledLinters {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{
+			ID:               name,
+			ShortDescription: sarifMessage{linterDescriptions[name]},
+		})
+	}
+
+	enc := json.NewEncoder(w.w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(&sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []*sarifRun{run},
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import "github.com/erizocosmico/godocjson/schema"
+
+// The documents generated are made of the types of the schema package, so
+// they can be read by other programs importing it.
+type (
+	Pkg             = schema.Pkg
+	Replacement     = schema.Replacement
+	DocSection      = schema.DocSection
+	Snippet         = schema.Snippet
+	Link            = schema.Link
+	Import          = schema.Import
+	File            = schema.File
+	ParseError      = schema.ParseError
+	Note            = schema.Note
+	Generator       = schema.Generator
+	Directive       = schema.Directive
+	Embed           = schema.Embed
+	Stats           = schema.Stats
+	Count           = schema.Count
+	GoVersion       = schema.GoVersion
+	LanguageFeature = schema.LanguageFeature
+	License         = schema.License
+	Readme          = schema.Readme
+	Dependency      = schema.Dependency
+	ModuleReplace   = schema.ModuleReplace
+	ModuleVersion   = schema.ModuleVersion
+	GitInfo         = schema.GitInfo
+	ErrorDecl       = schema.ErrorDecl
+	Embedding       = schema.Embedding
+	InheritedMethod = schema.InheritedMethod
+	ReturnedError   = schema.ReturnedError
+	Implementation  = schema.Implementation
+	Call            = schema.Call
+	LintFinding     = schema.LintFinding
+	PackageDocScore = schema.PackageDocScore
+
+	Value      = schema.Value
+	Type       = schema.Type
+	Func       = schema.Func
+	Field      = schema.Field
+	Enum       = schema.Enum
+	EnumMember = schema.EnumMember
+	IotaGroup  = schema.IotaGroup
+	IotaMember = schema.IotaMember
+	TypeUses   = schema.TypeUses
+	Metrics    = schema.Metrics
+	LineCount  = schema.LineCount
+	DocScore   = schema.DocScore
+	DeclToken  = schema.DeclToken
+	ASTNode    = schema.ASTNode
+	Example    = schema.Example
+
+	Pos     = schema.Pos
+	FilePos = schema.FilePos
+
+	Index       = schema.Index
+	IndexEntry  = schema.IndexEntry
+	IndexSymbol = schema.IndexSymbol
+)
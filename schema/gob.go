@@ -0,0 +1,32 @@
+package schema
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// GobReader reads the packages of a document generated in the gob format,
+// a stream of Pkg values, one at a time, so documents with many packages
+// do not need to be kept in memory.
+type GobReader struct {
+	dec *gob.Decoder
+}
+
+// NewGobReader returns a reader of the packages of the given document in
+// the gob format.
+func NewGobReader(r io.Reader) *GobReader {
+	return &GobReader{dec: gob.NewDecoder(r)}
+}
+
+// Next reads the next package, returning io.EOF if there are no more.
+func (r *GobReader) Next() (*Pkg, error) {
+	var pkg Pkg
+	if err := r.dec.Decode(&pkg); err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(&pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
@@ -0,0 +1,36 @@
+package schema
+
+// Index lists the packages written to a directory with -outdir.
+type Index struct {
+	GeneratorVersion string
+	Packages         []*IndexEntry
+	// Symbols maps the exported symbols of all the packages, qualified with
+	// their import path as in fully-qualified types, such as
+	// "example.com/foo.Type.Method", to where they are documented. It is
+	// only included if requested.
+	Symbols map[string]*IndexSymbol `json:",omitempty"`
+}
+
+// IndexSymbol is a symbol documented in a package of an index.
+type IndexSymbol struct {
+	ImportPath string
+	// Name is the name of the symbol, with methods as "Type.Method", which
+	// is also its ID in the pages rendered from the package.
+	Name string
+	Kind string
+	// File is the File of the package in the index.
+	File string
+}
+
+// IndexEntry is a package written to the directory of an index.
+type IndexEntry struct {
+	ImportPath string
+	Name       string
+	// Synopsis is the first sentence of the package comment.
+	Synopsis string
+	// File is the path of the file of the package, relative to the
+	// directory and using slashes.
+	File string
+	// Hash is the hex-encoded SHA-256 of the content of the file.
+	Hash string
+}
@@ -0,0 +1,420 @@
+package schema
+
+// Pkg is the documentation of a package, the document generated by
+// godocjson for each of them.
+type Pkg struct {
+	Doc string
+	// DocRaw is the original text of the comments Doc comes from, only
+	// included if requested, as with the DocRaw of symbols.
+	DocRaw string `json:",omitempty"`
+	// Snippets are the code blocks of Doc, without their indentation, as
+	// those of the comments of symbols.
+	Snippets []*Snippet `json:",omitempty"`
+	// Links are the URLs and link definitions of Doc, as those of the
+	// comments of symbols.
+	Links []*Link `json:",omitempty"`
+	// Translations are the translations of Doc from the overlay files,
+	// by locale, as those of symbols. They are only included if requested.
+	Translations map[string]string `json:",omitempty"`
+	// Deprecated is the deprecation notice of the package, if any, and
+	// Replacement the symbol it suggests using instead, if it could be
+	// resolved. Symbols have them too.
+	Deprecated  string       `json:",omitempty"`
+	Replacement *Replacement `json:",omitempty"`
+	// Sections are the sections of the package comment, starting at each
+	// of its headings, as a table of contents of long overviews.
+	Sections   []*DocSection `json:",omitempty"`
+	Name       string
+	ImportPath string
+	// PkgsiteURL is the URL of the documentation of the package in
+	// pkg.go.dev, as are those of its symbols. They are only included if
+	// requested.
+	PkgsiteURL string `json:",omitempty"`
+	Imports    []string
+	// ImportSpecs are all the import declarations of the package files.
+	ImportSpecs []*Import
+	// UsesUnsafe, UsesReflect and UsesCgo report whether the package
+	// imports unsafe, reflect or C.
+	UsesUnsafe  bool
+	UsesReflect bool
+	UsesCgo     bool
+	Filenames   []string
+	// Files are the metadata of each of the Filenames.
+	Files []*File
+	// ParseErrors are the errors of the files that could not be parsed,
+	// which are left out of the documentation.
+	ParseErrors []*ParseError `json:",omitempty"`
+
+	// Notes are the notes of the package, such as BUG(uid): comments, by
+	// their marker.
+	Notes map[string][]*Note
+
+	Bugs []string
+	// BugNotes are the Bugs with who reported them and where, the same as
+	// the BUG Notes.
+	BugNotes []*Note `json:",omitempty"`
+	// Generate are the //go:generate directives in the package files.
+	Generate []*Generator
+	// Directives are the directives of the package files that are not
+	// attached to a declaration, such as //go:build constraints.
+	Directives []*Directive
+	// Embeds are all the variables of the package populated with files
+	// through //go:embed directives, including unexported ones.
+	Embeds []*Embed
+
+	Stats *Stats
+	// GoVersion is the minimum Go version the package needs.
+	GoVersion *GoVersion
+
+	// Licenses are the license files of the module the package belongs to.
+	Licenses []*License
+	// Readme is the README of the package directory or, if it has none, of
+	// the module. It is only included if requested.
+	Readme *Readme `json:",omitempty"`
+	// Dependencies are the requirements of the module the package belongs
+	// to. They are only included if requested.
+	Dependencies []*Dependency `json:",omitempty"`
+	// Replaces are the replace directives of the go.mod file of the module,
+	// which apply to the imports of the package, and Excludes its exclude
+	// directives. Both are included even if Dependencies are not.
+	Replaces []*ModuleReplace `json:",omitempty"`
+	Excludes []*ModuleVersion `json:",omitempty"`
+	// Git is the revision of the repository the package is in. It is only
+	// included if requested.
+	Git *GitInfo `json:",omitempty"`
+
+	Consts []*Value
+	Types  []*Type
+	Vars   []*Value
+	Funcs  []*Func
+
+	// Errors are the sentinel errors and error types of the package.
+	Errors []*ErrorDecl
+	// Embeddings are the types embedded by the types of the package, in
+	// structs or interfaces, forming its composition hierarchy.
+	Embeddings []*Embedding
+	// Implementations are the types of the package implementing its
+	// interfaces. They are only included if requested.
+	Implementations []*Implementation `json:",omitempty"`
+	// CallGraph are the calls between the documented functions of the
+	// package. They are only included if requested.
+	CallGraph []*Call `json:",omitempty"`
+	// Examples are the examples of the package as a whole. Those of its
+	// symbols are attached to them. They are only included if requested.
+	Examples []*Example `json:",omitempty"`
+	// Lint are the findings of the linters enabled on the documentation of
+	// the symbols. They are only included if requested.
+	Lint []*LintFinding `json:",omitempty"`
+	// DocScore is the score of the documentation of the package, only
+	// included if requested, as are those of its symbols.
+	DocScore *PackageDocScore `json:",omitempty"`
+
+	GeneratorVersion string
+	// SchemaVersion is the Version of the schema the document follows.
+	SchemaVersion int
+	// Hash is the hex-encoded SHA-256 of the document, computed over its
+	// compact JSON encoding without the hash itself.
+	Hash string
+}
+
+// Replacement is the symbol a deprecation notice suggests using instead of
+// the deprecated one.
+type Replacement struct {
+	// ImportPath is the package declaring the symbol.
+	ImportPath string
+	// Name is the name of the symbol, with methods as "Type.Method".
+	Name string
+	// Kind is the kind of symbol, as in the symbols of the server. It is
+	// only known for symbols of the same package.
+	Kind string `json:",omitempty"`
+}
+
+// Snippet is a code block of a doc comment.
+type Snippet struct {
+	// Code is the code, without the indentation of the block.
+	Code string
+	// Language is the language the code seems to be in: go, json, shell
+	// or text.
+	Language string
+}
+
+// Link is a hyperlink of a doc comment, either a URL written in its text or
+// a link definition, such as "[Go]: https://go.dev".
+type Link struct {
+	URL string
+	// Text is the text of link definitions, such as "Go".
+	Text       string `json:",omitempty"`
+	Definition bool   `json:",omitempty"`
+}
+
+// DocSection is a section of a package comment.
+type DocSection struct {
+	Heading string
+	// ID is the ID of the heading in the pages rendered from the comment,
+	// as generated by go/doc/comment.
+	ID string
+	// Text is the text of the section, without the heading.
+	Text string
+}
+
+// Import is an import declaration.
+type Import struct {
+	Path string
+	// Name is the name the package is imported with, if any.
+	Name    string `json:",omitempty"`
+	IsBlank bool
+	IsDot   bool
+	// IsStd reports whether the package is in the standard library,
+	// IsInternal whether it is under an internal directory, so it can only
+	// be imported from the tree it is in, and IsVendored whether it is
+	// resolved to a vendor directory.
+	IsStd      bool
+	IsInternal bool
+	IsVendored bool
+	// Uses is the number of references to the package in the declarations
+	// of the file. Those of dot imports are only counted if types are
+	// resolved.
+	Uses int
+	Pos  *Pos
+}
+
+// File is a source file of a package.
+type File struct {
+	Name string
+	// Doc is the package comment in the file, if any.
+	Doc string `json:",omitempty"`
+	// BuildConstraint is the //go:build expression of the file. Legacy
+	// // +build lines are converted to the same syntax.
+	BuildConstraint string `json:",omitempty"`
+	// Imports are the import paths of the file, in the order they appear.
+	Imports []string
+	// Size is the size of the file in bytes and Hash the hex-encoded
+	// SHA-256 of its content.
+	Size        int
+	Hash        string
+	IsCgo       bool
+	IsGenerated bool
+}
+
+// ParseError is an error found parsing a file of a package.
+type ParseError struct {
+	Pos     *FilePos
+	Message string
+}
+
+// Note is a note of the form MARKER(uid): body in a comment of the package.
+type Note struct {
+	// UID is the user or issue the note is about.
+	UID  string
+	Body string
+	// Pos goes from the marker to the end of the body. It is nil in
+	// documents of version 1, whose notes only have offsets.
+	Pos *Pos
+}
+
+// Generator is a //go:generate directive.
+type Generator struct {
+	// Command is the command line to run, as written.
+	Command string
+	Pos     *Pos
+}
+
+// Directive is a comment directive, such as //go:noinline or //go:build.
+type Directive struct {
+	// Name is the name of the directive, such as "go:linkname".
+	Name string
+	Args string `json:",omitempty"`
+	Pos  *Pos
+}
+
+// Embed is a variable populated with files through //go:embed directives.
+type Embed struct {
+	Var      string
+	Patterns []string
+	Pos      *Pos
+}
+
+// Stats are counts of the declarations and source of a package.
+type Stats struct {
+	Files int
+	// Lines is the total number of lines of all the files.
+	Lines int
+
+	Funcs   *Count
+	Methods *Count
+	Types   *Count
+	Consts  *Count
+	Vars    *Count
+}
+
+// Count is a number of declarations, split by whether they are exported.
+type Count struct {
+	Exported   int
+	Unexported int
+}
+
+// GoVersion is the minimum Go version needed to build a package.
+type GoVersion struct {
+	// Directive is the go directive of the go.mod file of the module the
+	// package belongs to, if any.
+	Directive string `json:",omitempty"`
+	// Features are the language features used by the package that are not
+	// available in every Go version.
+	Features []*LanguageFeature
+	// Minimum is the highest of the go directive and the versions
+	// introducing the features used, e.g. "1.21".
+	Minimum string `json:",omitempty"`
+}
+
+// LanguageFeature is a language feature introduced in some Go version,
+// along with its first use in the package.
+type LanguageFeature struct {
+	Name    string
+	Version string
+	Pos     *FilePos
+}
+
+// License is a license file found at the root of the module.
+type License struct {
+	// SPDX is the SPDX identifier of the license, as guessed from its text.
+	// It is empty if the license could not be identified.
+	SPDX string
+	File string
+}
+
+// Readme is a README file of a package or module, at the root of its
+// directory or in its doc directory.
+type Readme struct {
+	File    string
+	Content string
+}
+
+// Dependency is a module required in the go.mod file of the module of a
+// package.
+type Dependency struct {
+	Path    string
+	Version string
+	// Indirect reports whether the requirement is marked as indirect, as
+	// those only needed by other dependencies are.
+	Indirect bool
+	// Imported reports whether the package imports any package of the
+	// module.
+	Imported bool
+}
+
+// ModuleReplace is a replace directive of a go.mod file.
+type ModuleReplace struct {
+	// Path and Version are the module replaced, in all its versions if
+	// Version is empty.
+	Path    string
+	Version string `json:",omitempty"`
+	// NewPath and NewVersion are the module it is replaced with. If it is
+	// replaced with a directory, NewVersion is empty and Dir is the
+	// directory.
+	NewPath    string
+	NewVersion string `json:",omitempty"`
+	Dir        string `json:",omitempty"`
+	// Imported reports whether the package imports any package of the
+	// replaced module.
+	Imported bool
+}
+
+// ModuleVersion is a version of a module.
+type ModuleVersion struct {
+	Path    string
+	Version string
+}
+
+// GitInfo describes the revision of the git repository a package is in.
+type GitInfo struct {
+	Commit string
+	// Tag is the tag pointing at Commit, if any.
+	Tag string `json:",omitempty"`
+	// Branch is empty if HEAD is detached.
+	Branch string `json:",omitempty"`
+	// Dirty reports whether there are uncommitted changes in the repository.
+	Dirty bool
+}
+
+// ErrorDecl is an error exposed by a package, either a sentinel variable
+// such as io.EOF or a type implementing the error interface.
+type ErrorDecl struct {
+	// Kind is "sentinel" or "type".
+	Kind string
+	Name string
+	Doc  string
+	// Message is the text of sentinels created with errors.New or
+	// fmt.Errorf from a string literal.
+	Message string `json:",omitempty"`
+	// PointerReceiver reports whether only pointers to an error type
+	// implement the error interface.
+	PointerReceiver bool `json:",omitempty"`
+	Pos             *Pos
+}
+
+// Embedding is a type of the package embedding another type, either as an
+// embedded field of a struct or as an embedded element of an interface.
+type Embedding struct {
+	// Type is the name of the embedding type.
+	Type string
+	// Embedded is the embedded type, qualified by the import path of its
+	// package unless it is predeclared, such as error, without any type
+	// arguments.
+	Embedded string
+	// Kind is "struct" or "interface", the kind of the embedding type.
+	Kind string
+	// Pointer reports whether a pointer to the type is embedded.
+	Pointer bool `json:",omitempty"`
+}
+
+// Implementation is a type of a package implementing one of its
+// interfaces, or a near miss: a type that has some of the methods of the
+// interface, but not all of them.
+type Implementation struct {
+	Type       string
+	Interface  string
+	Implements bool
+	// Pointer reports whether only pointers to the type implement the
+	// interface.
+	Pointer bool `json:",omitempty"`
+	// Missing are the methods of the interface the type lacks, or has
+	// with a different signature.
+	Missing []string `json:",omitempty"`
+}
+
+// Call is an edge of the call graph of a package, between two of its
+// documented functions or methods, named as in the symbols of the server.
+type Call struct {
+	Caller string
+	Callee string
+	// Dynamic reports whether the call is made through an interface, so
+	// Callee is only one of the methods that may be called.
+	Dynamic bool `json:",omitempty"`
+	// Indirect reports whether the call is made through unexported
+	// functions of the package.
+	Indirect bool `json:",omitempty"`
+	// Pos is the position of the call in the body of Caller.
+	Pos *FilePos
+}
+
+// LintFinding is a problem found by a linter in the documentation of a
+// symbol.
+type LintFinding struct {
+	Linter  string
+	Symbol  string
+	Message string
+	Pos     *Pos
+}
+
+// PackageDocScore is a measure of the quality of the documentation of a
+// package and all its symbols.
+type PackageDocScore struct {
+	// Score is the mean of the scores of the package comment and all the
+	// symbols.
+	Score int
+	// Doc is the score of the package comment, whose first sentence must
+	// start with "Package" followed by the package name.
+	Doc          *DocScore
+	Symbols      int
+	Undocumented int
+}
@@ -0,0 +1,15 @@
+package schema
+
+// Pos is the span of a declaration or any other node in its file.
+type Pos struct {
+	Start *FilePos
+	End   *FilePos
+}
+
+// FilePos is a position in a file. Lines and columns start at 1, and
+// files are relative to the root given with -path-base.
+type FilePos struct {
+	Line   int
+	Column int
+	File   string
+}
@@ -0,0 +1,146 @@
+// Package schema defines the documents generated by godocjson, so Go
+// programs can read them without copying their definitions.
+//
+// The documents follow the schema of the Version in their SchemaVersion.
+// Within a version, fields are only ever added, never renamed, removed or
+// changed to another type, so documents can be read by programs built
+// against older or newer releases of this package of the same version.
+// Documents generated before it was versioned have no SchemaVersion and
+// follow the first version. Documents of version 1 can still be read in
+// JSON, without the positions of their Notes, which changed incompatibly in
+// version 2, but not in the gob format.
+//
+// Fields are encoded with their Go names, so documents generated with
+// another -field-case cannot be read with this package.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Version is the version of the schema of the documents, which changes only
+// when they change incompatibly. Version 2 changed the positions of Notes
+// from offsets to file positions.
+const Version = 2
+
+// ReadPackage reads a document with the documentation of a single package.
+func ReadPackage(r io.Reader) (*Pkg, error) {
+	var data json.RawMessage
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	if err := checkFieldCase(data); err != nil {
+		return nil, err
+	}
+
+	var pkg Pkg
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(&pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// ReadPackages reads a document with the documentation of a list of
+// packages, as generated when documenting many of them, or a single one.
+func ReadPackages(r io.Reader) ([]*Pkg, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lists and single packages are told apart by their first byte, so
+	// the error decoding either is the one returned.
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '[' {
+		pkg, err := ReadPackage(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return []*Pkg{pkg}, nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	for _, item := range list {
+		if err := checkFieldCase(item); err != nil {
+			return nil, err
+		}
+	}
+
+	var pkgs []*Pkg
+	if err := json.Unmarshal(data, &pkgs); err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		if err := checkVersion(pkg); err != nil {
+			return nil, err
+		}
+	}
+	return pkgs, nil
+}
+
+// ReadIndex reads the index of the packages written to a directory with
+// -outdir.
+func ReadIndex(r io.Reader) (*Index, error) {
+	var index Index
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// checkFieldCase returns an error if the given package is not encoded with
+// the Go names of its fields. As encoding/json matches them ignoring case,
+// documents generated with another -field-case would otherwise be read as
+// mostly empty packages.
+func checkFieldCase(data []byte) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+
+	if _, ok := keys["Name"]; !ok {
+		return fmt.Errorf("package has no Name: documents generated with a -field-case other than pascal cannot be read")
+	}
+	return nil
+}
+
+func checkVersion(pkg *Pkg) error {
+	if pkg.SchemaVersion > Version {
+		return fmt.Errorf("package %s follows version %d of the schema, expecting up to %d", pkg.ImportPath, pkg.SchemaVersion, Version)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a note of any version of the schema. The offsets
+// notes had as their Pos and End in version 1 are dropped, as they cannot
+// be turned into file positions without the sources.
+func (n *Note) UnmarshalJSON(data []byte) error {
+	type note Note
+	var v struct {
+		*note
+		Pos json.RawMessage
+	}
+	v.note = (*note)(n)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	n.Pos = nil
+	if pos := bytes.TrimSpace(v.Pos); len(pos) > 0 && pos[0] == '{' {
+		n.Pos = new(Pos)
+		return json.Unmarshal(pos, n.Pos)
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package schema
+
+// Value is a declaration of constants or variables, which documents all the
+// names declared together in a group.
+type Value struct {
+	Kind        string
+	Doc         string
+	DocRaw      string       `json:",omitempty"`
+	Snippets    []*Snippet   `json:",omitempty"`
+	Links       []*Link      `json:",omitempty"`
+	Deprecated  string       `json:",omitempty"`
+	Replacement *Replacement `json:",omitempty"`
+	Since       string       `json:",omitempty"`
+	Names       []string
+	// PkgsiteURLs are the URLs of each of the names in pkg.go.dev, which
+	// are empty for unexported names.
+	PkgsiteURLs []string `json:",omitempty"`
+	Decl        string
+	// Decls are the declarations of each of the names on their own, only
+	// included if requested.
+	Decls []string `json:",omitempty"`
+	Pos   *Pos
+	// Generated reports whether the symbol is declared in a file with a
+	// header of generated code.
+	Generated bool      `json:",omitempty"`
+	DocScore  *DocScore `json:",omitempty"`
+
+	// Tokens are the tokens of Decl, only included if requested.
+	Tokens []*DeclToken `json:",omitempty"`
+	// AST is the syntax tree of the declaration, only included if
+	// requested.
+	AST *ASTNode `json:",omitempty"`
+
+	// NamePos are the positions of each of the names. They are null for
+	// unexported names hidden as _ in the Decl.
+	NamePos []*Pos
+
+	// Types are the types of each of the names, which are fully-qualified
+	// if types are resolved. Without type information, they are empty for
+	// values whose type cannot be inferred from the source.
+	Types []string `json:",omitempty"`
+
+	Directives []*Directive `json:",omitempty"`
+	// Embeds are the variables of the group populated with files through
+	// //go:embed directives.
+	Embeds []*Embed `json:",omitempty"`
+	// Iota is the sequence of constants of the group, if it uses iota.
+	Iota *IotaGroup `json:",omitempty"`
+	// Translations are the translations of Doc from the overlay files,
+	// by locale. They are only included if requested.
+	Translations map[string]string `json:",omitempty"`
+}
+
+// IotaGroup are the constants of a group using iota.
+type IotaGroup struct {
+	Members []*IotaMember
+	// Sequence reports whether the values of all the constants of the
+	// group, including unexported ones, are consecutive integers, and
+	// Bitmask whether they are distinct powers of two, with an optional
+	// zero, given by shifting by iota. At most one of them is true.
+	Sequence bool
+	Bitmask  bool
+}
+
+// IotaMember is a constant of a group using iota. Ordinal is the value of
+// iota in its spec and Value its computed value as a Go literal, which is
+// empty if it cannot be computed.
+type IotaMember struct {
+	Name    string
+	Ordinal int
+	Value   string `json:",omitempty"`
+}
+
+// Type is a type declaration, along with the constants, variables and
+// functions returning it, and its methods.
+type Type struct {
+	Kind        string
+	Doc         string
+	DocRaw      string       `json:",omitempty"`
+	Snippets    []*Snippet   `json:",omitempty"`
+	Links       []*Link      `json:",omitempty"`
+	Deprecated  string       `json:",omitempty"`
+	Replacement *Replacement `json:",omitempty"`
+	Since       string       `json:",omitempty"`
+	Name        string
+	PkgsiteURL  string `json:",omitempty"`
+	Decl        string
+	Pos         *Pos
+	// Generated reports whether the symbol is declared in a file with a
+	// header of generated code.
+	Generated bool `json:",omitempty"`
+	// Lines are the number of source lines of the declaration. They are
+	// always included.
+	Lines    *LineCount
+	DocScore *DocScore `json:",omitempty"`
+
+	// Tokens are the tokens of Decl, only included if requested.
+	Tokens []*DeclToken `json:",omitempty"`
+	// AST is the syntax tree of the declaration, only included if
+	// requested.
+	AST *ASTNode `json:",omitempty"`
+
+	// Fields are the exported fields of struct types.
+	Fields []*Field
+
+	Directives []*Directive `json:",omitempty"`
+	// Enum are the constants of the type, if it has any.
+	Enum *Enum `json:",omitempty"`
+
+	Consts  []*Value
+	Vars    []*Value
+	Funcs   []*Func
+	Methods []*Func
+	// Inherited are the exported methods the type gains by
+	// embedding types of other packages, which are not in Methods. They are
+	// only included if types are resolved.
+	Inherited []*InheritedMethod `json:",omitempty"`
+	// UsedBy are the functions and methods of the package accepting or
+	// returning the type.
+	UsedBy *TypeUses
+
+	Examples []*Example `json:",omitempty"`
+	// Translations are the translations of Doc from the overlay files,
+	// by locale. They are only included if requested.
+	Translations map[string]string `json:",omitempty"`
+}
+
+// Func is a function or method declaration.
+type Func struct {
+	Kind        string
+	Doc         string
+	DocRaw      string       `json:",omitempty"`
+	Snippets    []*Snippet   `json:",omitempty"`
+	Links       []*Link      `json:",omitempty"`
+	Deprecated  string       `json:",omitempty"`
+	Replacement *Replacement `json:",omitempty"`
+	Since       string       `json:",omitempty"`
+	Name        string
+	PkgsiteURL  string `json:",omitempty"`
+	Decl        string
+	// Tokens are the tokens of Decl, only included if requested.
+	Tokens []*DeclToken `json:",omitempty"`
+	// AST is the syntax tree of the declaration, including the body, only
+	// included if requested.
+	AST *ASTNode `json:",omitempty"`
+
+	Params     []*Field
+	Results    []*Field
+	IsVariadic bool
+
+	// ReturnsError reports whether any of the results is an error, in which
+	// case ErrorResult is its index in Results. Otherwise, it is -1.
+	ReturnsError bool
+	ErrorResult  int
+	// Errors are the errors the doc comment says the function returns,
+	// those of the package and sentinel errors of other packages, such as
+	// io.EOF.
+	Errors []*ReturnedError `json:",omitempty"`
+
+	Recv  string
+	Orig  string
+	Level int
+
+	Pos *Pos
+	// Generated reports whether the function is declared in a file with a
+	// header of generated code.
+	Generated bool `json:",omitempty"`
+	// Lines are the number of source lines of the declaration and of the
+	// body. They are always included.
+	Lines    *LineCount
+	DocScore *DocScore `json:",omitempty"`
+
+	Directives []*Directive `json:",omitempty"`
+	Metrics    *Metrics     `json:",omitempty"`
+	Examples   []*Example   `json:",omitempty"`
+	// Translations are the translations of Doc from the overlay files,
+	// by locale. They are only included if requested.
+	Translations map[string]string `json:",omitempty"`
+}
+
+// ReturnedError is an error a function returns, as its doc comment says.
+type ReturnedError struct {
+	// Name is the name of the error, qualified by the name of its package
+	// if it is declared in another one, such as "io.EOF".
+	Name string
+	// Doc is the sentence of the comment mentioning the error.
+	Doc string
+}
+
+// InheritedMethod is a method a type gains by embedding a type of another
+// package. Types are fully-qualified.
+type InheritedMethod struct {
+	Name string
+	// Package is the import path of the package declaring the method.
+	Package string
+	// Recv is the type declaring the method, and Embedded the embedded
+	// field of the type it is promoted through, which are the same unless
+	// the method is promoted through several embeddings. They are the
+	// embedded interface for methods of interfaces.
+	Recv     string
+	Embedded string
+	// Signature is the type of the method, without the receiver, such as
+	// "func()".
+	Signature string
+	// Level is the depth of the embedded field the method is promoted
+	// through, as the Level of Methods. It is 0 for interfaces, whose
+	// methods are all at the same level.
+	Level int `json:",omitempty"`
+	// Pointer reports whether only pointers to the type have the method.
+	Pointer bool `json:",omitempty"`
+}
+
+// Field is a function parameter, a function result or a struct field.
+type Field struct {
+	// Name is empty for unnamed parameters and results. For embedded struct
+	// fields, it is the name of the embedded type.
+	Name     string
+	Type     string
+	Embedded bool `json:",omitempty"`
+	// Tag is the tag of struct fields, unquoted, such as `json:"name"`.
+	Tag string `json:",omitempty"`
+	// Doc is the description of parameters and results in the doc comment
+	// of their function, if it has one.
+	Doc string `json:",omitempty"`
+	// IsVariadic is only set for the last parameter of variadic functions.
+	IsVariadic bool `json:",omitempty"`
+}
+
+// Enum are the constants declared with a named type, in the order they are
+// declared.
+type Enum struct {
+	Members []*EnumMember
+}
+
+// EnumMember is one of the constants of an enum. Value is the constant
+// value as a Go literal, which is empty if it cannot be computed.
+type EnumMember struct {
+	Name  string
+	Value string `json:",omitempty"`
+	Doc   string `json:",omitempty"`
+}
+
+// TypeUses are the functions and methods of the package whose signatures
+// mention a type, named as in the symbols of the server, e.g. "NewClient"
+// or "Client.Do". Those mentioning it in both their parameters and their
+// results are in both lists.
+type TypeUses struct {
+	Accepting []string
+	Returning []string
+}
+
+// Metrics are size and complexity measures of a function.
+type Metrics struct {
+	// Lines is the number of source lines from the declaration to the end of
+	// the body.
+	Lines int
+	// Statements is the number of statements in the body, including nested
+	// ones.
+	Statements int
+	// Complexity is the cyclomatic complexity of the function. Closures
+	// count towards the function they are declared in.
+	Complexity int
+}
+
+// LineCount is the number of source lines of a declaration.
+type LineCount struct {
+	// Decl is the number of lines from the start of the declaration to its
+	// end, including the body of functions.
+	Decl int
+	// Body is the number of lines of the body of functions, from brace to
+	// brace. It is zero for functions without a body and types.
+	Body int `json:",omitempty"`
+}
+
+// DocScore is a measure of the quality of the documentation of a symbol,
+// from a series of checks on its comment. Checks that do not apply to the
+// symbol, such as mentioning the parameters of functions without any, do not
+// count towards it.
+type DocScore struct {
+	// Score goes from 0, for undocumented symbols, to 100, for those
+	// passing all the checks.
+	Score int
+	// Failed are the checks the documentation does not pass: "missing",
+	// "name", "short", "long", "params" or "examples".
+	Failed []string `json:",omitempty"`
+}
+
+// DeclToken is a token of a declaration. Text between tokens, such as
+// spaces and operators, is not highlighted.
+type DeclToken struct {
+	// Kind is "keyword", "ident", "type", "string", "number" or "comment".
+	Kind string
+	// Offset and Len are the byte offset and length of the token in the
+	// Decl.
+	Offset int
+	Len    int
+}
+
+// ASTNode is a node of the syntax tree of a declaration.
+type ASTNode struct {
+	// Kind is the name of the type of the node in go/ast, such as
+	// "FuncDecl" or "Ident".
+	Kind string
+	// Value is the name of identifiers, the text of literals, or the
+	// keyword or operator of declarations, statements and expressions.
+	Value    string `json:",omitempty"`
+	Pos      *Pos
+	Children []*ASTNode `json:",omitempty"`
+}
+
+// Example is a testable example of a package, function, type or method.
+type Example struct {
+	// Name is the name of the example function without the Example
+	// prefix, e.g. "Buffer_Write_second".
+	Name string
+	// Suffix is the part of the name that distinguishes examples of the
+	// same symbol, e.g. "second".
+	Suffix string `json:",omitempty"`
+	Doc    string
+	Code   string
+	// Play is the example as a whole program that can be run, with the
+	// imports it needs, if it could be built.
+	Play string `json:",omitempty"`
+	// Trailing is the commentary after the last statement of the example,
+	// other than its output comment, which is left out of Code.
+	Trailing string `json:",omitempty"`
+	Output   string
+	// Unordered reports whether the output can be in any order.
+	Unordered bool `json:",omitempty"`
+	// EmptyOutput reports whether the example expects an empty output,
+	// rather than having no output comment.
+	EmptyOutput bool `json:",omitempty"`
+	Pos         *Pos
+}
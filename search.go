@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/doc"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/erizocosmico/godocjson/schema"
+)
+
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	corpusFile := fs.String("corpus", "", "document generated by godocjson to search, such as a merged corpus")
+	dir := fs.String("outdir", "", "directory written with -outdir to search, instead of a single document")
+	searchFormat := fs.String("format", "table", "output format: table or json")
+	kind := fs.String("kind", "", "only return symbols of this kind: const, var, func, type or method")
+	limit := fs.Int("limit", 20, "maximum number of symbols returned, or 0 for all of them")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson search -corpus corpus.json|-outdir dir query\n\n"+
+			"Prints the symbols matching the query, best matches first: those named as\n"+
+			"it, then those whose name starts with it, contains it or has its characters\n"+
+			"in the same order, and last those mentioning it in their documentation.\n\n")
+		fs.PrintDefaults()
+	}
+	rest := parseInterspersed(fs, args)
+	if len(rest) != 1 || (*corpusFile == "") == (*dir == "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *searchFormat != "table" && *searchFormat != "json" {
+		fatalf("invalid -format %q: expecting table or json", *searchFormat)
+	}
+
+	var (
+		pkgs []*Pkg
+		err  error
+	)
+	if *corpusFile != "" {
+		pkgs, err = loadPackages(*corpusFile)
+	} else {
+		pkgs, err = loadOutDir(*dir)
+	}
+	if err != nil {
+		fatalf("unable to load packages: %s", err)
+	}
+
+	var results = []*SearchResult{}
+	for _, sym := range newCorpus(pkgs).search(rest[0], *kind, *limit) {
+		results = append(results, &SearchResult{
+			ImportPath: sym.ImportPath,
+			Name:       sym.Name,
+			Kind:       sym.Kind,
+			Synopsis:   new(doc.Package).Synopsis(sym.Doc),
+			Pos:        sym.Pos,
+		})
+	}
+
+	if *searchFormat == "json" {
+		printJSON(results, nil)
+		return
+	}
+
+	if err := writeSearchTable(os.Stdout, results); err != nil {
+		fatalf("%s", err)
+	}
+}
+
+// SearchResult is a symbol found by the search command.
+type SearchResult struct {
+	ImportPath string
+	Name       string
+	Kind       string
+	// Synopsis is the first sentence of the documentation of the symbol.
+	Synopsis string
+	Pos      *Pos
+}
+
+func writeSearchTable(w io.Writer, results []*SearchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tKIND\tPACKAGE\tSYNOPSIS")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Name, r.Kind, r.ImportPath, r.Synopsis)
+	}
+	return tw.Flush()
+}
+
+// loadOutDir reads the packages written to the directory with -outdir,
+// which must be in the JSON format, from the files listed in its index.
+func loadOutDir(dir string) ([]*Pkg, error) {
+	f, err := os.Open(filepath.Join(dir, indexFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	index, err := schema.ReadIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index: %s", err)
+	}
+
+	var pkgs []*Pkg
+	for _, entry := range index.Packages {
+		if !strings.HasSuffix(entry.File, ".json") {
+			return nil, fmt.Errorf("%s is not in the JSON format", entry.File)
+		}
+
+		list, err := loadPackages(filepath.Join(dir, filepath.FromSlash(entry.File)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %s", entry.File, err)
+		}
+		pkgs = append(pkgs, list...)
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+)
+
+// SearchDocument is a flat document for search engines such as Meilisearch
+// and Typesense, for a package or one of its symbols. Its keys are in the
+// naming these engines expect.
+type SearchDocument struct {
+	// ID is derived from the import path and the name of the symbol, as
+	// import paths have characters not allowed in identifiers.
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Kind       string `json:"kind"`
+	ImportPath string `json:"import_path"`
+	Doc        string `json:"doc"`
+	// Weight ranks the document above those with a lower weight among
+	// results equally relevant.
+	Weight int `json:"weight"`
+}
+
+// searchWeights are the weights of the documents of each kind.
+var searchWeights = map[string]int{
+	"package": 10,
+	"type":    8,
+	"func":    6,
+	"method":  4,
+	"const":   3,
+	"var":     3,
+}
+
+// searchDocuments returns the search documents of the package and its
+// symbols. Deprecated symbols have the lowest weight.
+func searchDocuments(pkg *Pkg) []*SearchDocument {
+	path := pkg.ImportPath
+	if path == "" {
+		path = pkg.Name
+	}
+
+	var docs = []*SearchDocument{{
+		ID:         searchID(path),
+		Title:      path,
+		Kind:       "package",
+		ImportPath: pkg.ImportPath,
+		Doc:        packageDocText(pkg),
+		Weight:     searchWeights["package"],
+	}}
+
+	for _, sym := range packageSymbols(pkg) {
+		weight := searchWeights[sym.Kind]
+		if symbolDeprecated(sym) {
+			weight = 1
+		}
+
+		docs = append(docs, &SearchDocument{
+			ID:         searchID(path + "." + sym.Name),
+			Title:      pkg.Name + "." + sym.Name,
+			Kind:       sym.Kind,
+			ImportPath: pkg.ImportPath,
+			Doc:        sym.Doc,
+			Weight:     weight,
+		})
+	}
+	return docs
+}
+
+func searchID(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:16])
+}
+
+func symbolDeprecated(sym *Symbol) bool {
+	switch {
+	case sym.Type != nil:
+		return sym.Type.Deprecated != ""
+	case sym.Func != nil:
+		return sym.Func.Deprecated != ""
+	case sym.Value != nil:
+		return sym.Value.Deprecated != ""
+	}
+	return false
+}
+
+// meilisearchWriter writes the search documents of the packages as a JSON
+// array, as the documents endpoint of Meilisearch expects.
+type meilisearchWriter struct {
+	*jsonWriter
+}
+
+func newMeilisearchWriter(w io.Writer, list bool) packageWriter {
+	return &meilisearchWriter{newJSONWriter(w, true)}
+}
+
+func (w *meilisearchWriter) Write(pkg *Pkg) error {
+	for _, d := range searchDocuments(pkg) {
+		if err := w.WriteValue(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typesenseWriter writes the search documents of the packages as JSON
+// lines, as the import endpoint of Typesense expects.
+type typesenseWriter struct {
+	w *bufio.Writer
+}
+
+func newTypesenseWriter(w io.Writer, list bool) packageWriter {
+	return &typesenseWriter{bufio.NewWriter(w)}
+}
+
+func (w *typesenseWriter) Write(pkg *Pkg) error {
+	enc := json.NewEncoder(w.w)
+	for _, d := range searchDocuments(pkg) {
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *typesenseWriter) Close() error {
+	return w.w.Flush()
+}
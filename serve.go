@@ -0,0 +1,758 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Symbol is a top-level declaration of a package or a method. Values
+// declared together in a group are a symbol each. Methods are named after
+// their receiver, e.g. "Buffer.Write".
+type Symbol struct {
+	ImportPath string
+	Name       string
+	// Kind is one of "const", "var", "func", "type" or "method".
+	Kind string
+	Doc  string
+	Decl string
+	Pos  *Pos
+
+	// Only the one matching the kind of symbol is set.
+	Type  *Type  `json:",omitempty"`
+	Func  *Func  `json:",omitempty"`
+	Value *Value `json:",omitempty"`
+}
+
+// packageSymbols returns all the symbols of the package, in the order they
+// are documented.
+func packageSymbols(pkg *Pkg) []*Symbol {
+	var symbols []*Symbol
+	values := func(kind string, list []*Value) {
+		for _, v := range list {
+			for i, name := range v.Names {
+				pos := v.Pos
+				if i < len(v.NamePos) && v.NamePos[i] != nil {
+					pos = v.NamePos[i]
+				}
+
+				symbols = append(symbols, &Symbol{
+					ImportPath: pkg.ImportPath,
+					Name:       name,
+					Kind:       kind,
+					Doc:        v.Doc,
+					Decl:       v.Decl,
+					Pos:        pos,
+					Value:      v,
+				})
+			}
+		}
+	}
+	funcs := func(kind, prefix string, list []*Func) {
+		for _, f := range list {
+			symbols = append(symbols, &Symbol{
+				ImportPath: pkg.ImportPath,
+				Name:       prefix + f.Name,
+				Kind:       kind,
+				Doc:        f.Doc,
+				Decl:       f.Decl,
+				Pos:        f.Pos,
+				Func:       f,
+			})
+		}
+	}
+
+	values("const", pkg.Consts)
+	values("var", pkg.Vars)
+	funcs("func", "", pkg.Funcs)
+	for _, t := range pkg.Types {
+		symbols = append(symbols, &Symbol{
+			ImportPath: pkg.ImportPath,
+			Name:       t.Name,
+			Kind:       "type",
+			Doc:        t.Doc,
+			Decl:       t.Decl,
+			Pos:        t.Pos,
+			Type:       t,
+		})
+		values("const", t.Consts)
+		values("var", t.Vars)
+		funcs("func", "", t.Funcs)
+		funcs("method", t.Name+".", t.Methods)
+	}
+	return symbols
+}
+
+// corpus is the documentation of a set of packages, indexed to be queried.
+type corpus struct {
+	pkgs    []*Pkg
+	byPath  map[string]*Pkg
+	symbols map[string][]*Symbol
+	// refs are the symbols whose declaration refers to each symbol.
+	refs map[symbolKey][]*Symbol
+	// importedBy are the packages of the corpus importing each package.
+	importedBy map[string][]*Pkg
+	// hash is derived from the hashes of all the documents, which change
+	// whenever their sources do, and modTime is when the corpus was built.
+	hash    string
+	modTime time.Time
+
+	schema *gqlSchema
+}
+
+type symbolKey struct {
+	importPath string
+	name       string
+}
+
+func newCorpus(pkgs []*Pkg) *corpus {
+	c := &corpus{
+		pkgs:       pkgs,
+		byPath:     make(map[string]*Pkg),
+		symbols:    make(map[string][]*Symbol),
+		refs:       make(map[symbolKey][]*Symbol),
+		importedBy: make(map[string][]*Pkg),
+		modTime:    time.Now().Truncate(time.Second),
+	}
+
+	var hashes []string
+	for _, pkg := range pkgs {
+		c.byPath[pkg.ImportPath] = pkg
+		c.symbols[pkg.ImportPath] = packageSymbols(pkg)
+		hashes = append(hashes, pkg.ImportPath+" "+pkg.Hash)
+	}
+	sort.Strings(hashes)
+	c.hash = sourceHash([]byte(strings.Join(hashes, "\n")))
+
+	for _, pkg := range pkgs {
+		for _, imp := range pkg.Imports {
+			c.importedBy[imp] = append(c.importedBy[imp], pkg)
+		}
+		c.indexReferences(pkg)
+	}
+
+	c.schema = c.newSchema()
+	return c
+}
+
+// indexReferences records the symbols of the corpus referred to by the
+// declarations of the symbols of pkg. References are found by name, so
+// fields and methods are not taken into account.
+func (c *corpus) indexReferences(pkg *Pkg) {
+	var (
+		imports = make(map[string]string)
+		scope   = []string{pkg.ImportPath}
+	)
+
+	for _, spec := range pkg.ImportSpecs {
+		switch {
+		case spec.IsBlank:
+		case spec.IsDot:
+			scope = append(scope, spec.Path)
+		case spec.Name != "":
+			imports[spec.Name] = spec.Path
+		case c.byPath[spec.Path] != nil:
+			imports[c.byPath[spec.Path].Name] = spec.Path
+		default:
+			imports[path.Base(spec.Path)] = spec.Path
+		}
+	}
+
+	declared := func(importPath, name string) bool {
+		for _, s := range c.symbols[importPath] {
+			if s.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, sym := range c.symbols[pkg.ImportPath] {
+		seen := make(map[symbolKey]bool)
+		for _, ref := range declReferences(sym.Decl) {
+			var key symbolKey
+			if ref.qualifier != "" {
+				importPath, ok := imports[ref.qualifier]
+				if !ok || !declared(importPath, ref.name) {
+					continue
+				}
+				key = symbolKey{importPath, ref.name}
+			} else {
+				for _, importPath := range scope {
+					if declared(importPath, ref.name) {
+						key = symbolKey{importPath, ref.name}
+						break
+					}
+				}
+			}
+
+			if key.name == "" || seen[key] || key == (symbolKey{sym.ImportPath, sym.Name}) {
+				continue
+			}
+			seen[key] = true
+			c.refs[key] = append(c.refs[key], sym)
+		}
+	}
+}
+
+type declReference struct {
+	qualifier string
+	name      string
+}
+
+// declReferences returns the identifiers, qualified or not, used in the
+// given declaration, excluding the names it declares.
+func declReferences(decl string) []declReference {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+decl, 0)
+	if err != nil {
+		return nil
+	}
+
+	declaring := make(map[*ast.Ident]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.File:
+			declaring[n.Name] = true
+		case *ast.FuncDecl:
+			declaring[n.Name] = true
+		case *ast.TypeSpec:
+			declaring[n.Name] = true
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				declaring[name] = true
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				declaring[name] = true
+			}
+		case *ast.KeyValueExpr:
+			if id, ok := n.Key.(*ast.Ident); ok {
+				declaring[id] = true
+			}
+		}
+		return true
+	})
+
+	var refs []declReference
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok {
+				refs = append(refs, declReference{qualifier: x.Name, name: n.Sel.Name})
+				return false
+			}
+		case *ast.Ident:
+			if !declaring[n] {
+				refs = append(refs, declReference{name: n.Name})
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+// search returns the symbols whose name or documentation contain the given
+// text, ignoring case. Exact matches of the name come first, then names
+// starting with the text, then names containing it, then names with all its
+// characters in the same order, such as "Buffer.Write" for "bufwr", with
+// the closest ones first, and last the symbols that only mention it in
+// their documentation.
+func (c *corpus) search(text, kind string, limit int) []*Symbol {
+	type match struct {
+		sym  *Symbol
+		rank int
+		// spread is how far apart the characters of fuzzy matches are.
+		spread int
+	}
+
+	text = strings.ToLower(text)
+	var matches []match
+	for _, pkg := range c.pkgs {
+		for _, sym := range c.symbols[pkg.ImportPath] {
+			if kind != "" && sym.Kind != kind {
+				continue
+			}
+
+			name := strings.ToLower(sym.Name)
+			rank, spread := -1, 0
+			switch {
+			case name == text:
+				rank = 0
+			case strings.HasPrefix(name, text):
+				rank = 1
+			case strings.Contains(name, text):
+				rank = 2
+			case fuzzyMatch(name, text, &spread):
+				rank = 3
+			case strings.Contains(strings.ToLower(sym.Doc), text):
+				rank = 4
+			}
+
+			if rank >= 0 {
+				matches = append(matches, match{sym, rank, spread})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].spread < matches[j].spread
+	})
+
+	var result = []*Symbol{}
+	for _, m := range matches {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		result = append(result, m.sym)
+	}
+	return result
+}
+
+// fuzzyMatch reports whether all the characters of text are in s in the
+// same order, setting spread to the number of other characters between the
+// first and the last of them.
+func fuzzyMatch(s, text string, spread *int) bool {
+	want := []rune(text)
+	if len(want) == 0 {
+		return false
+	}
+
+	first, i := -1, 0
+	for j, r := range []rune(s) {
+		if r != want[i] {
+			continue
+		}
+
+		if first < 0 {
+			first = j
+		}
+
+		if i++; i == len(want) {
+			*spread = j - first + 1 - len(want)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *corpus) symbol(importPath, name string) *Symbol {
+	for _, s := range c.symbols[importPath] {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// queryRoot is the root object of GraphQL queries.
+type queryRoot struct {
+	corpus *corpus
+}
+
+// newSchema returns the GraphQL schema of the corpus, which is:
+//
+//	type Query {
+//		packages: [Package!]!
+//		package(importPath: String!): Package
+//		symbol(importPath: String!, name: String!): Symbol
+//		search(text: String!, kind: String, limit: Int = 20): [Symbol!]!
+//		references(importPath: String!, name: String!): [Symbol!]!
+//	}
+//
+// Package and Symbol have the fields of Pkg and Symbol, and also:
+//
+//	type Package {
+//		symbols(kind: String, prefix: String): [Symbol!]!
+//		symbol(name: String!): Symbol
+//		importedBy: [Package!]!
+//	}
+//
+//	type Symbol {
+//		package: Package
+//		references: [Symbol!]!
+//	}
+func (c *corpus) newSchema() *gqlSchema {
+	s := newGQLSchema()
+	s.object(new(queryRoot), "Query")
+	s.object(new(Pkg), "Package")
+
+	s.field(new(queryRoot), "packages", func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+		return c.pkgs, nil
+	})
+	s.field(new(queryRoot), "package", func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+		importPath, err := stringArg(args, "importPath")
+		if err != nil {
+			return nil, err
+		}
+		return c.byPath[importPath], nil
+	})
+	s.field(new(queryRoot), "symbol", func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+		key, err := symbolArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return c.symbol(key.importPath, key.name), nil
+	})
+	s.field(new(queryRoot), "search", func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+		text, err := stringArg(args, "text")
+		if err != nil {
+			return nil, err
+		}
+
+		kind, _ := args["kind"].(string)
+		limit, err := intArg(args, "limit", 20)
+		if err != nil {
+			return nil, err
+		}
+		return c.search(text, kind, limit), nil
+	})
+	s.field(new(queryRoot), "references", func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+		key, err := symbolArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return c.references(key), nil
+	})
+
+	s.field(new(Pkg), "symbols", func(obj interface{}, args map[string]interface{}) (interface{}, error) {
+		var kinds []string
+		if kind, _ := args["kind"].(string); kind != "" {
+			kinds = []string{kind}
+		}
+
+		prefix, _ := args["prefix"].(string)
+		return filterSymbols(c.symbols[obj.(*Pkg).ImportPath], kinds, prefix), nil
+	})
+	s.field(new(Pkg), "symbol", func(obj interface{}, args map[string]interface{}) (interface{}, error) {
+		name, err := stringArg(args, "name")
+		if err != nil {
+			return nil, err
+		}
+		return c.symbol(obj.(*Pkg).ImportPath, name), nil
+	})
+	s.field(new(Pkg), "importedBy", func(obj interface{}, args map[string]interface{}) (interface{}, error) {
+		pkgs := c.importedBy[obj.(*Pkg).ImportPath]
+		if pkgs == nil {
+			pkgs = []*Pkg{}
+		}
+		return pkgs, nil
+	})
+
+	s.field(new(Symbol), "package", func(obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return c.byPath[obj.(*Symbol).ImportPath], nil
+	})
+	s.field(new(Symbol), "references", func(obj interface{}, args map[string]interface{}) (interface{}, error) {
+		sym := obj.(*Symbol)
+		return c.references(symbolKey{sym.ImportPath, sym.Name}), nil
+	})
+
+	return s
+}
+
+func (c *corpus) references(key symbolKey) []*Symbol {
+	refs := c.refs[key]
+	if refs == nil {
+		refs = []*Symbol{}
+	}
+	return refs
+}
+
+func stringArg(args map[string]interface{}, name string) (string, error) {
+	v, ok := args[name].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %q of type String is required", name)
+	}
+	return v, nil
+}
+
+func intArg(args map[string]interface{}, name string, def int) (int, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+
+	n, ok := v.(float64)
+	if !ok || n != float64(int(n)) {
+		return 0, fmt.Errorf("argument %q must be an Int", name)
+	}
+	return int(n), nil
+}
+
+func symbolArgs(args map[string]interface{}) (symbolKey, error) {
+	importPath, err := stringArg(args, "importPath")
+	if err != nil {
+		return symbolKey{}, err
+	}
+
+	name, err := stringArg(args, "name")
+	if err != nil {
+		return symbolKey{}, err
+	}
+	return symbolKey{importPath, name}, nil
+}
+
+// maxGraphQLRequest is the maximum size of the body of GraphQL requests.
+const maxGraphQLRequest = 1 << 20
+
+// ServeHTTP serves GraphQL queries sent either as the query string of a
+// GET request or in the JSON body of a POST request. Responses have an ETag
+// derived from the corpus and the request, so clients polling for the same
+// query get a 304 until the documentation changes.
+func (c *corpus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req gqlRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGraphQLRequest))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, fmt.Sprintf("request larger than %d bytes", maxGraphQLRequest), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			req.Query = string(body)
+		} else if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	etag := c.etag(&req)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", c.modTime.UTC().Format(http.TimeFormat))
+	if notModified(r, etag, c.modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	resp := executeGraphQL(r.Context(), c.schema, &queryRoot{corpus: c}, &req)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		debugf("unable to write response: %s", err)
+	}
+}
+
+// etag returns the entity tag of the response to the request, which only
+// depends on the documents of the corpus and the request itself.
+func (c *corpus) etag(req *gqlRequest) string {
+	// Variables are a map, so they are always encoded in the same order.
+	data, _ := json.Marshal(req)
+	return `"` + sourceHash(append([]byte(c.hash+"\n"), data...)) + `"`
+}
+
+// notModified reports whether the client already has the response with the
+// given entity tag, or one last modified at the given time, according to
+// the If-None-Match and If-Modified-Since headers of the request. As in RFC
+// 9110, If-Modified-Since is ignored when If-None-Match is given.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modTime.After(since)
+}
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	corsOrigins := fs.String("cors-origin", "", "comma-separated origins allowed to call the server from a browser, or * for any")
+	tokenFile := fs.String("token-file", "", "file with the bearer token requests must be authorized with")
+	fs.BoolVar(resolveTypes, "resolve-types", false, "type-check packages to resolve the types of values")
+	fs.StringVar(cacheDir, "cache-dir", "", "directory where the documentation of each package is cached between runs")
+	watch := fs.Bool("watch", false, "regenerate the documentation when the sources change, notifying it to the WebSocket clients of /events")
+	addEnvFlags(fs)
+	fs.IntVar(concurrency, "concurrency", 0, concurrencyUsage)
+	fs.IntVar(concurrency, "j", 0, concurrencyUsage)
+	requestTimeout := fs.Duration("request-timeout", 30*time.Second, "maximum time to serve a request, or 0 for no limit")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 30*time.Second, "maximum time to wait for the requests in progress after receiving SIGTERM or SIGINT")
+	maxExtractions := fs.Int("max-extractions", 0, "maximum number of packages documented at the same time, or 0 for no limit other than -concurrency")
+	rateLimit := fs.Float64("rate-limit", 0, "maximum requests per second from all the clients together, or 0 for no limit")
+	clientRateLimit := fs.Float64("client-rate-limit", 0, "maximum requests per second from each client, told apart by their IP address and token, or 0 for no limit")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: godocjson serve [-addr host:port] [-watch] [packages]\n\n"+
+			"Serves the documentation of the given packages, ./... by default, through a\n"+
+			"GraphQL endpoint at /graphql, the symbol declared at a position of a file at\n"+
+			"/lookup?file=path&line=n&column=n, the symbols of a package in pages at\n"+
+			"/symbols?importPath=path, and the metrics of the server at /metrics.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyEnvFlags(); err != nil {
+		fatalf("%s", err)
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	if *maxExtractions < 0 {
+		fatalf("invalid -max-extractions %d: expecting a number greater than or equal to 0", *maxExtractions)
+	}
+
+	if *rateLimit < 0 || *clientRateLimit < 0 {
+		fatalf("invalid -rate-limit or -client-rate-limit: expecting a number greater than or equal to 0")
+	}
+
+	if *maxExtractions > 0 {
+		extractionSlots = make(chan struct{}, *maxExtractions)
+	}
+
+	pkgNames, err := expandPatterns(patterns)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	// The packages being documented when the server is asked to stop are
+	// finished before doing it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Sources are checked before generating their documentation, so changes
+	// made while doing it are not missed.
+	var fingerprints = make(map[string]string)
+	if *watch {
+		for _, name := range pkgNames {
+			if fingerprints[name], err = packageFingerprint(name); err != nil {
+				fatalf("%s", err)
+			}
+		}
+	}
+
+	var pkgs []*Pkg
+	err = extractAll(ctx, pkgNames, func(pkg *Pkg) error {
+		pkgs = append(pkgs, pkg)
+		return nil
+	})
+	if ctx.Err() != nil {
+		infof("stopped before serving")
+		return
+	}
+
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	token, err := readToken(*tokenFile)
+	if err != nil {
+		fatalf("unable to read token: %s", err)
+	}
+
+	c := newCorpus(pkgs)
+	var (
+		handler http.Handler = c
+		current              = func() *corpus { return c }
+	)
+	mux := http.NewServeMux()
+	var (
+		hub         *eventHub
+		watcherDone = make(chan struct{})
+	)
+	if *watch {
+		w := &watcher{
+			patterns:     patterns,
+			pkgs:         make(map[string]*Pkg),
+			fingerprints: fingerprints,
+			corpus:       &liveCorpus{corpus: c},
+			hub:          newEventHub(*corsOrigins),
+		}
+		for i, pkg := range pkgs {
+			w.pkgs[pkgNames[i]] = pkg
+		}
+
+		handler = w.corpus
+		current = w.corpus.get
+		hub = w.hub
+		mux.Handle("/events", newAuthHandler(w.hub, token))
+		go func() {
+			defer close(watcherDone)
+			w.run(ctx)
+		}()
+	} else {
+		close(watcherDone)
+	}
+
+	// The rate limits are shared by all the endpoints serving documentation.
+	limiter := newRateLimiter(*rateLimit, *clientRateLimit)
+	api := func(h http.Handler) http.Handler {
+		return stats.instrument(newTimeoutHandler(newCORSHandler(newAuthHandler(limiter.handler(h), token), *corsOrigins), *requestTimeout))
+	}
+	mux.Handle("/graphql", api(handler))
+	mux.Handle("/lookup", api(lookupHandler(current)))
+	mux.Handle("/symbols", api(symbolsHandler(current)))
+	mux.Handle("/metrics", newAuthHandler(stats, token))
+
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	infof("serving the documentation of %d packages at http://%s/graphql", len(pkgs), *addr)
+	select {
+	case err := <-errc:
+		fatalf("%s", err)
+	case <-ctx.Done():
+	}
+
+	infof("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	// Events clients are hijacked connections, which Shutdown does not
+	// close.
+	if hub != nil {
+		hub.close()
+	}
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errorf("unable to finish the requests in progress: %s", err)
+	}
+	<-watcherDone
+}
+
+// extractionSlots bounds the number of packages documented at the same
+// time, if set.
+var extractionSlots chan struct{}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	withSince = flag.Bool("since", false, "find the first release tag of the git repository in which each exported symbol appeared")
+	sinceAPI  = flag.String("since-api", "", "directory of API summary files named after the version that added their symbols, e.g. go1.21.txt, to find when each symbol appeared instead of the git history")
+)
+
+// releaseTagRegexp matches the tags of module releases, which are prefixed
+// with the directory of the module if it is not at the root of the
+// repository.
+var releaseTagRegexp = regexp.MustCompile(`^(.*/)?v[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// addSince sets the Since of every symbol of the package if requested.
+func addSince(pkg *Pkg, srcDir string) {
+	var since map[string]string
+	switch {
+	case *sinceAPI != "":
+		since = apiSince(*sinceAPI)[pkg.ImportPath]
+	case *withSince && srcDir != "":
+		since = gitSince(pkg.Name, srcDir)
+	default:
+		return
+	}
+
+	for _, v := range append(pkg.Consts, pkg.Vars...) {
+		v.Since = valueSince(v, since)
+	}
+
+	for _, f := range pkg.Funcs {
+		f.Since = since[f.Name]
+	}
+
+	for _, t := range pkg.Types {
+		t.Since = since[t.Name]
+		for _, v := range append(t.Consts, t.Vars...) {
+			v.Since = valueSince(v, since)
+		}
+
+		for _, f := range t.Funcs {
+			f.Since = since[f.Name]
+		}
+
+		for _, m := range t.Methods {
+			m.Since = since[t.Name+"."+m.Name]
+		}
+	}
+}
+
+// valueSince returns the earliest version in which any of the names of the
+// value appeared.
+func valueSince(v *Value, since map[string]string) string {
+	var first string
+	for _, name := range v.Names {
+		if s := since[name]; s != "" && (first == "" || compareVersions(s, first) < 0) {
+			first = s
+		}
+	}
+	return first
+}
+
+// gitSince returns the first release tag in which each exported symbol of
+// the package in srcDir appeared. Symbols that were never released are
+// not included.
+func gitSince(pkgName, srcDir string) map[string]string {
+	root, dir, prefix, ok := sinceRepository(srcDir)
+	if !ok {
+		return nil
+	}
+
+	var since = make(map[string]string)
+	for _, tag := range releaseTags(root, prefix) {
+		for name := range revisionSymbols(root, tag, dir, pkgName) {
+			if _, ok := since[name]; !ok {
+				since[name] = strings.TrimPrefix(tag, prefix)
+			}
+		}
+	}
+	return since
+}
+
+// sinceRepository returns the root of the git repository of srcDir, the
+// directory relative to it, with slashes, and the prefix of the release
+// tags of its module, as tags of modules in subdirectories are prefixed
+// with their path.
+func sinceRepository(srcDir string) (root, dir, prefix string, ok bool) {
+	root, err := git(srcDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		tracef("%s is not inside a git repository: %s", srcDir, err)
+		return "", "", "", false
+	}
+
+	dir, err = filepath.Rel(root, srcDir)
+	if err != nil {
+		debugf("unable to find %s in the repository: %s", srcDir, err)
+		return "", "", "", false
+	}
+
+	if mod := moduleRoot(srcDir); mod != "" {
+		if rel, err := filepath.Rel(root, mod); err == nil && rel != "." {
+			prefix = filepath.ToSlash(rel) + "/"
+		}
+	}
+	return root, filepath.ToSlash(dir), prefix, true
+}
+
+var releaseTagLists = struct {
+	sync.Mutex
+	m map[string][]string
+}{m: make(map[string][]string)}
+
+// releaseTags returns the release tags with the given prefix of the
+// repository at root, from the oldest version to the newest.
+func releaseTags(root, prefix string) []string {
+	releaseTagLists.Lock()
+	defer releaseTagLists.Unlock()
+	key := root + "\x00" + prefix
+	if tags, ok := releaseTagLists.m[key]; ok {
+		return tags
+	}
+
+	out, err := git(root, "tag", "--list", "--sort=version:refname", prefix+"v*")
+	if err != nil {
+		debugf("unable to list the tags of %s: %s", root, err)
+	}
+
+	var tags []string
+	for _, tag := range strings.Fields(out) {
+		if m := releaseTagRegexp.FindStringSubmatch(tag); m != nil && m[1] == prefix {
+			tags = append(tags, tag)
+		}
+	}
+
+	releaseTagLists.m[key] = tags
+	return tags
+}
+
+// revisionTrees are the exported symbols of the Go files of the revisions
+// of repositories read so far, by the root of the repository and the
+// revision, so the tree of each release is read once for all of its
+// packages.
+var revisionTrees = struct {
+	sync.Mutex
+	m map[string]*revisionTree
+}{m: make(map[string]*revisionTree)}
+
+type revisionTree struct {
+	once sync.Once
+	// symbols are the names of the exported symbols of the files of each
+	// directory, with slashes, by the directory and the package name of
+	// the files.
+	symbols map[string]map[string]map[string]bool
+}
+
+// revisionSymbols returns the names of the exported symbols of the package
+// with the given name in the directory of the repository at the given
+// revision, with methods as "Type.Method".
+func revisionSymbols(root, rev, dir, pkgName string) map[string]bool {
+	key := root + "\x00" + rev
+	revisionTrees.Lock()
+	t, ok := revisionTrees.m[key]
+	if !ok {
+		t = new(revisionTree)
+		revisionTrees.m[key] = t
+	}
+	revisionTrees.Unlock()
+
+	t.once.Do(func() {
+		var err error
+		if t.symbols, err = readRevisionSymbols(root, rev); err != nil {
+			debugf("unable to read %s at %s: %s", root, rev, err)
+		}
+	})
+	return t.symbols[dir][pkgName]
+}
+
+// readRevisionSymbols reads the exported symbols of all the non-test Go
+// files of the repository at the given revision, with a single git
+// cat-file for all of them, by their directory and package name.
+func readRevisionSymbols(root, rev string) (map[string]map[string]map[string]bool, error) {
+	out, err := git(root, "ls-tree", "-r", "-z", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	var names, objects []string
+	for _, entry := range strings.Split(out, "\x00") {
+		// Entries are "mode type object\tpath".
+		info, name, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 || fields[1] != "blob" ||
+			!strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		names = append(names, name)
+		objects = append(objects, fields[2])
+	}
+
+	var symbols = make(map[string]map[string]map[string]bool)
+	if len(names) == 0 {
+		return symbols, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(strings.Join(objects, "\n") + "\n")
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	err = addRevisionSymbols(symbols, bufio.NewReader(stdout), rev, names)
+	// The rest of the output is discarded so git is not blocked writing it.
+	io.Copy(io.Discard, stdout)
+	if werr := cmd.Wait(); werr != nil && err == nil {
+		err = werr
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = &gitError{werr, msg}
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// addRevisionSymbols reads the files with the given names from the output
+// of git cat-file --batch, adding the exported symbols of those matching
+// the build constraints to the symbols of their directory and package.
+func addRevisionSymbols(symbols map[string]map[string]map[string]bool, r *bufio.Reader, rev string, names []string) error {
+	var (
+		data []byte
+		fset = token.NewFileSet()
+		ctx  = build.Default
+	)
+	ctx.JoinPath = path.Join
+	ctx.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	for _, name := range names {
+		// Each object is "object type size\n", its contents and "\n".
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return fmt.Errorf("unexpected object %q for %s", strings.TrimSpace(header), name)
+		}
+
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("unexpected object %q for %s", strings.TrimSpace(header), name)
+		}
+
+		data = make([]byte, size+1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		data = data[:size]
+
+		dir, base := path.Split(name)
+		dir = path.Clean(dir)
+		if ok, err := ctx.MatchFile(dir, base); err != nil || !ok {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, name, data, parser.SkipObjectResolution)
+		if err != nil {
+			debugf("unable to parse %s at %s: %s", name, rev, err)
+			continue
+		}
+
+		if symbols[dir] == nil {
+			symbols[dir] = make(map[string]map[string]bool)
+		}
+
+		if symbols[dir][f.Name.Name] == nil {
+			symbols[dir][f.Name.Name] = make(map[string]bool)
+		}
+		addExportedSymbols(symbols[dir][f.Name.Name], f)
+	}
+	return nil
+}
+
+// addExportedSymbols adds the names of the exported symbols of the file to
+// the set.
+func addExportedSymbols(symbols map[string]bool, f *ast.File) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				symbols[decl.Name.Name] = true
+			} else if recv := embeddedName(decl.Recv.List[0].Type); recv != "" && token.IsExported(recv) {
+				symbols[recv+"."+decl.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						symbols[spec.Name.Name] = true
+					}
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							symbols[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+var apiSinceFiles struct {
+	sync.Once
+	since map[string]map[string]string
+}
+
+// apiSince returns, for every package listed in the API summary files of
+// the directory, the version in which each of its symbols appeared. The
+// version is the name of the file, and a symbol listed in several files
+// appeared in the lowest version.
+func apiSince(dir string) map[string]map[string]string {
+	apiSinceFiles.Do(func() {
+		apiSinceFiles.since = make(map[string]map[string]string)
+		paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+		if err != nil {
+			fatalf("%s: %s", dir, err)
+		}
+
+		for _, p := range paths {
+			version := strings.TrimSuffix(filepath.Base(p), ".txt")
+			if err := readAPIFile(p, version, apiSinceFiles.since); err != nil {
+				fatalf("unable to read %s: %s", p, err)
+			}
+		}
+	})
+	return apiSinceFiles.since
+}
+
+// readAPIFile adds the symbols listed in the API summary file to the
+// versions of every package.
+func readAPIFile(file, version string, since map[string]map[string]string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		pkgPath, name, ok := parseAPILine(s.Text())
+		if !ok {
+			continue
+		}
+
+		if since[pkgPath] == nil {
+			since[pkgPath] = make(map[string]string)
+		}
+
+		if v, ok := since[pkgPath][name]; !ok || compareVersions(version, v) < 0 {
+			since[pkgPath][name] = version
+		}
+	}
+	return s.Err()
+}
+
+// parseAPILine returns the package and the name of the symbol of a line of
+// an API summary file, such as "pkg bytes, method (*Buffer) Len() int".
+// Lines of struct fields and interface methods are ignored.
+func parseAPILine(line string) (pkgPath, name string, ok bool) {
+	rest, ok := strings.CutPrefix(line, "pkg ")
+	if !ok {
+		return "", "", false
+	}
+
+	// Platform specific lines look like "pkg syscall (linux-386), ...".
+	pkgPath, rest, ok = strings.Cut(rest, ", ")
+	if !ok {
+		return "", "", false
+	}
+	pkgPath, _, _ = strings.Cut(pkgPath, " ")
+
+	kind, rest, _ := strings.Cut(rest, " ")
+	switch kind {
+	case "func", "const", "var":
+		if i := strings.IndexAny(rest, " (["); i >= 0 {
+			rest = rest[:i]
+		}
+		name = rest
+	case "type":
+		if strings.Contains(rest, ", ") {
+			return "", "", false
+		}
+		name, _, _ = strings.Cut(rest, " ")
+		name, _, _ = strings.Cut(name, "[")
+	case "method":
+		recv, method, ok := strings.Cut(rest, ") ")
+		if !ok {
+			return "", "", false
+		}
+		recv = strings.TrimLeft(recv, "(*")
+		recv, _, _ = strings.Cut(recv, "[")
+		method, _, _ = strings.Cut(method, "(")
+		name = recv + "." + method
+	default:
+		return "", "", false
+	}
+	return pkgPath, name, name != ""
+}
+
+// compareVersions compares versions such as "v1.2.3" or "go1.21" by their
+// numeric components.
+func compareVersions(a, b string) int {
+	trim := func(v string) []string {
+		v = strings.TrimPrefix(strings.TrimPrefix(v, "go"), "v")
+		return strings.Split(v, ".")
+	}
+
+	x, y := trim(a), trim(b)
+	for i := 0; i < len(x) && i < len(y); i++ {
+		n, errN := strconv.Atoi(x[i])
+		m, errM := strconv.Atoi(y[i])
+		switch {
+		case errN != nil || errM != nil:
+			if c := strings.Compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		case n != m:
+			if n < m {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(x) - len(y)
+}
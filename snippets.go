@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"go/doc/comment"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// shellRegexp matches the first line of shell snippets: a prompt or one of
+// the commands usually given in documentation.
+var shellRegexp = regexp.MustCompile(`^(?:\$ |# |(?:go|git|curl|make|docker|kubectl|npm|brew|sudo|cd|export|echo) )`)
+
+// NewSnippets returns the code blocks of a doc comment, which are indented
+// in the comment, without the indentation, or nil if it has none.
+func NewSnippets(text string) []*Snippet {
+	var snippets []*Snippet
+	for _, block := range new(comment.Parser).Parse(text).Content {
+		if code, ok := block.(*comment.Code); ok {
+			snippets = append(snippets, &Snippet{
+				Code:     code.Text,
+				Language: snippetLanguage(code.Text),
+			})
+		}
+	}
+	return snippets
+}
+
+// snippetLanguage guesses the language of a snippet: go if it parses as Go
+// source, whether a whole file, declarations, statements or an expression,
+// json if it is valid JSON, shell if it starts with a prompt or a common
+// command, and text otherwise.
+func snippetLanguage(code string) string {
+	trimmed := strings.TrimSpace(code)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	if shellRegexp.MatchString(trimmed) {
+		return "shell"
+	}
+
+	fset := token.NewFileSet()
+	for _, src := range []string{
+		code,
+		"package p\n" + code,
+		"package p\nfunc _() {\n" + code + "\n}",
+	} {
+		if _, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution); err == nil {
+			return "go"
+		}
+	}
+
+	if _, err := parser.ParseExpr(code); err == nil {
+		return "go"
+	}
+	return "text"
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Source is the parsed source of a package, along with any information
+// derived from it that is needed to build its documentation.
+type Source struct {
+	Fset *token.FileSet
+	// Dir is the directory of the package.
+	Dir string
+	// Files are the parsed files of the package, sorted by file name.
+	Files []*ast.File
+	// Info holds the type information of the package. It is nil unless
+	// types are resolved.
+	Info *types.Info
+	// Bodies are the bodies of all the functions in the package, Comments
+	// all the comments of each file and Docs the doc comments of files,
+	// declarations and specs. They are all removed from the AST when the
+	// documentation is built.
+	Bodies   map[*ast.FuncDecl]*ast.BlockStmt
+	Comments map[*ast.File][]*ast.CommentGroup
+	Docs     map[ast.Node]*ast.CommentGroup
+	// Floating are the comments of each file that are neither doc comments
+	// nor inside a declaration.
+	Floating map[*ast.File][]*ast.CommentGroup
+	// Embeds are all the variables populated through //go:embed directives.
+	// They need to be found before unexported declarations are removed from
+	// the AST.
+	Embeds []*Embed
+	// Consts are the values of all the constants that could be evaluated,
+	// including those whose iota changes once unexported constants are
+	// removed.
+	Consts map[*ast.Ident]constant.Value
+	// Iota are the constants of the groups using iota, whose values of iota
+	// and groups change too once unexported constants are removed.
+	Iota map[*ast.Ident]*iotaConst
+	// ConstTypes are the types of the package-level constants, declared or
+	// untyped, that could be found without type information.
+	ConstTypes map[string]string
+	// Stats are the counts of all declarations in the package, which, for
+	// the same reason, need to be computed in advance.
+	Stats *Stats
+	// Features are the versioned language features used by the package.
+	Features []*LanguageFeature
+	// Implementations are the types implementing the interfaces of the
+	// package, if requested.
+	Implementations []*Implementation
+	// Hashes are the hashes of the contents of the files, by file name.
+	Hashes map[string]string
+	// CallGraph are the calls between the documented functions of the
+	// package, if requested.
+	CallGraph []*Call
+	// ImportUses are the number of references to the package of each
+	// import.
+	ImportUses map[*ast.ImportSpec]int
+	// ASTs are the syntax trees of the declarations and type specs, if
+	// requested.
+	ASTs map[ast.Node]*ASTNode
+	// Generated are the names of the files with a header of generated
+	// code.
+	Generated map[string]bool
+}
+
+// NewSource returns the source of the given package, parsed from dir.
+func NewSource(fset *token.FileSet, dir string, pkg *ast.Package) *Source {
+	var names = make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files = make([]*ast.File, len(names))
+	for i, name := range names {
+		files[i] = pkg.Files[name]
+	}
+
+	src := &Source{
+		Fset:     fset,
+		Dir:      dir,
+		Files:    files,
+		Bodies:   make(map[*ast.FuncDecl]*ast.BlockStmt),
+		Comments: make(map[*ast.File][]*ast.CommentGroup),
+		Docs:     make(map[ast.Node]*ast.CommentGroup),
+		Floating: make(map[*ast.File][]*ast.CommentGroup),
+
+		Generated: make(map[string]bool),
+	}
+
+	for _, f := range files {
+		src.Comments[f] = f.Comments
+		if ast.IsGenerated(f) {
+			src.Generated[fset.Position(f.Package).Filename] = true
+		}
+		src.addDoc(f, f.Doc)
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				src.addDoc(decl, decl.Doc)
+				if decl.Body != nil {
+					src.Bodies[decl] = decl.Body
+				}
+			case *ast.GenDecl:
+				src.addDoc(decl, decl.Doc)
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						src.addDoc(spec, spec.Doc)
+					case *ast.ValueSpec:
+						src.addDoc(spec, spec.Doc)
+					}
+				}
+			}
+		}
+
+		src.Floating[f] = floatingComments(f, src.Docs)
+	}
+
+	src.Embeds = packageEmbeds(src)
+	src.Consts = constValues(files)
+	src.Iota = iotaConsts(files)
+	src.ConstTypes = constTypes(files)
+	src.Stats = NewStats(files, fset)
+	if *includeAST {
+		src.ASTs = declASTs(files, fset)
+	}
+	return src
+}
+
+// isGenerated reports whether the given node is declared in a file with a
+// header of generated code.
+func (s *Source) isGenerated(node ast.Node) bool {
+	return s.Generated[s.Fset.Position(node.Pos()).Filename]
+}
+
+func (s *Source) addDoc(node ast.Node, doc *ast.CommentGroup) {
+	if doc != nil {
+		s.Docs[node] = doc
+	}
+}
+
+// docs returns the doc comments of the given nodes, skipping those without
+// any.
+func (s *Source) docs(nodes ...ast.Node) []*ast.CommentGroup {
+	var result []*ast.CommentGroup
+	for _, n := range nodes {
+		if doc := s.Docs[n]; doc != nil {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+// floatingComments returns the comments of the file that are neither doc
+// comments nor inside a declaration.
+func floatingComments(f *ast.File, docs map[ast.Node]*ast.CommentGroup) []*ast.CommentGroup {
+	var isDoc = make(map[*ast.CommentGroup]bool)
+	for _, doc := range docs {
+		isDoc[doc] = true
+	}
+
+	var result []*ast.CommentGroup
+	for _, c := range f.Comments {
+		if isDoc[c] {
+			continue
+		}
+
+		var inside bool
+		for _, decl := range f.Decls {
+			if decl.Pos() <= c.Pos() && c.End() <= decl.End() {
+				inside = true
+				break
+			}
+		}
+
+		if !inside {
+			result = append(result, c)
+		}
+	}
+	return result
+}
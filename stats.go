@@ -0,0 +1,66 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// addCount counts a declaration with the given name.
+func addCount(c *Count, name string) {
+	if ast.IsExported(name) {
+		c.Exported++
+	} else {
+		c.Unexported++
+	}
+}
+
+// NewStats computes the stats of the given files. Since it accounts for
+// unexported declarations, it must be called before they are removed from
+// the AST.
+func NewStats(files []*ast.File, fset *token.FileSet) *Stats {
+	s := &Stats{
+		Files:   len(files),
+		Funcs:   new(Count),
+		Methods: new(Count),
+		Types:   new(Count),
+		Consts:  new(Count),
+		Vars:    new(Count),
+	}
+
+	for _, f := range files {
+		if tf := fset.File(f.Pos()); tf != nil {
+			s.Lines += tf.LineCount()
+		}
+
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv != nil {
+					addCount(s.Methods, decl.Name.Name)
+				} else if decl.Name.Name != "init" && decl.Name.Name != "_" {
+					addCount(s.Funcs, decl.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						addCount(s.Types, spec.Name.Name)
+					case *ast.ValueSpec:
+						c := s.Vars
+						if decl.Tok == token.CONST {
+							c = s.Consts
+						}
+
+						for _, n := range spec.Names {
+							if n.Name != "_" {
+								addCount(c, n.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return s
+}
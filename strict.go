@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"go/scanner"
+	"go/types"
+	"os"
+)
+
+var strict = flag.Bool("strict", false, "abort on the first parse or type-check error, reporting it as a JSON object on the standard error")
+
+// StrictError is the error reported on the standard error with -strict.
+type StrictError struct {
+	// Kind is "parse", "typecheck" or "error" for any other error.
+	Kind string
+	// Package is the package being type-checked, for type-check errors.
+	Package string   `json:",omitempty"`
+	Pos     *FilePos `json:",omitempty"`
+	Message string
+}
+
+func newStrictError(err error) *StrictError {
+	var (
+		list    scanner.ErrorList
+		typeErr types.Error
+	)
+
+	switch {
+	case errors.As(err, &list) && len(list) > 0:
+		return &StrictError{
+			Kind:    "parse",
+			Pos:     &FilePos{Line: list[0].Pos.Line, Column: list[0].Pos.Column, File: relPath(list[0].Pos.Filename)},
+			Message: list[0].Msg,
+		}
+	case errors.As(err, &typeErr):
+		return &StrictError{
+			Kind:    "typecheck",
+			Pos:     NewFilePos(typeErr.Pos, typeErr.Fset),
+			Message: typeErr.Msg,
+		}
+	default:
+		return &StrictError{Kind: "error", Message: err.Error()}
+	}
+}
+
+// strictFatal reports the error as a JSON object on the standard error and
+// exits with the given status.
+func strictFatal(e *StrictError, status int) {
+	data, _ := json.Marshal(e)
+	os.Stderr.Write(append(data, '\n'))
+	os.Exit(status)
+}
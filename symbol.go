@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var symbolRegexp = regexp.MustCompile(`^[\p{Lu}][\p{L}\p{N}_]*(\.[\p{L}_][\p{L}\p{N}_]*)?$`)
+
+// isSymbol reports whether the given argument is the name of an exported
+// symbol or method, such as "Printf" or "Buffer.Write", rather than a
+// package.
+func isSymbol(arg string) bool {
+	return symbolRegexp.MatchString(arg)
+}
+
+// lookupSymbol returns the declaration of the symbol with the given name in
+// the package. The name can be that of a const, var, func or type, or of a
+// method in the form "Type.Method". Values declared in a group are found by
+// any of their names.
+func lookupSymbol(pkg *Pkg, name string) (interface{}, bool) {
+	if typName, method, ok := strings.Cut(name, "."); ok {
+		for _, t := range pkg.Types {
+			if t.Name != typName {
+				continue
+			}
+
+			for _, m := range t.Methods {
+				if m.Name == method {
+					return m, true
+				}
+			}
+		}
+		return nil, false
+	}
+
+	for _, f := range pkg.Funcs {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	if v, ok := lookupValue(pkg.Consts, name); ok {
+		return v, true
+	}
+
+	if v, ok := lookupValue(pkg.Vars, name); ok {
+		return v, true
+	}
+
+	for _, t := range pkg.Types {
+		if t.Name == name {
+			return t, true
+		}
+
+		for _, f := range t.Funcs {
+			if f.Name == name {
+				return f, true
+			}
+		}
+
+		if v, ok := lookupValue(t.Consts, name); ok {
+			return v, true
+		}
+
+		if v, ok := lookupValue(t.Vars, name); ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func lookupValue(values []*Value, name string) (*Value, bool) {
+	for _, v := range values {
+		for _, n := range v.Names {
+			if n == name {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
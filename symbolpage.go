@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// SymbolPage is a page of the symbols of a package, as served at /symbols.
+type SymbolPage struct {
+	ImportPath string
+	// Page is the number of the page, starting at 1, Pages the number of
+	// pages and Total the number of symbols matching the request.
+	Page  int
+	Pages int
+	Total int
+	// Symbols are the symbols of the page, with only the requested fields
+	// if any were.
+	Symbols []interface{}
+}
+
+// symbolsHandler serves the symbols of a package in pages, so clients of
+// packages with thousands of them can fetch only the ones they need. The
+// parameters of the request are:
+//
+//   - importPath: the package, which is required.
+//   - kinds: a comma-separated list of the kinds of symbols to include.
+//   - prefix: the prefix of the names of the symbols to include.
+//   - page and pageSize: the page to return, from 1, and its size, 100 by
+//     default and up to 1000.
+//   - fields: a comma-separated list of the fields of the symbols to
+//     include, along with their Name.
+type symbolsHandler func() *corpus
+
+func (h symbolsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	c := h()
+	pkg := c.byPath[q.Get("importPath")]
+	if pkg == nil {
+		http.Error(w, "no package served has import path "+strconv.Quote(q.Get("importPath")), http.StatusNotFound)
+		return
+	}
+
+	page, err := intParam(q.Get("page"), 1, 1<<31-1)
+	if err != nil {
+		http.Error(w, "invalid page: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pageSize, err := intParam(q.Get("pageSize"), defaultPageSize, maxPageSize)
+	if err != nil {
+		http.Error(w, "invalid pageSize: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseSymbolFieldList(q.Get("fields"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var kinds []string
+	if k := q.Get("kinds"); k != "" {
+		kinds = strings.Split(k, ",")
+	}
+
+	etag := `"` + sourceHash([]byte(c.hash+"\n"+r.URL.RawQuery)) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", c.modTime.UTC().Format(http.TimeFormat))
+	if notModified(r, etag, c.modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	symbols := filterSymbols(c.symbols[pkg.ImportPath], kinds, q.Get("prefix"))
+	if r.Context().Err() != nil {
+		// The request timed out or the client went away.
+		return
+	}
+
+	result := &SymbolPage{
+		ImportPath: pkg.ImportPath,
+		Page:       page,
+		Pages:      (len(symbols) + pageSize - 1) / pageSize,
+		Total:      len(symbols),
+		Symbols:    []interface{}{},
+	}
+
+	start := (page - 1) * pageSize
+	for i := start; i < start+pageSize && i < len(symbols); i++ {
+		sym, err := projectSymbol(symbols[i], fields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result.Symbols = append(result.Symbols, sym)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		debugf("unable to write response: %s", err)
+	}
+}
+
+// filterSymbols returns the symbols of any of the given kinds, or all of
+// them if none is given, whose names start with the prefix.
+func filterSymbols(symbols []*Symbol, kinds []string, prefix string) []*Symbol {
+	var result = []*Symbol{}
+	for _, sym := range symbols {
+		if len(kinds) > 0 && !containsString(kinds, sym.Kind) {
+			continue
+		}
+
+		if strings.HasPrefix(sym.Name, prefix) {
+			result = append(result, sym)
+		}
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// intParam parses a positive integer parameter up to max, which is def if
+// it is empty.
+func intParam(s string, def, max int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("expecting a number from 1 to %d", max)
+	}
+	return n, nil
+}
+
+// symbolFieldNames are the fields symbols can be projected to.
+var symbolFieldNames = []string{"ImportPath", "Name", "Kind", "Doc", "Decl", "Pos", "Type", "Func", "Value"}
+
+// parseSymbolFieldList parses a comma-separated list of fields of symbols,
+// returning nil if it is empty.
+func parseSymbolFieldList(list string) (map[string]bool, error) {
+	if list == "" {
+		return nil, nil
+	}
+
+	var fields = map[string]bool{"Name": true}
+	for _, f := range strings.Split(list, ",") {
+		if !containsString(symbolFieldNames, f) {
+			return nil, fmt.Errorf("invalid field %q: expecting one of %s", f, strings.Join(symbolFieldNames, ", "))
+		}
+		fields[f] = true
+	}
+	return fields, nil
+}
+
+// projectSymbol returns the symbol with only the given fields, or as it is
+// if there are none.
+func projectSymbol(sym *Symbol, fields map[string]bool) (interface{}, error) {
+	if fields == nil {
+		return sym, nil
+	}
+
+	data, err := json.Marshal(sym)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	var result = make(map[string]json.RawMessage)
+	for name, v := range all {
+		if fields[name] {
+			result[name] = v
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/csv"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// symbolColumns are the columns of the table of symbols written by the csv
+// and parquet formats.
+var symbolColumns = []string{"import_path", "kind", "name", "exported", "doc_length", "file", "line"}
+
+// symbolRow is a row of the table of symbols, with a symbol of a package.
+type symbolRow struct {
+	ImportPath string
+	Kind       string
+	Name       string
+	Exported   bool
+	// DocLength is the length in bytes of the doc comment.
+	DocLength int
+	// File and Line are where the symbol is declared, which are empty and
+	// 0 without positions.
+	File string
+	Line int
+}
+
+// symbolRows returns a row for each of the symbols of a package, in the
+// order they are documented.
+func symbolRows(pkg *Pkg) []*symbolRow {
+	var rows []*symbolRow
+	for _, sym := range packageSymbols(pkg) {
+		// Methods of unexported types are not exported either.
+		typ, method, isMethod := strings.Cut(sym.Name, ".")
+		row := &symbolRow{
+			ImportPath: sym.ImportPath,
+			Kind:       sym.Kind,
+			Name:       sym.Name,
+			Exported:   token.IsExported(typ) && (!isMethod || token.IsExported(method)),
+			DocLength:  len(sym.Doc),
+		}
+
+		if sym.Pos != nil && sym.Pos.Start != nil {
+			row.File, row.Line = sym.Pos.Start.File, sym.Pos.Start.Line
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// csvWriter writes the symbols of all the packages as a single CSV table,
+// with a header row with the names of its columns.
+type csvWriter struct {
+	w *csv.Writer
+	n int
+}
+
+func newCSVWriter(w io.Writer, list bool) packageWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(pkg *Pkg) error {
+	if w.n == 0 {
+		w.w.Write(symbolColumns)
+	}
+	w.n++
+
+	for _, row := range symbolRows(pkg) {
+		err := w.w.Write([]string{
+			row.ImportPath,
+			row.Kind,
+			row.Name,
+			strconv.FormatBool(row.Exported),
+			strconv.Itoa(row.DocLength),
+			row.File,
+			strconv.Itoa(row.Line),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return w.w.Error()
+}
+
+func (w *csvWriter) Close() error {
+	if w.n == 0 {
+		w.w.Write(symbolColumns)
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
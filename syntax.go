@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+var includeAST = flag.Bool("include-ast", false, "include the syntax tree of every declaration, with the kind, position and children of each node")
+
+// declASTs returns the syntax trees of the declarations of the files, along
+// with those of the type specs, which go/doc moves to declarations of their
+// own when they are grouped, by node. They need to be built before the
+// documentation is, as it strips function bodies and unexported
+// declarations from the AST. Comments are left out.
+func declASTs(files []*ast.File, fset *token.FileSet) map[ast.Node]*ASTNode {
+	var result = make(map[ast.Node]*ASTNode)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			var stack []*ASTNode
+			ast.Inspect(decl, func(node ast.Node) bool {
+				if node == nil {
+					stack = stack[:len(stack)-1]
+					return true
+				}
+
+				if _, ok := node.(*ast.CommentGroup); ok {
+					return false
+				}
+
+				n := &ASTNode{
+					Kind:  reflect.TypeOf(node).Elem().Name(),
+					Value: astNodeValue(node),
+					Pos:   NewPos(node, fset),
+				}
+				if len(stack) > 0 {
+					parent := stack[len(stack)-1]
+					parent.Children = append(parent.Children, n)
+				}
+				switch node.(type) {
+				case *ast.FuncDecl, *ast.GenDecl, *ast.TypeSpec:
+					result[node] = n
+				}
+				stack = append(stack, n)
+				return true
+			})
+		}
+	}
+	return result
+}
+
+func astNodeValue(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.BasicLit:
+		return n.Value
+	case *ast.GenDecl:
+		return n.Tok.String()
+	case *ast.UnaryExpr:
+		return n.Op.String()
+	case *ast.BinaryExpr:
+		return n.Op.String()
+	case *ast.AssignStmt:
+		return n.Tok.String()
+	case *ast.IncDecStmt:
+		return n.Tok.String()
+	case *ast.BranchStmt:
+		return n.Tok.String()
+	case *ast.RangeStmt:
+		if n.Tok != token.ILLEGAL {
+			return n.Tok.String()
+		}
+	}
+	return ""
+}
+
+// declAST returns the syntax tree of the first of the given nodes that has
+// one. It is nil unless syntax trees are requested.
+func (s *Source) declAST(nodes ...ast.Node) *ASTNode {
+	for _, n := range nodes {
+		if t := s.ASTs[n]; t != nil {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc/comment"
+	goformat "go/format"
+	"io"
+	"strings"
+)
+
+// textIndent is the indentation of the comments of symbols in the text
+// format.
+const textIndent = "    "
+
+// textWriter writes the documentation of every package as plain text, in
+// the format of go doc -all: the package clause and comment, followed by
+// the declarations of the exported constants, variables, functions and
+// types, each with its comment indented below it.
+type textWriter struct {
+	w *bufio.Writer
+	// n is the number of packages written, which are separated by a blank
+	// line.
+	n int
+}
+
+func newTextWriter(w io.Writer, list bool) packageWriter {
+	return &textWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *textWriter) Write(pkg *Pkg) error {
+	if w.n > 0 {
+		if err := w.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	w.n++
+
+	_, err := w.w.Write(goDocText(pkg))
+	return err
+}
+
+func (w *textWriter) Close() error {
+	return w.w.Flush()
+}
+
+// goDocText returns the documentation of a package as printed by go doc
+// -all.
+func goDocText(pkg *Pkg) []byte {
+	t := &goDoc{parser: newDocParser(pkg, symbolNames(pkg))}
+	t.printf("package %s", pkg.Name)
+	if pkg.ImportPath != "" {
+		t.printf(" // import %q", pkg.ImportPath)
+	}
+	t.printf("\n\n")
+	t.text(packageDocText(pkg), "", textIndent)
+	t.newlines(1)
+
+	for _, v := range pkg.Consts {
+		if hasExportedName(v) {
+			t.header("CONSTANTS")
+			t.emit(v.Doc, v.Decl)
+		}
+	}
+
+	for _, v := range pkg.Vars {
+		if hasExportedName(v) {
+			t.header("VARIABLES")
+			t.emit(v.Doc, v.Decl)
+		}
+	}
+
+	for _, f := range pkg.Funcs {
+		if ast.IsExported(f.Name) {
+			t.header("FUNCTIONS")
+			t.emit(f.Doc, f.Decl)
+		}
+	}
+
+	for _, typ := range pkg.Types {
+		if !ast.IsExported(typ.Name) {
+			continue
+		}
+
+		t.header("TYPES")
+		t.emit(typ.Doc, typ.Decl)
+		t.newlines(2)
+		for _, list := range [][]*Value{typ.Consts, typ.Vars} {
+			for _, v := range list {
+				if hasExportedName(v) {
+					t.emit(v.Doc, v.Decl)
+				}
+			}
+		}
+
+		for _, list := range [][]*Func{typ.Funcs, typ.Methods} {
+			for _, f := range list {
+				if ast.IsExported(f.Name) {
+					t.emit(f.Doc, f.Decl)
+					t.newlines(2)
+				}
+			}
+		}
+	}
+
+	return t.buf.Bytes()
+}
+
+func hasExportedName(v *Value) bool {
+	for _, name := range v.Names {
+		if ast.IsExported(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// goDoc is the text of a package being written.
+type goDoc struct {
+	buf    bytes.Buffer
+	parser *comment.Parser
+	// section is the last section header written.
+	section string
+}
+
+func (t *goDoc) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&t.buf, format, args...)
+}
+
+// header writes the header of a section, unless it is the section being
+// written.
+func (t *goDoc) header(section string) {
+	if t.section != section {
+		t.printf("\n%s\n\n", section)
+		t.section = section
+	}
+}
+
+// emit writes a declaration followed by its comment, if any, indented and
+// separated from the next declaration by a blank line.
+func (t *goDoc) emit(doc, decl string) {
+	t.buf.WriteString(goDocDecl(decl))
+	t.newlines(1)
+	if strings.TrimSpace(doc) != "" {
+		t.text(doc, textIndent, textIndent+textIndent)
+		t.newlines(2)
+	}
+}
+
+// text writes a comment with the given prefixes for its text and code
+// blocks, filled to 80 columns.
+func (t *goDoc) text(doc, prefix, codePrefix string) {
+	if strings.TrimSpace(doc) == "" {
+		return
+	}
+
+	pr := &comment.Printer{TextPrefix: prefix, TextCodePrefix: codePrefix}
+	t.buf.Write(pr.Text(t.parser.Parse(doc)))
+}
+
+// newlines makes the text end with at least n newlines, unless it is
+// empty.
+func (t *goDoc) newlines(n int) {
+	b := t.buf.Bytes()
+	if len(b) == 0 {
+		return
+	}
+
+	for have := len(b) - len(bytes.TrimRight(b, "\n")); have < n; have++ {
+		t.buf.WriteByte('\n')
+	}
+}
+
+// goDocDecl returns a declaration formatted as by gofmt, aligning fields
+// with spaces, with the comments of hidden fields and methods worded as go
+// doc does.
+func goDocDecl(decl string) string {
+	if src, err := goformat.Source([]byte(decl)); err == nil {
+		decl = string(src)
+	}
+
+	return strings.NewReplacer(
+		filteredComment+"fields", "// Has unexported fields.",
+		filteredComment+"methods", "// Has unexported methods.",
+	).Replace(strings.TrimRight(decl, "\n"))
+}
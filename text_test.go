@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// goDocPackages are the packages whose text is compared with the output of
+// go doc -all, which cover struct fields and interface methods, hidden
+// ones among them, grouped declarations and doc links to fields and
+// methods.
+var goDocPackages = []string{"sync", "container/list", "bufio", "go/ast", "go/token", "encoding/json", "text/template", "net/url"}
+
+// TestGoDocText compares the text of some packages of the standard library
+// with the output of go doc -all, if the go command is available.
+func TestGoDocText(t *testing.T) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	for _, pkgName := range goDocPackages {
+		t.Run(pkgName, func(t *testing.T) {
+			want, err := exec.Command(goCmd, "doc", "-all", pkgName).Output()
+			if err != nil {
+				t.Skipf("go doc -all %s: %s", pkgName, err)
+			}
+
+			pkg, err := extract(pkgName)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			got := goDocText(pkg)
+			if !bytes.Equal(got, want) {
+				t.Errorf("text differs from go doc -all:\n%s", firstDiff(string(got), string(want)))
+			}
+		})
+	}
+}
+
+// firstDiff returns the first line that differs between got and want, with
+// a few lines of context.
+func firstDiff(got, want string) string {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+
+		if g != w {
+			start := i - 3
+			if start < 0 {
+				start = 0
+			}
+			return "line " + strconv.Itoa(i+1) + ":\n" +
+				"context:\n" + strings.Join(wantLines[start:i], "\n") +
+				"\ngot:  " + g + "\nwant: " + w
+		}
+	}
+	return ""
+}
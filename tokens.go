@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+)
+
+var withTokens = flag.Bool("tokens", false, "include the tokens of every declaration, with their kind and offsets, to highlight them")
+
+// declPrefix is prepended to declarations to parse them.
+const declPrefix = "package p\n"
+
+// NewDeclTokens returns the tokens of the given declaration if they are
+// requested. Identifiers are only known to be types if the declaration
+// can be parsed.
+func NewDeclTokens(decl string) []*DeclToken {
+	if !*withTokens {
+		return nil
+	}
+
+	var (
+		fset  = token.NewFileSet()
+		types = make(map[int]bool)
+	)
+
+	if f, err := parser.ParseFile(fset, "", declPrefix+decl, parser.SkipObjectResolution); err == nil {
+		m := &typeMarker{fset: fset, offsets: types}
+		for _, d := range f.Decls {
+			m.decl(d)
+		}
+	}
+
+	var (
+		s      scanner.Scanner
+		tokens = []*DeclToken{}
+		file   = fset.AddFile("", -1, len(decl))
+	)
+
+	s.Init(file, []byte(decl), nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		var kind string
+		switch {
+		case tok.IsKeyword():
+			kind = "keyword"
+		case tok == token.IDENT:
+			kind = "ident"
+			if types[file.Offset(pos)] {
+				kind = "type"
+			}
+		case tok == token.STRING || tok == token.CHAR:
+			kind = "string"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			kind = "number"
+		case tok == token.COMMENT:
+			kind = "comment"
+		default:
+			continue
+		}
+
+		length := len(lit)
+		if tok.IsKeyword() {
+			length = len(tok.String())
+		}
+		tokens = append(tokens, &DeclToken{Kind: kind, Offset: file.Offset(pos), Len: length})
+	}
+	return tokens
+}
+
+// typeMarker records the offsets, in the declaration, of the identifiers
+// naming types.
+type typeMarker struct {
+	fset    *token.FileSet
+	offsets map[int]bool
+}
+
+func (m *typeMarker) mark(id *ast.Ident) {
+	if offset := m.fset.Position(id.Pos()).Offset - len(declPrefix); offset >= 0 {
+		m.offsets[offset] = true
+	}
+}
+
+func (m *typeMarker) decl(decl ast.Decl) {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		m.fields(d.Recv)
+		m.expr(d.Type)
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				m.mark(s.Name)
+				m.fields(s.TypeParams)
+				m.expr(s.Type)
+			case *ast.ValueSpec:
+				if s.Type != nil {
+					m.expr(s.Type)
+				}
+
+				for _, v := range s.Values {
+					m.value(v)
+				}
+			}
+		}
+	}
+}
+
+func (m *typeMarker) fields(list *ast.FieldList) {
+	if list == nil {
+		return
+	}
+
+	for _, f := range list.List {
+		m.expr(f.Type)
+	}
+}
+
+// expr marks the identifiers of a type expression, which are all types
+// except for package names, array lengths and the names of fields,
+// parameters and methods.
+func (m *typeMarker) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		m.mark(e)
+	case *ast.SelectorExpr:
+		m.mark(e.Sel)
+	case *ast.StarExpr:
+		m.expr(e.X)
+	case *ast.ParenExpr:
+		m.expr(e.X)
+	case *ast.Ellipsis:
+		m.expr(e.Elt)
+	case *ast.ArrayType:
+		m.expr(e.Elt)
+	case *ast.MapType:
+		m.expr(e.Key)
+		m.expr(e.Value)
+	case *ast.ChanType:
+		m.expr(e.Value)
+	case *ast.FuncType:
+		m.fields(e.TypeParams)
+		m.fields(e.Params)
+		m.fields(e.Results)
+	case *ast.StructType:
+		m.fields(e.Fields)
+	case *ast.InterfaceType:
+		m.fields(e.Methods)
+	case *ast.IndexExpr:
+		m.expr(e.X)
+		m.expr(e.Index)
+	case *ast.IndexListExpr:
+		m.expr(e.X)
+		for _, i := range e.Indices {
+			m.expr(i)
+		}
+	case *ast.BinaryExpr:
+		// Unions of constraints.
+		m.expr(e.X)
+		m.expr(e.Y)
+	case *ast.UnaryExpr:
+		m.expr(e.X)
+	}
+}
+
+// value marks the types used in a value, in composite literals and
+// conversions to non-named types.
+func (m *typeMarker) value(expr ast.Expr) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			if n.Type != nil {
+				m.expr(n.Type)
+			}
+		case *ast.FuncLit:
+			m.expr(n.Type)
+			return false
+		case *ast.CallExpr:
+			switch fun := n.Fun.(type) {
+			case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.StarExpr, *ast.ParenExpr:
+				m.expr(fun)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+var resolveTypes = flag.Bool("resolve-types", false, "type-check packages to emit fully-qualified types for params, results, fields and values")
+
+// checkTypes type-checks the given package. Errors are not fatal, as all the
+// type information that could be gathered is still useful, so they are only
+// reported, unless -strict is given. With -offline, an error is returned if
+// any import could not be found, as without network access it will not be
+// found by trying again either.
+func checkTypes(pkgName string, fset *token.FileSet, pkg *ast.Package) (*types.Info, error) {
+	var files = make([]*ast.File, 0, len(pkg.Files))
+	for _, f := range pkg.Files {
+		files = append(files, f)
+	}
+
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+
+	imp := &recordingImporter{ImporterFrom: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)}
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			if *strict {
+				e := newStrictError(err)
+				e.Package = pkgName
+				strictFatal(e, 1)
+			}
+			debugf("type-checking %s: %s", pkgName, err)
+		},
+	}
+
+	conf.Check(pkgName, fset, files, info)
+	if *offline && len(imp.missing) > 0 {
+		return nil, fmt.Errorf("unable to type-check offline, as these imports are not in the module cache, vendor directories or GOPATH:\n\t%s", strings.Join(imp.missing, "\n\t"))
+	}
+	return info, nil
+}
+
+// recordingImporter is an importer that records the imports it could not
+// find, other than the cgo pseudo-package "C", which is never found.
+type recordingImporter struct {
+	types.ImporterFrom
+	// missing are the import paths not found, each one followed by the
+	// reason.
+	missing []string
+	seen    map[string]bool
+}
+
+func (i *recordingImporter) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, "", 0)
+}
+
+func (i *recordingImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	pkg, err := i.ImporterFrom.ImportFrom(path, dir, mode)
+	if err != nil && path != "C" && !i.seen[path] {
+		if i.seen == nil {
+			i.seen = make(map[string]bool)
+		}
+		i.seen[path] = true
+		i.missing = append(i.missing, fmt.Sprintf("%s: %s", path, err))
+	}
+	return pkg, err
+}
+
+// qualifyFully qualifies all the types with the import path of their
+// packages.
+func qualifyFully(pkg *types.Package) string {
+	return pkg.Path()
+}
+
+// typeString returns the representation of the given type expression. If
+// types are resolved, it is fully-qualified, otherwise it is as written.
+func (s *Source) typeString(expr ast.Expr) string {
+	if s.Info != nil {
+		if ell, ok := expr.(*ast.Ellipsis); ok {
+			return "..." + s.typeString(ell.Elt)
+		}
+
+		if t := s.Info.TypeOf(expr); t != nil {
+			return types.TypeString(t, qualifyFully)
+		}
+	}
+
+	return types.ExprString(expr)
+}
+
+// valueTypes returns the type of each name declared in the given const or
+// var declaration. Without type information, or if it is incomplete, it is
+// the declared type or the one inferred from the value in the simplest
+// cases, such as literals, and empty if it cannot be inferred.
+func (s *Source) valueTypes(decl *ast.GenDecl) []string {
+	var result []string
+	for _, spec := range decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		for i := range vs.Names {
+			result = append(result, s.valueType(decl.Tok, vs, i))
+		}
+	}
+	return result
+}
+
+func (s *Source) valueType(tok token.Token, vs *ast.ValueSpec, i int) string {
+	if s.Info != nil {
+		if obj := s.Info.Defs[vs.Names[i]]; obj != nil && obj.Type() != types.Typ[types.Invalid] {
+			return types.TypeString(obj.Type(), qualifyFully)
+		}
+	}
+
+	if tok == token.CONST {
+		return s.ConstTypes[vs.Names[i].Name]
+	}
+
+	if vs.Type != nil {
+		return s.typeString(vs.Type)
+	}
+
+	if len(vs.Values) != len(vs.Names) {
+		return ""
+	}
+	return s.inferType(vs.Values[i])
+}
+
+// inferType returns the type of a variable initialized with the given
+// expression, for literals, conversions and constant expressions, which
+// get their default type.
+func (s *Source) inferType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return s.inferType(e.X)
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return s.typeString(e.Type)
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := e.X.(*ast.CompositeLit); ok && e.Op == token.AND && lit.Type != nil {
+			return "*" + s.typeString(lit.Type)
+		}
+	case *ast.FuncLit:
+		return s.typeString(e.Type)
+	case *ast.CallExpr:
+		if isErrorConstructor(e) {
+			return "error"
+		}
+	}
+
+	typ := constExprType(expr, s.ConstTypes)
+	if untyped, ok := strings.CutPrefix(typ, "untyped "); ok {
+		return defaultTypes[untyped]
+	}
+	return typ
+}
+
+// defaultTypes are the types untyped constants are converted to when they
+// are assigned to variables.
+var defaultTypes = map[string]string{
+	"bool":    "bool",
+	"int":     "int",
+	"rune":    "rune",
+	"float":   "float64",
+	"complex": "complex128",
+	"string":  "string",
+}
+
+// untypedOrder ranks the kinds of untyped constants, as the result of an
+// operation between them is of the kind ranked the highest.
+var untypedOrder = map[string]int{
+	"untyped int":     1,
+	"untyped rune":    2,
+	"untyped float":   3,
+	"untyped complex": 4,
+}
+
+// constTypes returns the types of the constants declared in the files,
+// either the one of the declaration or, for untyped constants, "untyped"
+// followed by their kind, as go/types prints them. Those that cannot be
+// known without type information are not included.
+func constTypes(files []*ast.File) map[string]string {
+	var result = make(map[string]string)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+
+			var (
+				lastType   ast.Expr
+				lastValues []ast.Expr
+			)
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				// Specs without values repeat the type and values of the
+				// previous one.
+				if len(vs.Values) > 0 {
+					lastType, lastValues = vs.Type, vs.Values
+				}
+
+				for i, name := range vs.Names {
+					var typ string
+					switch {
+					case lastType != nil:
+						typ = types.ExprString(lastType)
+					case i < len(lastValues):
+						typ = constExprType(lastValues[i], result)
+					}
+
+					if typ != "" && name.Name != "_" {
+						result[name.Name] = typ
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// constExprType returns the type of a constant expression given the types
+// of the constants of the package, or an empty string if it is unknown.
+func constExprType(expr ast.Expr, consts map[string]string) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return "untyped int"
+		case token.FLOAT:
+			return "untyped float"
+		case token.IMAG:
+			return "untyped complex"
+		case token.CHAR:
+			return "untyped rune"
+		case token.STRING:
+			return "untyped string"
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true", "false":
+			return "untyped bool"
+		case "iota":
+			return "untyped int"
+		}
+		return consts[e.Name]
+	case *ast.ParenExpr:
+		return constExprType(e.X, consts)
+	case *ast.UnaryExpr:
+		return constExprType(e.X, consts)
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return "untyped bool"
+		case token.SHL, token.SHR:
+			return constExprType(e.X, consts)
+		}
+
+		x, y := constExprType(e.X, consts), constExprType(e.Y, consts)
+		switch {
+		case x == "" || y == "":
+			return ""
+		case !strings.HasPrefix(x, "untyped "):
+			return x
+		case !strings.HasPrefix(y, "untyped "):
+			return y
+		case untypedOrder[y] > untypedOrder[x]:
+			return y
+		}
+		return x
+	case *ast.CallExpr:
+		// Calls in constant expressions are either conversions or calls to
+		// builtins such as len, which cannot be told apart from conversions
+		// to types named the same without type information.
+		if len(e.Args) != 1 {
+			return ""
+		}
+
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			if isConstBuiltin(fun.Name) {
+				return ""
+			}
+			return fun.Name
+		case *ast.SelectorExpr:
+			if pkg, ok := fun.X.(*ast.Ident); ok && pkg.Name != "unsafe" {
+				return types.ExprString(fun)
+			}
+		case *ast.ParenExpr:
+			return types.ExprString(fun.X)
+		}
+	}
+	return ""
+}
+
+// isConstBuiltin reports whether the name is a builtin function that can
+// be used in constant expressions.
+func isConstBuiltin(name string) bool {
+	switch name {
+	case "len", "cap", "real", "imag", "complex", "min", "max":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package main
+
+//go:generate sh -c "go run . -types > godocjson.d.ts"
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var printTypes = flag.Bool("types", false, "print the TypeScript definitions of the documents generated and exit")
+
+// tsRoots are the types of the documents generated: the packages, and the
+// index written with -outdir.
+var tsRoots = []reflect.Type{
+	reflect.TypeOf(Pkg{}),
+	reflect.TypeOf(Index{}),
+}
+
+// writeTypeScript writes the TypeScript definitions of the documents, with
+// an interface for every struct they are made of, named after it. Fields
+// are named as in the output, following -field-case, and those left out
+// when empty, all of them with -omit-empty, are optional.
+func writeTypeScript(w io.Writer) error {
+	g := &tsGenerator{seen: make(map[reflect.Type]bool)}
+	for _, t := range tsRoots {
+		g.queue(t)
+	}
+
+	g.buf.WriteString("// Type definitions of the documents generated by godocjson.\n")
+	g.buf.WriteString("// Code generated by godocjson -types. DO NOT EDIT.\n")
+	for len(g.pending) > 0 {
+		t := g.pending[0]
+		g.pending = g.pending[1:]
+		g.writeInterface(t)
+	}
+
+	_, err := io.WriteString(w, g.buf.String())
+	return err
+}
+
+type tsGenerator struct {
+	buf     strings.Builder
+	seen    map[reflect.Type]bool
+	pending []reflect.Type
+}
+
+func (g *tsGenerator) queue(t reflect.Type) {
+	if !g.seen[t] {
+		g.seen[t] = true
+		g.pending = append(g.pending, t)
+	}
+}
+
+func (g *tsGenerator) writeInterface(t reflect.Type) {
+	fmt.Fprintf(&g.buf, "\nexport interface %s {\n", t.Name())
+	g.writeFields(t)
+	g.buf.WriteString("}\n")
+}
+
+// writeFields writes the fields of the struct, following the rules of
+// encoding/json for their tags and embedded structs.
+func (g *tsGenerator) writeFields(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				g.writeFields(ft)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = recaseName(f.Name, *fieldCase)
+		}
+
+		optional := ""
+		if strings.Contains(","+opts+",", ",omitempty,") || *omitEmpty {
+			optional = "?"
+		}
+		fmt.Fprintf(&g.buf, "\t%s%s: %s;\n", tsName(name), optional, g.typeName(f.Type))
+	}
+}
+
+// typeName returns the TypeScript type of the values of the given type, as
+// encoded by encoding/json. Nil pointers, slices and maps are null, but
+// the documents have no nil elements in them.
+func (g *tsGenerator) typeName(t reflect.Type) string {
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return "unknown"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Ptr:
+		return g.typeName(t.Elem()) + " | null"
+	case reflect.Slice:
+		// Byte slices are encoded as base64 strings.
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string | null"
+		}
+		return g.elemName(t.Elem()) + "[] | null"
+	case reflect.Array:
+		return g.elemName(t.Elem()) + "[]"
+	case reflect.Map:
+		elem := t.Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		return "Record<string, " + g.typeName(elem) + "> | null"
+	case reflect.Struct:
+		g.queue(t)
+		return t.Name()
+	}
+	return "unknown"
+}
+
+// elemName returns the type of the elements of a list, which are never
+// null, in parentheses if it is a union.
+func (g *tsGenerator) elemName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	name := g.typeName(t)
+	if strings.Contains(name, " | ") {
+		return "(" + name + ")"
+	}
+	return name
+}
+
+// tsName returns the name as a property name, quoted if it is not a valid
+// identifier, such as "$schema".
+func tsName(name string) string {
+	for i, r := range name {
+		if !(r == '_' || r == '$' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"go/ast"
+	"go/doc"
+)
+
+// NewTypeUses returns the uses of every type of the package, by type name.
+// Methods promoted from embedded types are not included, as they already
+// are in the uses of the embedded type.
+func NewTypeUses(pkg *doc.Package) map[string]*TypeUses {
+	var uses = make(map[string]*TypeUses)
+	for _, t := range pkg.Types {
+		uses[t.Name] = &TypeUses{Accepting: []string{}, Returning: []string{}}
+	}
+
+	add := func(name string, fn *ast.FuncType) {
+		for typ := range mentionedTypes(fn.Params, fn.TypeParams) {
+			if u, ok := uses[typ]; ok {
+				u.Accepting = append(u.Accepting, name)
+			}
+		}
+
+		for typ := range mentionedTypes(fn.Results, fn.TypeParams) {
+			if u, ok := uses[typ]; ok {
+				u.Returning = append(u.Returning, name)
+			}
+		}
+	}
+
+	for _, f := range pkg.Funcs {
+		add(f.Name, f.Decl.Type)
+	}
+
+	for _, t := range pkg.Types {
+		for _, f := range t.Funcs {
+			add(f.Name, f.Decl.Type)
+		}
+
+		for _, m := range t.Methods {
+			if m.Level == 0 {
+				add(t.Name+"."+m.Name, m.Decl.Type)
+			}
+		}
+	}
+	return uses
+}
+
+// mentionedTypes returns the names of the types of the package mentioned
+// in the given fields. Qualified identifiers refer to other packages and
+// type parameters are not types of the package, so they are skipped.
+func mentionedTypes(fields, typeParams *ast.FieldList) map[string]bool {
+	var names = make(map[string]bool)
+	if fields == nil {
+		return names
+	}
+
+	var params = make(map[string]bool)
+	if typeParams != nil {
+		for _, f := range typeParams.List {
+			for _, n := range f.Names {
+				params[n.Name] = true
+			}
+		}
+	}
+
+	var inspect func(n ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			return false
+		case *ast.Field:
+			// The names of parameters of function types are not types.
+			ast.Inspect(n.Type, inspect)
+			return false
+		case *ast.Ident:
+			if !params[n.Name] {
+				names[n.Name] = true
+			}
+		}
+		return true
+	}
+
+	for _, f := range fields.List {
+		ast.Inspect(f.Type, inspect)
+	}
+	return names
+}
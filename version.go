@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// These are meant to be set at build time using:
+//
+//	go build -ldflags "-X main.version=v1.0.0 -X main.commit=abc123 -X main.date=2017-01-01"
+//
+// When they are not, they are filled using the build info embedded by the
+// go tool, if any.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	if version == "" && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if commit == "" {
+				commit = s.Value
+			}
+		case "vcs.time":
+			if date == "" {
+				date = s.Value
+			}
+		}
+	}
+}
+
+// generatorVersion returns the version of godocjson that will be reported in
+// the generated documents.
+func generatorVersion() string {
+	if commit != "" {
+		return releaseVersion() + "+" + commit
+	}
+	return releaseVersion()
+}
+
+func releaseVersion() string {
+	if version == "" {
+		return "(devel)"
+	}
+	return version
+}
+
+func printVersion() {
+	fmt.Printf("godocjson %s\n", releaseVersion())
+	if commit != "" {
+		fmt.Printf("commit: %s\n", commit)
+	}
+	if date != "" {
+		fmt.Printf("built: %s\n", date)
+	}
+}
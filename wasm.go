@@ -0,0 +1,56 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+// main exposes a global extractDocs function to JavaScript and waits
+// forever, so it can be called at any time. extractDocs receives an object
+// mapping file names to their Go source and returns the documentation of
+// the package they make up, or an Error if it could not be built:
+//
+//	const pkg = extractDocs({"foo.go": "package foo\n\nfunc Foo() {}\n"});
+func main() {
+	js.Global().Set("extractDocs", js.FuncOf(jsExtractDocs))
+	select {}
+}
+
+func jsExtractDocs(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 || args[0].Type() != js.TypeObject {
+		return jsError("extractDocs expects an object mapping file names to their source")
+	}
+
+	var (
+		files   = args[0]
+		keys    = js.Global().Get("Object").Call("keys", files)
+		sources = make(map[string][]byte, keys.Length())
+	)
+
+	for i := 0; i < keys.Length(); i++ {
+		name := keys.Index(i).String()
+		src := files.Get(name)
+		if src.Type() != js.TypeString {
+			return jsError("source of " + name + " is not a string")
+		}
+		sources[name] = []byte(src.String())
+	}
+
+	pkg, err := extractFiles(sources)
+	if err != nil {
+		return jsError(err.Error())
+	}
+
+	data, err := json.Marshal(pkg)
+	if err != nil {
+		return jsError(err.Error())
+	}
+
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
+
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New(msg)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often the sources are checked for changes.
+const watchInterval = time.Second
+
+// eventBuffer is the number of events kept for each client of /events
+// before it is considered too slow and disconnected.
+const eventBuffer = 64
+
+// ChangeEvent is pushed to the clients of /events whenever the
+// documentation of a package is regenerated.
+type ChangeEvent struct {
+	ImportPath string
+	// Hash is the new hash of the document, or empty if the package was
+	// removed.
+	Hash string
+}
+
+// liveCorpus serves the latest corpus built by a watcher.
+type liveCorpus struct {
+	mu     sync.RWMutex
+	corpus *corpus
+}
+
+func (l *liveCorpus) set(c *corpus) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.corpus = c
+}
+
+func (l *liveCorpus) get() *corpus {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.corpus
+}
+
+func (l *liveCorpus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.get().ServeHTTP(w, r)
+}
+
+// eventHub pushes the change events to the WebSocket clients connected to
+// it.
+type eventHub struct {
+	mu sync.Mutex
+	// clients are the connections of the clients, by the channel of the
+	// events to send them.
+	clients map[chan *ChangeEvent]*wsConn
+	// origins are the origins other than the server allowed to connect
+	// from browsers, as given with -cors-origin.
+	origins map[string]bool
+}
+
+func newEventHub(origins string) *eventHub {
+	return &eventHub{clients: make(map[chan *ChangeEvent]*wsConn), origins: parseOrigins(origins)}
+}
+
+func (h *eventHub) broadcast(events []*ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.clients {
+		if !sendEvents(ch, events) {
+			debugf("disconnecting slow events client")
+			delete(h.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// sendEvents sends the events to a client without blocking, reporting
+// whether its buffer had room for all of them.
+func sendEvents(ch chan *ChangeEvent, events []*ChangeEvent) bool {
+	for _, e := range events {
+		select {
+		case ch <- e:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// close disconnects all the clients, closing their connections so those
+// waiting to write an event are not left behind.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch, conn := range h.clients {
+		delete(h.clients, ch)
+		close(ch)
+		conn.Close()
+	}
+}
+
+func (h *eventHub) remove(ch chan *ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[ch]; ok {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r, h.origins)
+	if err != nil {
+		debugf("unable to accept events client: %s", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := make(chan *ChangeEvent, eventBuffer)
+	h.mu.Lock()
+	h.clients[ch] = conn
+	h.mu.Unlock()
+	defer h.remove(ch)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := conn.readControl(); err != nil {
+			debugf("events client disconnected: %s", err)
+		}
+	}()
+
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			data, _ := json.Marshal(e)
+			if err := conn.WriteText(data); err != nil {
+				debugf("unable to write event: %s", err)
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// watcher regenerates the documentation of the packages matching some
+// patterns when their sources change, updating the corpus served and
+// notifying the clients of the events hub.
+type watcher struct {
+	patterns []string
+	// pkgs and fingerprints are the documentation of each package and the
+	// fingerprint of its sources when it was generated, by package name.
+	pkgs         map[string]*Pkg
+	fingerprints map[string]string
+
+	corpus *liveCorpus
+	hub    *eventHub
+}
+
+// run checks the sources for changes until the context is done, which
+// does not interrupt an update in progress.
+func (w *watcher) run(ctx context.Context) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := w.update(); err != nil {
+				errorf("%s", err)
+			}
+		}
+	}
+}
+
+// update regenerates the documentation of the packages that changed since
+// the last time, including the ones added and removed, and notifies their
+// changes.
+func (w *watcher) update() error {
+	names, err := expandPatterns(w.patterns)
+	if err != nil {
+		return err
+	}
+
+	var (
+		events []*ChangeEvent
+		seen   = make(map[string]bool)
+	)
+
+	for _, name := range names {
+		seen[name] = true
+		fp, err := packageFingerprint(name)
+		if err != nil {
+			errorf("unable to check %s for changes: %s", name, err)
+			continue
+		}
+
+		if fp == w.fingerprints[name] {
+			continue
+		}
+		w.fingerprints[name] = fp
+
+		debugf("sources of %s changed", name)
+		pkg, err := extract(name)
+		if err != nil {
+			errorf("unable to document %s: %s", name, err)
+			continue
+		}
+
+		if old, ok := w.pkgs[name]; ok && old.Hash == pkg.Hash {
+			continue
+		}
+
+		w.pkgs[name] = pkg
+		events = append(events, &ChangeEvent{ImportPath: pkg.ImportPath, Hash: pkg.Hash})
+	}
+
+	for name, pkg := range w.pkgs {
+		if !seen[name] {
+			delete(w.pkgs, name)
+			delete(w.fingerprints, name)
+			events = append(events, &ChangeEvent{ImportPath: pkg.ImportPath})
+		}
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	var pkgs []*Pkg
+	for _, name := range names {
+		if pkg, ok := w.pkgs[name]; ok {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	w.corpus.set(newCorpus(pkgs))
+	infof("regenerated the documentation of %d package(s)", len(events))
+	w.hub.broadcast(events)
+	return nil
+}
+
+// packageFingerprint returns a string that changes whenever any of the Go
+// files in the directory of the package does, according to their sizes and
+// modification times.
+func packageFingerprint(pkgName string) (string, error) {
+	srcDir, err := packageSrcDir(pkgName)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "%s %d %d\n", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return sourceHash([]byte(sb.String())), nil
+}
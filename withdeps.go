@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+)
+
+// depthFlag is a flag that can be given either as a boolean, meaning a
+// depth of 1, or with a depth.
+type depthFlag int
+
+func (d *depthFlag) String() string {
+	return strconv.Itoa(int(*d))
+}
+
+func (d *depthFlag) Set(s string) error {
+	if b, err := strconv.ParseBool(s); err == nil {
+		*d = 0
+		if b {
+			*d = 1
+		}
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("expecting a depth greater than or equal to 0")
+	}
+	*d = depthFlag(n)
+	return nil
+}
+
+func (d *depthFlag) IsBoolFlag() bool {
+	return true
+}
+
+var withDeps depthFlag
+
+func init() {
+	flag.Var(&withDeps, "with-deps", "also document the packages imported by the given ones, or with -with-deps=N those up to N imports away, excluding the standard library")
+}
+
+// addDependencies returns the given packages followed by the packages they
+// import, directly or through others up to the given depth, each one only
+// once. Packages of the standard library are left out, and those that
+// cannot be found are reported and skipped.
+func addDependencies(pkgNames []string, depth int) []string {
+	var (
+		result = append([]string(nil), pkgNames...)
+		seen   = make(map[string]bool)
+		level  = pkgNames
+	)
+
+	for _, pkg := range pkgNames {
+		seen[pkg] = true
+	}
+
+	for ; depth > 0 && len(level) > 0; depth-- {
+		var next []string
+		for _, pkg := range level {
+			dir, err := pkgDir(pkg)
+			if err != nil {
+				warnf("unable to find the dependencies of %s: %s", pkg, err)
+				continue
+			}
+
+			for _, imp := range dirImports(dir) {
+				if seen[imp] || imp == "C" || isStdlib(imp) {
+					continue
+				}
+				seen[imp] = true
+
+				dep, err := build.Import(imp, dir, build.FindOnly)
+				if err != nil {
+					warnf("unable to find %s, imported by %s: %s", imp, pkg, err)
+					continue
+				}
+
+				// Packages in the module cache are only found by the go
+				// command, so their module is remembered to find them
+				// again when documenting them.
+				if dep.Root != "" {
+					if mod := modulePath(dep.Root); mod != "" {
+						addModule(mod, dep.Root)
+					}
+				}
+
+				if dep.ImportPath != imp && seen[dep.ImportPath] {
+					continue
+				}
+				seen[dep.ImportPath] = true
+
+				debugf("documenting %s, imported by %s", dep.ImportPath, pkg)
+				next = append(next, dep.ImportPath)
+			}
+		}
+
+		result = append(result, next...)
+		level = next
+	}
+
+	return result
+}
+
+// pkgDir returns the directory of the given package, or of the given Go
+// file.
+func pkgDir(pkg string) (string, error) {
+	if isGoFile(pkg) {
+		return filepath.Dir(pkg), nil
+	}
+	return packageSrcDir(pkg)
+}
+
+// dirImports returns the import paths of the files of the package in the
+// given directory that would be documented.
+func dirImports(dir string) []string {
+	files, err := goFiles(dir, false)
+	if err != nil {
+		debugf("unable to list the files of %s: %s", dir, err)
+		return nil
+	}
+
+	var (
+		imports []string
+		fset    = token.NewFileSet()
+	)
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			debugf("unable to parse the imports of %s: %s", name, err)
+			continue
+		}
+
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+				imports = append(imports, path)
+			}
+		}
+	}
+	return imports
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// xmlWriter writes packages as XML, with the same data model as their JSON
+// encoding. Every package is a Package element, inside a Packages element
+// if many may be written, and every value is an element:
+//
+//   - Objects have an element for each of their keys, in the same order,
+//     named after it. Keys that are not valid XML names, such as import
+//     paths, are written as entry elements with the key in their key
+//     attribute instead.
+//   - Arrays have an item element for each of their elements.
+//   - Strings, numbers and booleans are the text of the element.
+//   - Null is an empty element with a nil="true" attribute.
+type xmlWriter struct {
+	w    *bufio.Writer
+	list bool
+	n    int
+}
+
+func newXMLWriter(w io.Writer, list bool) packageWriter {
+	return &xmlWriter{w: bufio.NewWriter(w), list: list}
+}
+
+func (w *xmlWriter) Write(pkg *Pkg) error {
+	if w.n == 0 {
+		w.w.WriteString(xml.Header)
+		if w.list {
+			w.w.WriteString("<Packages>\n")
+		}
+	}
+	w.n++
+
+	data, err := json.Marshal(outputValue(pkg))
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := readJSONValue(dec)
+	if err != nil {
+		return err
+	}
+
+	indent := ""
+	if w.list {
+		indent = "\t"
+	}
+	return encodeXML(w.w, "Package", "", v, indent)
+}
+
+func (w *xmlWriter) Close() error {
+	if w.list {
+		if w.n == 0 {
+			w.w.WriteString(xml.Header)
+		}
+		w.w.WriteString("</Packages>\n")
+	}
+
+	return w.w.Flush()
+}
+
+// encodeXML writes a value as an element with the given name, and key
+// attribute if it is not empty, indented with the given prefix.
+func encodeXML(w *bufio.Writer, name, key string, v interface{}, indent string) error {
+	w.WriteString(indent + "<" + name)
+	if key != "" {
+		w.WriteString(` key="`)
+		xml.EscapeText(w, []byte(key))
+		w.WriteString(`"`)
+	}
+
+	switch v := v.(type) {
+	case nil:
+		w.WriteString(` nil="true"/>` + "\n")
+	case string:
+		w.WriteString(">")
+		escapeXMLText(w, v)
+		w.WriteString("</" + name + ">\n")
+	case bool, json.Number:
+		fmt.Fprintf(w, ">%v</%s>\n", v, name)
+	case []interface{}:
+		if len(v) == 0 {
+			w.WriteString("/>\n")
+			return nil
+		}
+
+		w.WriteString(">\n")
+		for _, elem := range v {
+			if err := encodeXML(w, "item", "", elem, indent+"\t"); err != nil {
+				return err
+			}
+		}
+		w.WriteString(indent + "</" + name + ">\n")
+	case *jsonObject:
+		if len(v.keys) == 0 {
+			w.WriteString("/>\n")
+			return nil
+		}
+
+		w.WriteString(">\n")
+		for i, k := range v.keys {
+			elem, elemKey := k, ""
+			if !isXMLName(k) {
+				elem, elemKey = "entry", k
+			}
+
+			if err := encodeXML(w, elem, elemKey, v.values[i], indent+"\t"); err != nil {
+				return err
+			}
+		}
+		w.WriteString(indent + "</" + name + ">\n")
+	default:
+		return fmt.Errorf("unexpected JSON value of type %T", v)
+	}
+	return nil
+}
+
+// escapeXMLText writes the text of an element, escaping it as
+// xml.EscapeText does except for newlines and tabs, which are kept as they
+// are so multi-line comments and declarations can still be read.
+func escapeXMLText(w *bufio.Writer, s string) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			w.WriteString("&amp;")
+		case r == '<':
+			w.WriteString("&lt;")
+		case r == '>':
+			w.WriteString("&gt;")
+		case r == '\r':
+			w.WriteString("&#xD;")
+		case r == '\n' || r == '\t' || (r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0x10FFFF):
+			w.WriteRune(r)
+		default:
+			// These cannot be written in XML 1.0, not even escaped.
+			w.WriteRune(unicode.ReplacementChar)
+		}
+	}
+}
+
+// isXMLName reports whether a key can be used as the name of an element:
+// it is made of letters, digits, hyphens, underscores and dots, does not
+// start with a digit, hyphen or dot, nor with "xml" in any case, which is
+// reserved, and has no colons, which would make it a qualified name.
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
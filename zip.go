@@ -0,0 +1,330 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+var zipFile = flag.String("zip", "", "document all packages of a module zip archive, as served by a module proxy")
+
+const (
+	// maxModuleSize is the maximum size of all the files of a module zip
+	// once decompressed, and maxModuleFileSize that of its go.mod and
+	// license files, as enforced by the go command.
+	maxModuleSize     = 500 << 20
+	maxModuleFileSize = 16 << 20
+)
+
+// moduleZip is a module archive as downloaded from a module proxy, in which
+// all files are under a "module@version/" directory.
+type moduleZip struct {
+	// Module is the module path and Version its version, both taken from
+	// the directory all files are in.
+	Module  string
+	Version string
+	files   map[string]*zip.File
+	dirs    map[string][]string
+}
+
+// openModuleZip reads the index of a module archive, which must be that of
+// the given module and version unless they are empty. Archives too large
+// once decompressed are rejected, as the go command does.
+func openModuleZip(r *zip.Reader, module, version string) (*moduleZip, error) {
+	z := &moduleZip{
+		files: make(map[string]*zip.File),
+		dirs:  make(map[string][]string),
+	}
+
+	var size uint64
+	for _, f := range r.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+
+		// Module paths contain slashes, but versions never do.
+		mod, rest, ok := strings.Cut(f.Name, "@")
+		v, _, hasDir := strings.Cut(rest, "/")
+		if !ok || !hasDir {
+			return nil, fmt.Errorf("invalid module zip: %s is not inside a module@version directory", f.Name)
+		}
+
+		if z.Module == "" {
+			z.Module, z.Version = mod, v
+		} else if z.Module != mod || z.Version != v {
+			return nil, fmt.Errorf("invalid module zip: found files of both %s@%s and %s@%s", z.Module, z.Version, mod, v)
+		}
+
+		if module != "" && (mod != module || v != version) {
+			return nil, fmt.Errorf("invalid module zip: found files of %s@%s instead of %s@%s", mod, v, module, version)
+		}
+
+		size += f.UncompressedSize64
+		if f.UncompressedSize64 > maxModuleSize || size > maxModuleSize {
+			return nil, fmt.Errorf("invalid module zip: it is larger than %d bytes once decompressed", maxModuleSize)
+		}
+
+		if base := path.Base(f.Name); (base == "go.mod" || licenseFileRegexp.MatchString(base)) && f.UncompressedSize64 > maxModuleFileSize {
+			return nil, fmt.Errorf("invalid module zip: %s is larger than %d bytes", f.Name, maxModuleFileSize)
+		}
+
+		z.files[f.Name] = f
+		dir := path.Dir(f.Name)
+		z.dirs[dir] = append(z.dirs[dir], path.Base(f.Name))
+	}
+
+	if z.Module == "" {
+		return nil, fmt.Errorf("invalid module zip: it is empty")
+	}
+
+	return z, nil
+}
+
+func (z *moduleZip) root() string {
+	return z.Module + "@" + z.Version
+}
+
+func (z *moduleZip) read(name string) ([]byte, error) {
+	f, ok := z.files[name]
+	if !ok {
+		return nil, fmt.Errorf("file %s not found in module zip", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// The sizes in the archive were checked when opening it, but nothing
+	// but reading the files tells whether they are true.
+	data, err := io.ReadAll(io.LimitReader(rc, int64(f.UncompressedSize64)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(data)) > f.UncompressedSize64 {
+		return nil, fmt.Errorf("invalid module zip: %s is larger than its size in the archive", name)
+	}
+	return data, nil
+}
+
+// buildContext returns a build context reading files from the archive, so
+// build constraints can be evaluated without extracting it.
+func (z *moduleZip) buildContext() *build.Context {
+	ctx := build.Default
+	ctx.JoinPath = path.Join
+	ctx.OpenFile = func(name string) (io.ReadCloser, error) {
+		data, err := z.read(name)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return &ctx
+}
+
+// packageDirs returns the directories of the archive containing Go
+// packages, skipping those the go tool would ignore.
+func (z *moduleZip) packageDirs() []string {
+	var dirs []string
+	for dir, files := range z.dirs {
+		if ignoredDir(strings.TrimPrefix(strings.TrimPrefix(dir, z.root()), "/")) {
+			continue
+		}
+
+		for _, f := range files {
+			if strings.HasSuffix(f, ".go") && !strings.HasSuffix(f, "_test.go") {
+				dirs = append(dirs, dir)
+				break
+			}
+		}
+	}
+
+	sort.Strings(dirs)
+	return dirs
+}
+
+// ignoredDir reports whether any element of the given slash-separated path
+// is a directory ignored when matching packages.
+func ignoredDir(rel string) bool {
+	if rel == "" {
+		return false
+	}
+
+	for _, elem := range strings.Split(rel, "/") {
+		if elem == "vendor" || elem == "testdata" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
+
+func (z *moduleZip) importPath(dir string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(dir, z.root()), "/")
+	if rel == "" {
+		return z.Module
+	}
+	return z.Module + "/" + rel
+}
+
+// licenses returns the licenses at the root of the archive.
+func (z *moduleZip) licenses() []*License {
+	var licenses []*License
+	for _, name := range z.dirs[z.root()] {
+		if !licenseFileRegexp.MatchString(name) {
+			continue
+		}
+
+		full := z.root() + "/" + name
+		text, err := z.read(full)
+		if err != nil {
+			debugf("unable to read license file %s: %s", full, err)
+			continue
+		}
+
+		licenses = append(licenses, &License{SPDX: guessLicense(string(text)), File: full})
+	}
+
+	sort.Slice(licenses, func(i, j int) bool {
+		return licenses[i].File < licenses[j].File
+	})
+	return licenses
+}
+
+// extractZip builds the documentation of all the packages in the module
+// archive at the given path, passing them to emit in order of import path.
+// Unless they are empty, the archive must be that of the given module and
+// version.
+func extractZip(zipPath, module, version string, emit func(*Pkg) error) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	z, err := openModuleZip(&r.Reader, module, version)
+	if err != nil {
+		return err
+	}
+
+	ctx := z.buildContext()
+	licenses := z.licenses()
+	for _, dir := range z.packageDirs() {
+		pkgName := z.importPath(dir)
+		pkg, err := z.extract(ctx, dir, pkgName)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pkgName, err)
+		}
+
+		if pkg == nil {
+			continue
+		}
+
+		pkg.Licenses = licenses
+		// The version of the packages of an archive is known, so they
+		// link to its documentation rather than that of the latest.
+		if *withPkgsiteURLs {
+			setPkgsiteURLs(pkg, z.Version)
+		}
+		pkg.Hash = documentHash(pkg)
+		if err := emit(pkg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extract builds the documentation of the package in the given directory of
+// the archive, which is nil if no file matches the build constraints.
+func (z *moduleZip) extract(ctx *build.Context, dir, pkgName string) (*Pkg, error) {
+	var (
+		fset      = token.NewFileSet()
+		pkgs      = make(map[string]*ast.Package)
+		hashes    = make(map[string]string)
+		tests     []*ast.File
+		errs      []*ParseError
+		lastError error
+	)
+
+	for _, name := range z.dirs[dir] {
+		isTest := strings.HasSuffix(name, "_test.go")
+		if !strings.HasSuffix(name, ".go") || (isTest && !*withExamples) {
+			continue
+		}
+
+		full := dir + "/" + name
+		if ok, err := ctx.MatchFile(dir, name); err != nil || !ok {
+			debugf("skipping %s: excluded by build constraints", full)
+			continue
+		}
+
+		data, err := z.read(full)
+		if err != nil {
+			return nil, err
+		}
+
+		if *skipGenerated && !isTest && isGeneratedFile(full, data) {
+			debugf("skipping generated file %s", full)
+			continue
+		}
+
+		debugf("parsing %s", full)
+		f, err := parser.ParseFile(fset, full, data, parser.ParseComments)
+		if err != nil {
+			list, ok := newParseErrors(err)
+			if !ok || *strict {
+				return nil, err
+			}
+
+			errorf("%s", err)
+			errs = append(errs, list...)
+			lastError = err
+			continue
+		}
+
+		hashes[full] = sourceHash(data)
+		if isTest {
+			tests = append(tests, f)
+			continue
+		}
+
+		if strings.HasSuffix(f.Name.Name, "_test") {
+			continue
+		}
+
+		p, ok := pkgs[f.Name.Name]
+		if !ok {
+			p = &ast.Package{Name: f.Name.Name, Files: make(map[string]*ast.File)}
+			pkgs[f.Name.Name] = p
+		}
+		p.Files[full] = f
+	}
+
+	if len(pkgs) == 0 {
+		return nil, lastError
+	}
+
+	pkg, err := selectPackage(pkgs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", dir, err)
+	}
+
+	result, err := buildPkg(pkgName, fset, "", pkg, tests, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ParseErrors = errs
+	return result, nil
+}